@@ -1,5 +1,11 @@
 package nango
 
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
 const (
 	PinLow = iota
 	PinHigh
@@ -11,6 +17,22 @@ const (
 	PinInputPullup
 )
 
+var pinModeNames = map[int]string{
+	PinInput:       "input",
+	PinOutput:      "output",
+	PinInputPullup: "input_pullup",
+}
+
+//ParsePinMode returns the pin mode called name: input, output or input_pullup
+func ParsePinMode(name string) (int, error) {
+	for mode, n := range pinModeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid pin mode %q", name)
+}
+
 const (
 	LsbFirst = iota
 	MsbFirst
@@ -18,45 +40,144 @@ const (
 
 type ArduinoApi struct {
 	*FirmwareClass
+	//SkipRedundant skips PinMode and DigitalWrite calls which would not change
+	//the mode or output value last set through this ArduinoApi
+	SkipRedundant bool
+	//CoalesceWrites keeps only the latest AnalogWrite per pin waiting to be
+	//sent, so rapid updates to one pin can't build up a backlog. Intermediate
+	//values may never reach the board.
+	CoalesceWrites bool
+	//Model, if set, is used to reject calls on pins which don't support them
+	Model   *BoardModel
+	cacheMu sync.Mutex
+	modes   map[string]int
+	values  map[string]int
+	duty    map[string]int //values last written with AnalogWrite
 }
 
-func NewArduinoApi(conn *FirmwareConnection) *ArduinoApi {
+func NewArduinoApi(conn Conn) *ArduinoApi {
 	return &ArduinoApi{
-		&FirmwareClass{
+		FirmwareClass: &FirmwareClass{
 			Conn:      conn,
-			Id:        0,
-			Namespace: "A",
-		}}
+			Id:        StaticId,
+			Namespace: NamespaceArduino,
+		},
+		modes:  make(map[string]int),
+		values: make(map[string]int),
+		duty:   make(map[string]int),
+	}
+}
+
+//InvalidateCache forgets the pin modes and values tracked for SkipRedundant,
+//e.g. after the board has been reset
+func (api *ArduinoApi) InvalidateCache() {
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	api.modes = make(map[string]int)
+	api.values = make(map[string]int)
+	api.duty = make(map[string]int)
+}
+
+//cached reports whether pin is known to already be in state val
+func (api *ArduinoApi) cached(cache map[string]int, pin string, val int) bool {
+	if !api.SkipRedundant {
+		return false
+	}
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	v, ok := cache[pin]
+	return ok && v == val
+}
+
+//remember records the state of pin, or forgets it if the call failed since
+//the state is then unknown
+func (api *ArduinoApi) remember(cache map[string]int, pin string, val int, err error) {
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	if err != nil {
+		delete(cache, pin)
+		return
+	}
+	cache[pin] = val
+}
+
+//forget drops the state of pin from cache
+func (api *ArduinoApi) forget(cache map[string]int, pin string) {
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	delete(cache, pin)
 }
 
 func (api *ArduinoApi) DigitalWrite(pin string, val int) error {
-	return api.CallAndReturnNothing("dw", pin, val)
+	if err := api.Model.Check(pin, CapDigital); err != nil {
+		return err
+	}
+	if api.cached(api.values, pin, val) {
+		return nil
+	}
+	err := api.CallAndReturnNothing(MethodDigitalWrite, pin, val)
+	api.remember(api.values, pin, val, err)
+	api.forget(api.duty, pin)
+	return err
 }
 
 func (api *ArduinoApi) DigitalRead(pin string) (int, error) {
-	return api.CallAndReturnInt("r", pin)
+	if err := api.Model.Check(pin, CapDigital); err != nil {
+		return -1, err
+	}
+	return api.CallAndReturnInt(MethodDigitalRead, pin)
 }
 
 func (api *ArduinoApi) AnalogWrite(pin string, val int) error {
-	return api.CallAndReturnNothing("aw", pin)
+	if err := api.Model.Check(pin, CapPWM); err != nil {
+		return err
+	}
+	var err error
+	if api.CoalesceWrites {
+		var b *Buffer
+		b, err = keyedMethodCall(context.Background(), api.FirmwareClass, NamespaceArduino+"."+MethodAnalogWrite+":"+pin, MethodAnalogWrite, []interface{}{pin, val})
+		b.Release()
+	} else {
+		err = api.CallAndReturnNothing(MethodAnalogWrite, pin, val)
+	}
+	api.remember(api.duty, pin, val, err)
+	api.forget(api.values, pin)
+	return err
 }
 
 func (api *ArduinoApi) AnalogRead(pin string) (int, error) {
-	return api.CallAndReturnInt("a", pin)
+	if err := api.Model.Check(pin, CapAnalogIn); err != nil {
+		return -1, err
+	}
+	return api.CallAndReturnInt(MethodAnalogRead, pin)
 }
 
 func (api *ArduinoApi) PinMode(pin string, mode int) error {
-	return api.CallAndReturnNothing("pm", pin, mode)
+	if err := api.Model.Check(pin, CapDigital); err != nil {
+		return err
+	}
+	if api.cached(api.modes, pin, mode) {
+		return nil
+	}
+	err := api.CallAndReturnNothing(MethodPinMode, pin, mode)
+	api.remember(api.modes, pin, mode, err)
+	//changing the mode can change the output state, e.g. INPUT_PULLUP drives
+	//the pin high
+	api.cacheMu.Lock()
+	delete(api.values, pin)
+	delete(api.duty, pin)
+	api.cacheMu.Unlock()
+	return err
 }
 
 func (api *ArduinoApi) Millis() (int, error) {
-	return api.CallAndReturnInt("m")
+	return api.CallAndReturnInt(MethodMillis)
 }
 
 func (api *ArduinoApi) PulseIn(pin string, val int) (int, error) {
-	return api.CallAndReturnInt("pi", pin, val)
+	return api.CallAndReturnInt(MethodPulseIn, pin, val)
 }
 
 func (api *ArduinoApi) ShiftOut(dataPin string, clockPin string, bitOrder int, val byte) (int, error) {
-	return api.CallAndReturnInt("s", dataPin, clockPin, bitOrder, val)
+	return api.CallAndReturnInt(MethodShiftOut, dataPin, clockPin, bitOrder, val)
 }
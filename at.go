@@ -0,0 +1,211 @@
+package nango
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ATError is returned by modules driven with AT commands which respond to a
+//command with an error
+type ATError struct {
+	Command string
+	//Response is the error response, e.g. "ERROR" or "+CMS ERROR: 500"
+	Response string
+}
+
+func (e *ATError) Error() string {
+	return fmt.Sprintf("AT command %s failed: %s", e.Command, e.Response)
+}
+
+//atModem exchanges AT commands with a module attached to a UART on the
+//board. Lines received while waiting for a response which aren't part of it,
+//such as unsolicited result codes, are passed to unsolicited.
+type atModem struct {
+	serial      AttachedSerial
+	r           *lineReader
+	clock       Clock
+	mu          sync.Mutex
+	unsolicited func(line string)
+	//isResponse reports whether a line is part of the response to cmd,
+	//following the lines of the response received so far
+	isResponse func(cmd string, lines []string, line string) bool
+	//data, if set, receives the payloads of +IPD,length:payload
+	//notifications, which may span lines
+	data func(b []byte)
+}
+
+func newATModem(serial AttachedSerial, clock Clock) *atModem {
+	return &atModem{serial: serial, r: newLineReader(serial), clock: clock}
+}
+
+//readLine returns the next non-empty line, waiting until deadline
+func (m *atModem) readLine(deadline time.Time) (string, error) {
+	for {
+		line, err := m.r.readLine()
+		if errors.Is(err, ErrTimeout) {
+			if m.clock.Now().After(deadline) {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if m.data != nil && strings.HasPrefix(strings.TrimLeft(line, "\r\n"), "+IPD,") {
+			if err := m.readData(strings.TrimLeft(line, "\r\n"), deadline); err != nil {
+				return "", err
+			}
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line != "" {
+			return line, nil
+		}
+	}
+}
+
+//readData reads the payload of the +IPD notification starting line
+func (m *atModem) readData(line string, deadline time.Time) error {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return fmt.Errorf("malformed data notification %q", line)
+	}
+	n, err := strconv.Atoi(line[len("+IPD,"):colon])
+	if err != nil {
+		return fmt.Errorf("malformed data notification %q", line)
+	}
+	payload := []byte(line[colon+1:])
+	buf := make([]byte, 256)
+	for len(payload) < n {
+		want := n - len(payload)
+		if want > len(buf) {
+			want = len(buf)
+		}
+		k, err := m.r.r.Read(buf[:want])
+		payload = append(payload, buf[:k]...)
+		if errors.Is(err, ErrTimeout) {
+			if m.clock.Now().After(deadline) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	m.r.partial = string(payload[n:])
+	m.data(payload[:n])
+	return nil
+}
+
+//poll handles the lines received until deadline, such as data notifications
+func (m *atModem) poll(deadline time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	line, err := m.readLine(deadline)
+	if err != nil {
+		return err
+	}
+	if m.unsolicited != nil {
+		m.unsolicited(line)
+	}
+	return nil
+}
+
+//waitPrompt waits for the "> " prompt for data, which isn't terminated by a
+//line break
+func (m *atModem) waitPrompt(cmd string, deadline time.Time) error {
+	for {
+		if m.r.partial != "" {
+			p := strings.TrimSpace(m.r.partial)
+			m.r.partial = ""
+			if p == ">" {
+				return nil
+			}
+		}
+		b, err := m.r.r.ReadByte()
+		if errors.Is(err, ErrTimeout) {
+			if m.clock.Now().After(deadline) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		switch b {
+		case '>':
+			return nil
+		case '\r', '\n', ' ':
+		default:
+			m.r.r.UnreadByte()
+			line, err := m.readLine(deadline)
+			if err != nil {
+				return err
+			}
+			if isATError(line) {
+				return &ATError{Command: cmd, Response: line}
+			}
+		}
+	}
+}
+
+func isATError(line string) bool {
+	return line == "ERROR" || strings.HasPrefix(line, "+CME ERROR") || strings.HasPrefix(line, "+CMS ERROR")
+}
+
+//command sends cmd and returns the lines of its response before the final
+//result code
+func (m *atModem) command(cmd string, timeout time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.serial.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, err
+	}
+	return m.response(cmd, m.clock.Now().Add(timeout))
+}
+
+//commandData sends cmd, waits for the data prompt and then sends data
+func (m *atModem) commandData(cmd string, data string, terminator string, timeout time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deadline := m.clock.Now().Add(timeout)
+	if _, err := m.serial.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, err
+	}
+	if err := m.waitPrompt(cmd, deadline); err != nil {
+		return nil, err
+	}
+	if _, err := m.serial.Write([]byte(data + terminator)); err != nil {
+		return nil, err
+	}
+	return m.response(cmd, deadline)
+}
+
+func (m *atModem) response(cmd string, deadline time.Time) ([]string, error) {
+	var lines []string
+	for {
+		line, err := m.readLine(deadline)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case line == "OK" || line == "SEND OK":
+			return lines, nil
+		case isATError(line) || line == "SEND FAIL":
+			return nil, &ATError{Command: cmd, Response: line}
+		case line == cmd:
+			//echo
+		case m.isResponse != nil && !m.isResponse(cmd, lines, line):
+			if m.unsolicited != nil {
+				m.unsolicited(line)
+			}
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
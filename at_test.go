@@ -0,0 +1,147 @@
+package nango
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeModem is an AttachedSerial answering AT commands from a script
+type fakeModem struct {
+	mu       sync.Mutex
+	script   map[string]string
+	written  []string
+	pending  string
+	response bytes.Buffer
+	//timeouts counts the reads which timed out for want of a response
+	timeouts int
+}
+
+func (m *fakeModem) Begin(baud int) error { return nil }
+
+func (m *fakeModem) Available() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.response.Len(), nil
+}
+
+func (m *fakeModem) Read(b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.response.Len() == 0 {
+		m.timeouts++
+		return 0, SerialTimeoutError{Port: "modem", Op: "Read"}
+	}
+	return m.response.Read(b)
+}
+
+func (m *fakeModem) Write(b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending += string(b)
+	for {
+		i := strings.IndexAny(m.pending, "\n\x1a")
+		if i < 0 {
+			return len(b), nil
+		}
+		cmd := strings.TrimRight(m.pending[:i], "\r")
+		m.pending = m.pending[i+1:]
+		m.written = append(m.written, cmd)
+		m.response.WriteString(m.script[cmd])
+	}
+}
+
+func TestSIM800(t *testing.T) {
+	m := &fakeModem{script: map[string]string{
+		"AT":                   "AT\r\nOK\r\n",
+		"ATE0":                 "ATE0\r\nOK\r\n",
+		"AT+CMGF=1":            "\r\nOK\r\n",
+		"AT+CREG?":             "\r\n+CMTI: \"SM\",3\r\n+CREG: 0,5\r\n\r\nOK\r\n",
+		"AT+CSQ":               "\r\n+CSQ: 18,0\r\n\r\nOK\r\n",
+		`AT+CMGS="+441234"`:    "\r\n> ",
+		"alarm":                "\r\n+CMGS: 12\r\n\r\nOK\r\n",
+		`AT+CMGL="REC UNREAD"`: "\r\n+CMGL: 3,\"REC UNREAD\",\"+449876\",\"\",\"21/06/01,12:30:00+04\"\r\nstatus\r\n" +
+			"+CMGL: 4,\"REC UNREAD\",\"+449876\",\"\",\"21/06/01,12:31:00+04\"\r\n+1 alarm\r\n+CALL: +441234\r\n\r\nOK\r\n",
+	}}
+	_, conn := openLoopback(t)
+	s, err := NewSIM800(conn, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urcs []string
+	s.at.unsolicited = func(line string) { urcs = append(urcs, line) }
+	if ok, err := s.Registered(); err != nil || !ok {
+		t.Fatalf("expected roaming registration, got %v (%v)", ok, err)
+	}
+	if len(urcs) != 1 || !strings.HasPrefix(urcs[0], "+CMTI") {
+		t.Fatalf("expected +CMTI passed on as unsolicited, got %v", urcs)
+	}
+	if dbm, ok, err := s.SignalStrength(); err != nil || !ok || dbm != -77 {
+		t.Fatalf("expected -77dBm, got %d %v (%v)", dbm, ok, err)
+	}
+	if err := s.SendSMS("+441234", "alarm"); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := s.ReadUnread()
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %v (%v)", msgs, err)
+	}
+	want := time.Date(2021, 6, 1, 11, 30, 0, 0, time.UTC)
+	if msg := msgs[0]; msg.Index != 3 || msg.Sender != "+449876" || msg.Text != "status" || !msg.Time.Equal(want) {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	//text lines starting with + are part of the message, not result codes
+	if msg := msgs[1]; msg.Index != 4 || msg.Text != "+1 alarm\n+CALL: +441234" || len(urcs) != 1 {
+		t.Fatalf("unexpected message %+v (unsolicited %v)", msg, urcs)
+	}
+
+	m.script["AT+CMGD=3"] = "\r\n+CMS ERROR: 321\r\n"
+	var atErr *ATError
+	if err := s.Delete(3); !errors.As(err, &atErr) || atErr.Response != "+CMS ERROR: 321" {
+		t.Fatalf("expected ATError, got %v", err)
+	}
+}
+
+func TestESP8266(t *testing.T) {
+	m := &fakeModem{script: map[string]string{
+		"AT":                              "\r\nOK\r\n",
+		"ATE0":                            "\r\nOK\r\n",
+		"AT+CWMODE=1":                     "\r\nOK\r\n",
+		"AT+CIPMUX=0":                     "\r\nOK\r\n",
+		`AT+CWJAP="lab","secret"`:         "WIFI CONNECTED\r\nWIFI GOT IP\r\n\r\nOK\r\n",
+		"AT+CIFSR":                        "+CIFSR:STAIP,\"192.168.1.20\"\r\n+CIFSR:STAMAC,\"5c:cf:7f:00:00:01\"\r\n\r\nOK\r\n",
+		`AT+CIPSTART="TCP","10.0.0.1",80`: "CONNECT\r\n\r\nOK\r\n",
+		"AT+CIPSEND=5":                    "\r\nOK\r\n> ",
+		"ping":                            "\r\nRecv 5 bytes\r\n\r\nSEND OK\r\n\r\n+IPD,5:po\nng\r\nCLOSED\r\n",
+	}}
+	_, conn := openLoopback(t)
+	e, err := NewESP8266(conn, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Join("lab", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if ip, err := e.LocalIP(); err != nil || ip != "192.168.1.20" {
+		t.Fatalf("expected 192.168.1.20, got %q (%v)", ip, err)
+	}
+	c, err := e.Dial("tcp", "10.0.0.1", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Write([]byte("ping\n")); err != nil || n != 5 {
+		t.Fatalf("expected 5 bytes written, got %d (%v)", n, err)
+	}
+	b := make([]byte, 16)
+	n, err := c.Read(b)
+	if err != nil || string(b[:n]) != "po\nng" {
+		t.Fatalf("expected po\\nng, got %q (%v)", b[:n], err)
+	}
+	if _, err := c.Read(b); err != io.EOF {
+		t.Fatalf("expected EOF once closed by the peer, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package nango
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+//AttachedSerial is a UART on the arduino, such as a SoftwareSerial instance,
+//presented as an io.ReadWriter so attached devices can be driven with
+//standard Go code
+type AttachedSerial interface {
+	io.ReadWriter
+	Begin(baud int) error
+	Available() (int, error)
+}
+
+//uartWriteChunk is the maximum number of bytes sent to the firmware per write call
+const uartWriteChunk = 32
+
+//uart implements AttachedSerial for firmware classes exposing the arduino
+//Stream methods begin, available, read and write
+type uart struct {
+	*FirmwareClass
+	//ReadTimeout is how long Read waits for data before returning a SerialTimeoutError
+	ReadTimeout time.Duration
+	//PollInterval is how often Read polls the firmware for available data
+	PollInterval time.Duration
+}
+
+func newUart(f *FirmwareClass) *uart {
+	return &uart{
+		FirmwareClass: f,
+		ReadTimeout:   2 * time.Second,
+		PollInterval:  10 * time.Millisecond,
+	}
+}
+
+func (u *uart) Begin(baud int) error {
+	return u.CallAndReturnNothing(MethodStreamBegin, baud)
+}
+
+func (u *uart) Available() (int, error) {
+	return u.CallAndReturnInt(MethodStreamAvailable)
+}
+
+//Read reads the bytes available on the uart into b. It blocks until at least
+//one byte is available or ReadTimeout elapses, then fetches up to len(b) of
+//them in a single call.
+func (u *uart) Read(b []byte) (i int, err error) {
+	if len(b) == 0 {
+		return
+	}
+	clock := clockOf(u.Conn)
+	deadline := clock.Now().Add(u.ReadTimeout)
+	var n int
+	for {
+		n, err = u.Available()
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			break
+		}
+		if clock.Now().After(deadline) {
+			err = SerialTimeoutError{Port: u.Namespace, Op: "Read"}
+			return
+		}
+		clock.Sleep(u.PollInterval)
+	}
+	if n > len(b) {
+		n = len(b)
+	}
+	r, err := u.CallAndBorrowBytes(MethodStreamReadBytes, n)
+	if err != nil {
+		return
+	}
+	defer r.Release()
+	if len(r.Bytes()) > n {
+		err = fmt.Errorf("uart readBytes: requested %d bytes but received %d", n, len(r.Bytes()))
+		return
+	}
+	i = copy(b, r.Bytes())
+	return
+}
+
+//Write writes b to the uart, in chunks small enough for the firmware's
+//receive buffer
+func (u *uart) Write(b []byte) (i int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > uartWriteChunk {
+			chunk = chunk[:uartWriteChunk]
+		}
+		var n int
+		n, err = u.CallAndReturnInt(MethodStreamWrite, chunk)
+		i += n
+		if err != nil {
+			return
+		}
+		if n < len(chunk) {
+			err = io.ErrShortWrite
+			return
+		}
+		b = b[n:]
+	}
+	return
+}
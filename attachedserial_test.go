@@ -0,0 +1,189 @@
+package nango_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+//fakeUart answers SoftwareSerial stream calls from rx, recording the calls
+//made and the size of each write. Writes longer than short are acknowledged
+//one byte short if short is set.
+type fakeUart struct {
+	mu     sync.Mutex
+	rx     []byte
+	calls  []string
+	writes []int
+	short  int
+}
+
+func (f *fakeUart) attach(lb *nango.Loopback) {
+	lb.Respond(nango.NamespaceSoftwareSerial, nango.MethodNew, "1")
+	lb.Handle(nango.NamespaceSoftwareSerial, nango.MethodStreamAvailable, func(c nango.LoopbackCall) (string, bool) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, c.Method)
+		return strconv.Itoa(len(f.rx)), true
+	})
+	lb.Handle(nango.NamespaceSoftwareSerial, nango.MethodStreamReadBytes, func(c nango.LoopbackCall) (string, bool) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, c.Method+" "+c.Args[0])
+		n, _ := strconv.Atoi(c.Args[0])
+		b := f.rx[:n]
+		f.rx = f.rx[n:]
+		return hex.EncodeToString(b), true
+	})
+	lb.Handle(nango.NamespaceSoftwareSerial, nango.MethodStreamWrite, func(c nango.LoopbackCall) (string, bool) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		b, _ := hex.DecodeString(c.Args[0])
+		f.writes = append(f.writes, len(b))
+		n := len(b)
+		if f.short > 0 && n > f.short {
+			n--
+		}
+		return strconv.Itoa(n), true
+	})
+}
+
+func (f *fakeUart) receive(b []byte) {
+	f.mu.Lock()
+	f.rx = append(f.rx, b...)
+	f.mu.Unlock()
+}
+
+func (f *fakeUart) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	return calls
+}
+
+func openUart(t *testing.T) (*fakeUart, *nango.SoftwareSerial, *nangotest.Clock) {
+	t.Helper()
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb, conn := openClocked(t, clock)
+	f := &fakeUart{}
+	f.attach(lb)
+	s, err := nango.NewSoftwareSerial(conn, "D10", "D11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, s, clock
+}
+
+//readAsync reads into b on another goroutine, advancing clock a poll
+//interval at a time while the read is waiting for data
+func readAsync(t *testing.T, s *nango.SoftwareSerial, clock *nangotest.Clock, b []byte, arrive func(polls int)) (int, error) {
+	t.Helper()
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := s.Read(b)
+		done <- result{n, err}
+	}()
+	for polls := 0; ; {
+		select {
+		case r := <-done:
+			return r.n, r.err
+		case <-time.After(5 * time.Second):
+			t.Fatal("read did not return")
+		default:
+		}
+		if clock.Waiters() > 0 {
+			polls++
+			if arrive != nil {
+				arrive(polls)
+			}
+			clock.Advance(s.PollInterval)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUartRead(t *testing.T) {
+	f, s, clock := openUart(t)
+	f.receive([]byte("hello"))
+	b := make([]byte, 3)
+	n, err := s.Read(b)
+	if err != nil || string(b[:n]) != "hel" {
+		t.Fatalf("expected hel, got %q, %v", b[:n], err)
+	}
+	//the available bytes are fetched in one call, not one call per byte
+	if calls := f.log(); len(calls) != 2 || calls[1] != nango.MethodStreamReadBytes+" 3" {
+		t.Fatalf("expected available then a single readBytes 3, got %v", calls)
+	}
+	n, err = s.Read(make([]byte, 8))
+	if err != nil || n != 2 {
+		t.Fatalf("expected the remaining 2 bytes, got %d, %v", n, err)
+	}
+	f.log()
+
+	//data arriving while polling is read once it is available
+	b = make([]byte, 8)
+	n, err = readAsync(t, s, clock, b, func(polls int) {
+		if polls == 3 {
+			f.receive([]byte("late"))
+		}
+	})
+	if err != nil || string(b[:n]) != "late" {
+		t.Fatalf("expected late, got %q, %v", b[:n], err)
+	}
+	calls := f.log()
+	if len(calls) != 5 || calls[4] != nango.MethodStreamReadBytes+" 4" {
+		t.Fatalf("expected 4 polls of available then readBytes 4, got %v", calls)
+	}
+}
+
+func TestUartReadTimeout(t *testing.T) {
+	f, s, clock := openUart(t)
+	s.ReadTimeout = 100 * time.Millisecond
+	s.PollInterval = 10 * time.Millisecond
+	start := clock.Now()
+	n, err := readAsync(t, s, clock, make([]byte, 8), nil)
+	var timeout nango.SerialTimeoutError
+	if n != 0 || !errors.As(err, &timeout) {
+		t.Fatalf("expected a SerialTimeoutError, got %d, %v", n, err)
+	}
+	if waited := clock.Now().Sub(start); waited < s.ReadTimeout || waited > s.ReadTimeout+s.PollInterval {
+		t.Fatalf("expected the read to give up after %v, waited %v", s.ReadTimeout, waited)
+	}
+	for _, c := range f.log() {
+		if c != nango.MethodStreamAvailable {
+			t.Fatalf("expected only available polls, got %v", c)
+		}
+	}
+}
+
+func TestUartWrite(t *testing.T) {
+	f, s, _ := openUart(t)
+	n, err := s.Write(make([]byte, 70))
+	if err != nil || n != 70 {
+		t.Fatalf("expected 70 bytes written, got %d, %v", n, err)
+	}
+	if len(f.writes) != 3 || f.writes[0] != 32 || f.writes[1] != 32 || f.writes[2] != 6 {
+		t.Fatalf("expected writes of 32, 32 and 6 bytes, got %v", f.writes)
+	}
+
+	f.writes = nil
+	f.short = 16
+	n, err = s.Write(make([]byte, 40))
+	if err != io.ErrShortWrite || n != 31 {
+		t.Fatalf("expected io.ErrShortWrite after 31 bytes, got %d, %v", n, err)
+	}
+	if len(f.writes) != 1 {
+		t.Fatalf("expected writing to stop after the short chunk, got %v", f.writes)
+	}
+}
@@ -0,0 +1,79 @@
+package nango
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+//ErrAuthFailed is returned by Open when the board, or the bridge in front of
+//it, rejects the connection's shared secret or fails to prove it knows it
+var ErrAuthFailed = errors.New("authentication failed")
+
+//authNonceSize is the size in bytes of the nonces exchanged in the handshake
+const authNonceSize = 16
+
+//WithSharedSecret authenticates the connection with key when it is opened,
+//for transports exposed on a network. The board issues a nonce which the
+//connection answers along with a nonce of its own, and each side proves it
+//knows key with an HMAC-SHA256 over its role and both nonces, so neither side
+//can be impersonated without the key, nor a proof replayed or reflected from
+//another connection. Until authenticated the board rejects every other call.
+func WithSharedSecret(key []byte) Option {
+	return func(s *FirmwareConnection) {
+		s.secret = append([]byte(nil), key...)
+	}
+}
+
+//Roles bound into the proofs of the handshake, so the proof one side sends
+//can never serve as the other's
+const (
+	authRoleHost  = "host"
+	authRoleBoard = "board"
+)
+
+//authMAC returns the proof of role: the HMAC-SHA256 under key of role
+//followed by the board's and then the host's nonce
+func authMAC(key []byte, role string, boardNonce []byte, hostNonce []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(role))
+	m.Write(boardNonce)
+	m.Write(hostNonce)
+	return m.Sum(nil)
+}
+
+//authenticate runs the handshake if the connection has a shared secret
+func (s *FirmwareConnection) authenticate() error {
+	if s.secret == nil {
+		return nil
+	}
+	auth := &FirmwareClass{Conn: s, Id: StaticId, Namespace: NamespaceAuth}
+	challenge, err := auth.call(MethodAuthChallenge)
+	if err != nil {
+		return err
+	}
+	nonce, err := hex.DecodeString(challenge)
+	if err != nil || len(nonce) != authNonceSize {
+		return auth.responseError(MethodAuthChallenge, challenge, errors.New("expected a hex nonce"))
+	}
+	ours := make([]byte, authNonceSize)
+	if _, err := rand.Read(ours); err != nil {
+		return err
+	}
+	proof, err := auth.call(MethodAuthRespond, authMAC(s.secret, authRoleHost, nonce, ours), ours)
+	var exc *FirmwareException
+	if errors.As(err, &exc) {
+		return fmt.Errorf("%w: %s", ErrAuthFailed, exc.Reason)
+	}
+	if err != nil {
+		return err
+	}
+	theirs, err := hex.DecodeString(proof)
+	if err != nil || !hmac.Equal(theirs, authMAC(s.secret, authRoleBoard, nonce, ours)) {
+		return fmt.Errorf("%w: the board did not prove it knows the secret", ErrAuthFailed)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package nango
+
+//Batch queues the calls made by fn on a Pipeline and sends them to the
+//firmware as a single transfer once fn returns
+func (s *FirmwareConnection) Batch(fn func(p *Pipeline)) error {
+	p := s.Pipeline()
+	fn(p)
+	return p.Exec()
+}
@@ -0,0 +1,248 @@
+package nango
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//ChargePoint is a point on a discharge curve
+type ChargePoint struct {
+	Voltage float64
+	Percent float64
+}
+
+//Chemistry describes the discharge of a battery cell. Voltages are per cell
+//at rest, so readings under heavy load will read low.
+type Chemistry struct {
+	Name string
+	//Curve maps cell voltage to state of charge, in ascending order
+	Curve []ChargePoint
+	//Low and Critical are the default cell voltages of a BatteryMonitor's
+	//alerts
+	Low      float64
+	Critical float64
+}
+
+var (
+	//ChemistryLiPo is a lithium polymer or lithium ion cell
+	ChemistryLiPo = Chemistry{
+		Name: "LiPo",
+		Curve: []ChargePoint{
+			{3.27, 0}, {3.61, 5}, {3.69, 10}, {3.73, 20}, {3.77, 30}, {3.80, 40},
+			{3.84, 50}, {3.87, 60}, {3.95, 70}, {4.02, 80}, {4.11, 90}, {4.20, 100},
+		},
+		Low:      3.6,
+		Critical: 3.4,
+	}
+	//ChemistryLiFePO4 is a lithium iron phosphate cell
+	ChemistryLiFePO4 = Chemistry{
+		Name: "LiFePO4",
+		Curve: []ChargePoint{
+			{2.50, 0}, {3.00, 9}, {3.20, 14}, {3.22, 20}, {3.25, 30}, {3.26, 40},
+			{3.27, 50}, {3.28, 60}, {3.30, 70}, {3.32, 80}, {3.35, 90}, {3.40, 100},
+		},
+		Low:      3.1,
+		Critical: 2.9,
+	}
+	//ChemistryNiMH is a nickel metal hydride cell
+	ChemistryNiMH = Chemistry{
+		Name: "NiMH",
+		Curve: []ChargePoint{
+			{1.00, 0}, {1.10, 10}, {1.18, 20}, {1.22, 40}, {1.25, 60}, {1.28, 80}, {1.35, 95}, {1.40, 100},
+		},
+		Low:      1.1,
+		Critical: 1.0,
+	}
+	//ChemistryLeadAcid is a cell of a sealed lead acid battery, six to a 12V
+	//battery
+	ChemistryLeadAcid = Chemistry{
+		Name: "lead acid",
+		Curve: []ChargePoint{
+			{1.750, 0}, {1.885, 10}, {1.943, 20}, {1.968, 30}, {1.993, 40}, {2.017, 50},
+			{2.040, 60}, {2.062, 70}, {2.083, 80}, {2.103, 90}, {2.122, 100},
+		},
+		Low:      1.983,
+		Critical: 1.933,
+	}
+)
+
+//Percent returns the state of charge of a cell at voltage v by interpolating
+//the chemistry's discharge curve
+func (c Chemistry) Percent(v float64) float64 {
+	if len(c.Curve) == 0 {
+		return 0
+	}
+	if v <= c.Curve[0].Voltage {
+		return c.Curve[0].Percent
+	}
+	for i := 1; i < len(c.Curve); i++ {
+		lo, hi := c.Curve[i-1], c.Curve[i]
+		if v < hi.Voltage {
+			return lo.Percent + (v-lo.Voltage)/(hi.Voltage-lo.Voltage)*(hi.Percent-lo.Percent)
+		}
+	}
+	return c.Curve[len(c.Curve)-1].Percent
+}
+
+//VoltageDivider reads a battery voltage through a resistor divider on an
+//analog pin: ratio is (R1+R2)/R2 and vref the ADC reference voltage
+func VoltageDivider(api *ArduinoApi, pin string, ratio float64, vref float64) ReadFunc {
+	return func(ctx context.Context) (float64, error) {
+		v, err := api.AnalogRead(pin)
+		if err != nil {
+			return 0, err
+		}
+		return float64(v) / 1023 * vref * ratio, nil
+	}
+}
+
+//INA219Voltage reads a battery voltage as the bus voltage of an INA219
+func INA219Voltage(d *INA219) ReadFunc {
+	return func(ctx context.Context) (float64, error) {
+		return d.Voltage()
+	}
+}
+
+//BatteryLevel classifies a battery's charge
+type BatteryLevel int
+
+const (
+	BatteryOK BatteryLevel = iota
+	BatteryLow
+	BatteryCritical
+)
+
+func (l BatteryLevel) String() string {
+	return [...]string{"ok", "low", "critical"}[l]
+}
+
+//BatteryStatus is a reading of a BatteryMonitor
+type BatteryStatus struct {
+	Time    time.Time
+	Voltage float64
+	//CellVoltage is the voltage divided across the cells
+	CellVoltage float64
+	Percent     float64
+	Level       BatteryLevel
+	//Err is the error of the reading, if it failed. The other fields then
+	//hold the last good reading.
+	Err error
+}
+
+//BatteryMonitor reads a battery's voltage and delivers its status on C
+//whenever its level changes, so a mobile rig can warn at BatteryLow and shut
+//down gracefully at BatteryCritical. Events are dropped if the consumer falls
+//behind.
+type BatteryMonitor struct {
+	C <-chan BatteryStatus
+
+	Voltage   ReadFunc
+	Chemistry Chemistry
+	Cells     int
+	//Low and Critical are the cell voltages at and below which the battery
+	//is low or critical
+	Low      float64
+	Critical float64
+	//Hysteresis is how far, per cell, the voltage must recover above a
+	//threshold to leave its level, so sag under load doesn't flap alerts
+	Hysteresis float64
+	//Interval is how often the voltage is read
+	Interval time.Duration
+
+	c      chan BatteryStatus
+	mu     sync.Mutex
+	status BatteryStatus
+	sched  *Scheduler
+	name   string
+}
+
+//NewBatteryMonitor returns a monitor of a battery of cells of the given
+//chemistry, alerting at the chemistry's thresholds with hysteresis of 2% of
+//the low threshold
+func NewBatteryMonitor(voltage ReadFunc, chemistry Chemistry, cells int) *BatteryMonitor {
+	c := make(chan BatteryStatus, 4)
+	return &BatteryMonitor{
+		C:          c,
+		c:          c,
+		Voltage:    voltage,
+		Chemistry:  chemistry,
+		Cells:      cells,
+		Low:        chemistry.Low,
+		Critical:   chemistry.Critical,
+		Hysteresis: 0.02 * chemistry.Low,
+		Interval:   10 * time.Second,
+	}
+}
+
+//Step reads the voltage at now, delivering the status if the level changed
+func (b *BatteryMonitor) Step(ctx context.Context, now time.Time) error {
+	v, err := b.Voltage(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &b.status
+	s.Time, s.Err = now, err
+	if err != nil {
+		return err
+	}
+	cells := b.Cells
+	if cells < 1 {
+		cells = 1
+	}
+	s.Voltage = v
+	s.CellVoltage = v / float64(cells)
+	s.Percent = b.Chemistry.Percent(s.CellVoltage)
+	level := b.level(s.CellVoltage, s.Level)
+	if level == s.Level {
+		return nil
+	}
+	s.Level = level
+	select {
+	case b.c <- *s:
+	default:
+	}
+	return nil
+}
+
+//level classifies cell voltage v, coming from level prev
+func (b *BatteryMonitor) level(v float64, prev BatteryLevel) BatteryLevel {
+	switch {
+	case v <= b.Critical:
+		return BatteryCritical
+	case prev == BatteryCritical && v < b.Critical+b.Hysteresis:
+		return BatteryCritical
+	case v <= b.Low:
+		return BatteryLow
+	case prev >= BatteryLow && v < b.Low+b.Hysteresis:
+		return BatteryLow
+	}
+	return BatteryOK
+}
+
+//Status returns the latest reading
+func (b *BatteryMonitor) Status() BatteryStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+//Start reads the battery every Interval on s as the task called name
+func (b *BatteryMonitor) Start(s *Scheduler, name string) {
+	b.mu.Lock()
+	b.sched, b.name = s, name
+	b.mu.Unlock()
+	s.Every(name, b.Interval, func(ctx context.Context) error {
+		return b.Step(ctx, s.clock.Now())
+	})
+}
+
+//Stop stops reading the battery
+func (b *BatteryMonitor) Stop() {
+	b.mu.Lock()
+	s, name := b.sched, b.name
+	b.sched = nil
+	b.mu.Unlock()
+	if s != nil {
+		s.Remove(name)
+	}
+}
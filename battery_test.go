@@ -0,0 +1,82 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChemistryPercent(t *testing.T) {
+	for _, c := range []struct{ v, percent float64 }{
+		{3.0, 0}, {3.27, 0}, {3.82, 45}, {4.2, 100}, {4.3, 100},
+	} {
+		if p := ChemistryLiPo.Percent(c.v); math.Abs(p-c.percent) > 1e-9 {
+			t.Errorf("expected %v%% at %vV, got %v", c.percent, c.v, p)
+		}
+	}
+}
+
+func TestVoltageDivider(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond(NamespaceArduino, MethodAnalogRead, "682")
+	v, err := VoltageDivider(NewArduinoApi(conn), "A0", 3, 5)(context.Background())
+	if err != nil || math.Abs(v-10) > 0.01 {
+		t.Fatalf("expected 10V, got %v (%v)", v, err)
+	}
+}
+
+func TestBatteryMonitor(t *testing.T) {
+	volts := 0.0
+	var readErr error
+	b := NewBatteryMonitor(func(ctx context.Context) (float64, error) { return volts, readErr }, ChemistryLiPo, 3)
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	step := func(v float64) {
+		t.Helper()
+		volts = v
+		b.Step(context.Background(), now)
+		now = now.Add(time.Second)
+	}
+	expect := func(level BatteryLevel) {
+		t.Helper()
+		select {
+		case s := <-b.C:
+			if s.Level != level {
+				t.Fatalf("expected %v, got %+v", level, s)
+			}
+		default:
+			t.Fatalf("expected %v event", level)
+		}
+	}
+	expectNone := func() {
+		t.Helper()
+		select {
+		case s := <-b.C:
+			t.Fatalf("unexpected event %+v", s)
+		default:
+		}
+	}
+
+	step(12.0)
+	expectNone()
+	if s := b.Status(); s.CellVoltage != 4 || s.Level != BatteryOK {
+		t.Fatalf("unexpected status %+v", s)
+	}
+	step(10.8)
+	expect(BatteryLow)
+	//recovering within the hysteresis stays low
+	step(10.85)
+	expectNone()
+	step(10.1)
+	expect(BatteryCritical)
+	step(11.2)
+	expect(BatteryOK)
+
+	readErr = errors.New("timeout")
+	step(0)
+	if s := b.Status(); s.Err != readErr || s.Voltage != 11.2 {
+		t.Fatalf("expected last good reading kept on error, got %+v", s)
+	}
+	expectNone()
+}
@@ -0,0 +1,171 @@
+package nango
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+//BME280Address is the default address of a BME280, 0x77 if SDO is pulled high
+const BME280Address I2CAddress = 0x76
+
+const (
+	bme280ChipID    = 0x60
+	bme280RegID     = 0xd0
+	bme280RegCalib0 = 0x88
+	bme280RegCalib1 = 0xe1
+	bme280RegHum    = 0xf2
+	bme280RegMeas   = 0xf4
+	bme280RegConfig = 0xf5
+	bme280RegData   = 0xf7
+)
+
+//Environment is a reading of the ambient conditions
+type Environment struct {
+	//Temperature is in degrees Celsius
+	Temperature float64
+	//Pressure is in pascals
+	Pressure float64
+	//Humidity is the relative humidity in percent
+	Humidity float64
+}
+
+//bme280Calibration holds the trimming parameters stored in the sensor's NVM
+type bme280Calibration struct {
+	t1             uint16
+	t2, t3         int16
+	p1             uint16
+	p2, p3, p4, p5 int16
+	p6, p7, p8, p9 int16
+	h1, h3         uint8
+	h2, h4, h5     int16
+	h6             int8
+}
+
+//BME280 reads temperature, pressure and humidity from a Bosch BME280 on the
+//I2C bus
+type BME280 struct {
+	master  *I2CMaster
+	address I2CAddress
+	cal     bme280Calibration
+}
+
+//NewBME280 registers a BME280 at address on the bus, reads its calibration
+//and starts it measuring continuously with 1x oversampling
+func NewBME280(m *I2CMaster, address I2CAddress) (*BME280, error) {
+	b := &BME280{master: m, address: address}
+	if err := m.RegisterAndProbe(b); err != nil {
+		return nil, err
+	}
+	if err := b.init(); err != nil {
+		m.Unregister(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *BME280) init() error {
+	id, err := b.readRegisters(bme280RegID, 1)
+	if err != nil {
+		return err
+	}
+	if id[0] != bme280ChipID {
+		return fmt.Errorf("bme280: unexpected chip id 0x%02x at 0x%02x", id[0], int(b.address))
+	}
+	c0, err := b.readRegisters(bme280RegCalib0, 26)
+	if err != nil {
+		return err
+	}
+	c1, err := b.readRegisters(bme280RegCalib1, 7)
+	if err != nil {
+		return err
+	}
+	b.cal = parseBME280Calibration(c0, c1)
+	//humidity oversampling only takes effect after ctrl_meas is written.
+	//Standby 1000ms between measurements in normal mode.
+	for _, w := range [][]byte{{bme280RegHum, 0x01}, {bme280RegMeas, 0x27}, {bme280RegConfig, 0xa0}} {
+		if err := b.master.Send(b.address, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Address implements I2CDriver
+func (b *BME280) Address() I2CAddress {
+	return b.address
+}
+
+func (b *BME280) readRegisters(reg byte, n int) ([]byte, error) {
+	if err := b.master.Send(b.address, []byte{reg}); err != nil {
+		return nil, err
+	}
+	data, err := b.master.Request(b.address, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != n {
+		return nil, fmt.Errorf("bme280: read %d of %d bytes from register 0x%02x", len(data), n, reg)
+	}
+	return data, nil
+}
+
+//Read returns the latest measurement
+func (b *BME280) Read() (Environment, error) {
+	d, err := b.readRegisters(bme280RegData, 8)
+	if err != nil {
+		return Environment{}, err
+	}
+	adcP := int32(d[0])<<12 | int32(d[1])<<4 | int32(d[2])>>4
+	adcT := int32(d[3])<<12 | int32(d[4])<<4 | int32(d[5])>>4
+	adcH := int32(d[6])<<8 | int32(d[7])
+	return b.cal.compensate(adcT, adcP, adcH), nil
+}
+
+func parseBME280Calibration(c0, c1 []byte) bme280Calibration {
+	le := binary.LittleEndian
+	s := func(b []byte) int16 { return int16(le.Uint16(b)) }
+	return bme280Calibration{
+		t1: le.Uint16(c0[0:]), t2: s(c0[2:]), t3: s(c0[4:]),
+		p1: le.Uint16(c0[6:]), p2: s(c0[8:]), p3: s(c0[10:]), p4: s(c0[12:]), p5: s(c0[14:]),
+		p6: s(c0[16:]), p7: s(c0[18:]), p8: s(c0[20:]), p9: s(c0[22:]),
+		h1: c0[25],
+		h2: s(c1[0:]),
+		h3: c1[2],
+		//h4 and h5 are 12 bit values sharing the nibbles of 0xe5
+		h4: int16(int8(c1[3]))<<4 | int16(c1[4]&0x0f),
+		h5: int16(int8(c1[5]))<<4 | int16(c1[4]>>4),
+		h6: int8(c1[6]),
+	}
+}
+
+//compensate converts raw readings with the floating point formulas from the
+//datasheet
+func (c bme280Calibration) compensate(adcT, adcP, adcH int32) Environment {
+	var e Environment
+	v1 := (float64(adcT)/16384 - float64(c.t1)/1024) * float64(c.t2)
+	v2 := float64(adcT)/131072 - float64(c.t1)/8192
+	v2 = v2 * v2 * float64(c.t3)
+	fine := v1 + v2
+	e.Temperature = fine / 5120
+
+	v1 = fine/2 - 64000
+	v2 = v1 * v1 * float64(c.p6) / 32768
+	v2 += v1 * float64(c.p5) * 2
+	v2 = v2/4 + float64(c.p4)*65536
+	v1 = (float64(c.p3)*v1*v1/524288 + float64(c.p2)*v1) / 524288
+	v1 = (1 + v1/32768) * float64(c.p1)
+	if v1 != 0 {
+		p := 1048576 - float64(adcP)
+		p = (p - v2/4096) * 6250 / v1
+		v1 = float64(c.p9) * p * p / 2147483648
+		v2 = p * float64(c.p8) / 32768
+		e.Pressure = p + (v1+v2+float64(c.p7))/16
+	}
+
+	h := fine - 76800
+	h = (float64(adcH) - (float64(c.h4)*64 + float64(c.h5)/16384*h)) *
+		(float64(c.h2) / 65536 * (1 + float64(c.h6)/67108864*h*(1+float64(c.h3)/67108864*h)))
+	h *= 1 - float64(c.h1)*h/524288
+	e.Humidity = clamp(h, 0, 100)
+	return e
+}
@@ -0,0 +1,36 @@
+package nango
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBME280Compensate(t *testing.T) {
+	//the worked example from the BMP280 datasheet, which shares the
+	//temperature and pressure compensation
+	c := bme280Calibration{
+		t1: 27504, t2: 26435, t3: -1000,
+		p1: 36477, p2: -10685, p3: 3024, p4: 2855, p5: 140, p6: -7, p7: 15500, p8: -14600, p9: 6000,
+	}
+	e := c.compensate(519888, 415148, 0)
+	if math.Abs(e.Temperature-25.08) > 0.01 {
+		t.Errorf("expected 25.08°C, got %v", e.Temperature)
+	}
+	if math.Abs(e.Pressure-100653.27) > 0.1 {
+		t.Errorf("expected 100653.27Pa, got %v", e.Pressure)
+	}
+	if e.Humidity != 0 {
+		t.Errorf("expected humidity clamped to 0, got %v", e.Humidity)
+	}
+}
+
+func TestParseBME280Calibration(t *testing.T) {
+	c0 := make([]byte, 26)
+	c0[0], c0[1] = 0x70, 0x6b
+	c0[25] = 75
+	c1 := []byte{0x6a, 0x01, 0x00, 0x13, 0x25, 0x03, 0x1e}
+	c := parseBME280Calibration(c0, c1)
+	if c.t1 != 27504 || c.h1 != 75 || c.h2 != 362 || c.h4 != 0x135 || c.h5 != 0x32 || c.h6 != 30 {
+		t.Fatalf("unexpected calibration %+v", c)
+	}
+}
@@ -0,0 +1,128 @@
+package nango
+
+import (
+	"io"
+	"sync"
+
+	"github.com/justinsantoro/nango/serial"
+)
+
+//Board bundles the subsystems of one board behind a single connection. Each
+//subsystem is created the first time it is requested and shared afterwards.
+type Board struct {
+	conn Conn
+	//Model, if set before the subsystems are first used, describes the
+	//board's pins so calls on pins which don't support them are rejected
+	Model  *BoardModel
+	mu     sync.Mutex
+	pins   *ArduinoApi
+	i2c    *I2CMaster
+	spi    *Spi
+	eeprom *EEPROM
+	servos map[string]*Servo
+	//drivers created on the board with NewDriver
+	drivers []attachedDriver
+}
+
+//NewBoard returns a Board using conn, which should already be open
+func NewBoard(conn Conn) *Board {
+	return &Board{
+		conn:   conn,
+		servos: make(map[string]*Servo),
+	}
+}
+
+//OpenBoard opens a connection on the serial port described by serialConf and
+//returns a Board using it
+func OpenBoard(serialConf *serial.Config, opts ...Option) (*Board, error) {
+	conn := NewFirmwareConnection(serialConf, opts...)
+	err := conn.Open()
+	if err != nil {
+		return nil, err
+	}
+	return NewBoard(conn), nil
+}
+
+//Conn returns the connection the board uses
+func (b *Board) Conn() Conn {
+	return b.conn
+}
+
+//Pins gives access to the digital and analog pins
+func (b *Board) Pins() *ArduinoApi {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pins == nil {
+		b.pins = NewArduinoApi(b.conn)
+		b.pins.Model = b.Model
+	}
+	return b.pins
+}
+
+//I2C returns the master of the board's I2C bus
+func (b *Board) I2C() *I2CMaster {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.i2c == nil {
+		b.i2c = NewI2cMaster(NewWire(b.conn))
+	}
+	return b.i2c
+}
+
+//SPI returns the board's SPI bus
+func (b *Board) SPI() *Spi {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spi == nil {
+		b.spi = NewSpi(b.conn)
+	}
+	return b.spi
+}
+
+//EEPROM returns the board's EEPROM
+func (b *Board) EEPROM() *EEPROM {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.eeprom == nil {
+		b.eeprom = NewEEPROM(b.conn)
+	}
+	return b.eeprom
+}
+
+//Servo returns the servo attached to pin, attaching one the first time the
+//pin is requested
+func (b *Board) Servo(pin string) (*Servo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.servos[pin]; ok {
+		return s, nil
+	}
+	s, err := NewServo(b.conn, pin)
+	if err != nil {
+		return nil, err
+	}
+	b.servos[pin] = s
+	return s, nil
+}
+
+//Close detaches the board's servos and closes its connection if the
+//connection can be closed
+func (b *Board) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var err error
+	for pin, s := range b.servos {
+		errServo := s.Close()
+		if err == nil {
+			err = errServo
+		}
+		delete(b.servos, pin)
+	}
+	if c, ok := b.conn.(io.Closer); ok {
+		errClose := c.Close()
+		if err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package nango
+
+import (
+	"encoding/hex"
+	"sync"
+)
+
+//Buffer is a pooled byte buffer holding a response from the firmware. Bytes
+//is only valid until Release is called, after which the buffer is reused for
+//later responses.
+type Buffer struct {
+	b []byte
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{b: make([]byte, 0, 64)}
+	},
+}
+
+func getBuffer() *Buffer {
+	b := bufferPool.Get().(*Buffer)
+	b.b = b.b[:0]
+	return b
+}
+
+//Bytes returns the contents of the buffer
+func (b *Buffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.b
+}
+
+//String returns a copy of the contents of the buffer as a string
+func (b *Buffer) String() string {
+	if b == nil {
+		return ""
+	}
+	return string(b.b)
+}
+
+//Copy returns a copy of the contents of the buffer owned by the caller
+func (b *Buffer) Copy() []byte {
+	return append([]byte(nil), b.Bytes()...)
+}
+
+//Release returns the buffer to the pool. The buffer must not be used afterwards.
+func (b *Buffer) Release() {
+	if b == nil {
+		return
+	}
+	//don't hold on to unusually large buffers
+	if cap(b.b) > 64*1024 {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+//decodeHex replaces the hex encoded contents of the buffer with the bytes
+//they encode
+func (b *Buffer) decodeHex() error {
+	n, err := hex.Decode(b.b, b.b)
+	if err != nil {
+		return err
+	}
+	b.b = b.b[:n]
+	return nil
+}
+
+//readBuffer reads the next response from the firmware into a pooled buffer
+func readBuffer(conn Conn) (*Buffer, error) {
+	line, err := conn.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if e := parseException(line); e != nil {
+		return nil, e
+	}
+	b := getBuffer()
+	b.b = append(b.b, line...)
+	return b, nil
+}
@@ -0,0 +1,44 @@
+package nango
+
+import "context"
+
+//Response is the set of types a response can be parsed as by Call
+type Response interface {
+	int | float64 | bool | string | []byte
+}
+
+//Call calls method and parses the response as a T. []byte responses are
+//decoded from hex and bools from the connection's dialect.
+func Call[T Response](f *FirmwareClass, method string, args ...any) (T, error) {
+	return CallContext[T](context.Background(), f, method, args...)
+}
+
+//CallContext is like Call but the call is abandoned if ctx is done before it
+//has been sent to the firmware
+func CallContext[T Response](ctx context.Context, f *FirmwareClass, method string, args ...any) (_ T, err error) {
+	defer f.recoverResponse(method, &err)
+	var zero T
+	b, err := methodCallBuffer(ctx, f, method, args)
+	if err != nil {
+		return zero, err
+	}
+	defer b.Release()
+	var v any
+	switch any(zero).(type) {
+	case int:
+		v, err = f.parseInt(method, b.String())
+	case float64:
+		v, err = f.parseFloat(method, b.String())
+	case bool:
+		v, err = f.parseBool(method, b.String())
+	case string:
+		v = b.String()
+	case []byte:
+		err = f.decodeHex(method, b)
+		v = b.Copy()
+	}
+	if err != nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
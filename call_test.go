@@ -0,0 +1,36 @@
+package nango
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond("T", "int", "42")
+	lb.Respond("T", "float", "2.5")
+	lb.Respond("T", "bool", "True")
+	lb.Respond("T", "bytes", "cafe")
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	if v, err := Call[int](f, "int"); err != nil || v != 42 {
+		t.Fatalf("expected 42, got %d (%v)", v, err)
+	}
+	if v, err := Call[float64](f, "float"); err != nil || v != 2.5 {
+		t.Fatalf("expected 2.5, got %v (%v)", v, err)
+	}
+	if v, err := Call[bool](f, "bool"); err != nil || !v {
+		t.Fatalf("expected true, got %v (%v)", v, err)
+	}
+	if v, err := Call[string](f, "echo", "x", 1); err != nil || v != "x,1" {
+		t.Fatalf("expected x,1, got %q (%v)", v, err)
+	}
+	if v, err := Call[[]byte](f, "bytes"); err != nil || !bytes.Equal(v, []byte{0xca, 0xfe}) {
+		t.Fatalf("expected cafe, got %x (%v)", v, err)
+	}
+	_, err := Call[int](f, "float")
+	var fwErr *FirmwareError
+	if !errors.As(err, &fwErr) || fwErr.Response != "2.5" {
+		t.Fatalf("expected FirmwareError, got %v", err)
+	}
+}
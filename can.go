@@ -0,0 +1,105 @@
+package nango
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	CanErrorActive = iota
+	CanErrorPassive
+	CanBusOff
+	CanRecovering
+)
+
+//CanFrame is a classic CAN data or remote frame
+type CanFrame struct {
+	Id       uint32
+	Extended bool
+	Remote   bool
+	Data     []byte
+}
+
+//Can gives access to the CAN controller built into boards such as the Due or
+//the TWAI controller of the ESP32. It is independent of SPI attached
+//controllers such as the MCP2515.
+type Can struct {
+	*FirmwareClass
+}
+
+func NewCan(conn Conn) *Can {
+	return &Can{
+		&FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceCAN,
+		},
+	}
+}
+
+//Begin starts the controller at the given bitrate in bits per second
+func (c *Can) Begin(bitrate int) error {
+	return c.CallAndReturnNothing(MethodCANBegin, bitrate)
+}
+
+//End stops the controller
+func (c *Can) End() error {
+	return c.CallAndReturnNothing(MethodCANEnd)
+}
+
+//Send queues a frame for transmission
+func (c *Can) Send(frame CanFrame) error {
+	if len(frame.Data) > 8 {
+		return fmt.Errorf("can frame data length %d exceeds 8 bytes", len(frame.Data))
+	}
+	if frame.Id > 0x7FF && !frame.Extended || frame.Id > 0x1FFFFFFF {
+		return fmt.Errorf("can frame id 0x%x out of range", frame.Id)
+	}
+	//ids are sent as strings since extended ids overflow the firmware's int
+	return c.CallAndReturnNothing(MethodCANSend, strconv.FormatUint(uint64(frame.Id), 10), frame.Extended, frame.Remote, frame.Data)
+}
+
+//Receive returns the next received frame. ok is false if no frame is waiting.
+func (c *Can) Receive() (frame CanFrame, ok bool, err error) {
+	defer c.recoverResponse(MethodCANReceive, &err)
+	s, err := c.call(MethodCANReceive)
+	if err != nil || s == "" {
+		return
+	}
+	//frames are encoded as id,extended,remote,hexdata
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		err = c.responseError(MethodCANReceive, s, errors.New("malformed frame"))
+		return
+	}
+	id, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		err = c.responseError(MethodCANReceive, s, err)
+		return
+	}
+	frame.Id = uint32(id)
+	frame.Extended = fields[1] == "1"
+	frame.Remote = fields[2] == "1"
+	frame.Data, err = hex.DecodeString(fields[3])
+	if err != nil {
+		err = c.responseError(MethodCANReceive, s, err)
+		return
+	}
+	ok = true
+	return
+}
+
+//State returns the controller error state, one of CanErrorActive,
+//CanErrorPassive, CanBusOff or CanRecovering
+func (c *Can) State() (int, error) {
+	return c.CallAndReturnInt(MethodCANState)
+}
+
+//Recover initiates bus-off recovery. The controller returns to
+//CanErrorActive once it has observed the required recessive bits.
+func (c *Can) Recover() error {
+	return c.CallAndReturnNothing(MethodCANRecover)
+}
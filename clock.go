@@ -0,0 +1,37 @@
+package nango
+
+import (
+	"time"
+)
+
+//Clock is the source of time used by a connection for timeouts, retries,
+//coalescing and latency measurement. Tests can substitute a fake clock with
+//WithClock to control these deterministically.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+//realClock is the system clock
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+//WithClock sets the clock the connection measures time with
+func WithClock(c Clock) Option {
+	return func(s *FirmwareConnection) {
+		s.clock = c
+	}
+}
+
+//clockOf returns the clock of conn if it is a FirmwareConnection, otherwise
+//the system clock
+func clockOf(conn Conn) Clock {
+	if s := connectionOf(conn); s != nil && s.clock != nil {
+		return s.clock
+	}
+	return realClock{}
+}
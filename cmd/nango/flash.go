@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/justinsantoro/nango/flash"
+)
+
+func flashCommand(args []string) error {
+	fs := flag.NewFlagSet("flash", flag.ContinueOnError)
+	model := fs.String("board", "", "board model, detected from the port if not given")
+	tool := fs.String("tool", "", "upload tool, avrdude or arduino-cli")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: nango flash [--board model] [--tool tool] <firmware.hex>")
+	}
+	port, err := selectPort()
+	if err != nil {
+		return err
+	}
+	err = flash.Flash(flash.Options{
+		Port:   port,
+		Model:  *model,
+		Hex:    fs.Arg(0),
+		Tool:   *tool,
+		Output: os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+	//check the board answers on the new firmware
+	b, err := openBoard()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	_, err = b.Pins().Millis()
+	if err != nil {
+		return fmt.Errorf("firmware uploaded but the board does not respond: %w", err)
+	}
+	fmt.Fprintln(stdout, "firmware uploaded and responding")
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+)
+
+func i2cCommand(args []string) error {
+	if len(args) != 1 || args[0] != "scan" {
+		return fmt.Errorf("usage: nango i2c scan")
+	}
+	b, err := openBoard()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	addrs, err := b.I2C().Scan()
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		fmt.Fprintf(stdout, "0x%02x\n", int(a))
+	}
+	return nil
+}
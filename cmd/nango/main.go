@@ -0,0 +1,157 @@
+//Command nango controls a board running the nango firmware from the command
+//line, so wiring can be checked without writing a Go program.
+//
+//	nango ports list
+//	nango pin mode D13 output
+//	nango pin write D13 high
+//	nango pin read A0
+//	nango i2c scan
+//	nango monitor A0 --interval 100ms
+//	nango flash --board uno firmware.hex
+//
+//Commands registered by plugins are available when the plugin is imported
+//into a build of this command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/serial"
+)
+
+const usage = `usage: nango [flags] <command> [arguments]
+
+commands:
+  ports list                       list candidate serial ports
+  pin mode <pin> <mode>            set mode to input, output or input_pullup
+  pin write <pin> <value>          write high, low or an analog value 0-255
+  pin read <pin>                   read a digital pin, or an analog pin A0-A15
+  i2c scan                         list the addresses of devices on the I2C bus
+  monitor <pin> [--interval d]     print the value of a pin until interrupted
+  flash [--board b] [--tool t] <hex>
+                                   upload firmware with avrdude or arduino-cli
+`
+
+var (
+	portName    = flag.String("port", os.Getenv("NANGO_PORT"), "serial port, defaults to $NANGO_PORT or the first port found")
+	baud        = flag.Int("baud", 115200, "baud rate")
+	readTimeout = flag.Duration("timeout", 2*time.Second, "response timeout")
+	resetDelay  = flag.Duration("reset-delay", 2*time.Second, "time to wait for the board to reset after connecting")
+)
+
+var (
+	//stdout is where commands print their results
+	stdout io.Writer = os.Stdout
+	//openBoard connects to the board the commands run on
+	openBoard = openSerialBoard
+)
+
+type command func(args []string) error
+
+var commands = map[string]command{
+	"ports":   portsCommand,
+	"pin":     pinCommand,
+	"i2c":     i2cCommand,
+	"monitor": monitorCommand,
+	"flash":   flashCommand,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		printPluginUsage()
+		fmt.Fprint(flag.CommandLine.Output(), "\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		cmd, ok = pluginCommand(flag.Arg(0))
+	}
+	if !ok {
+		fmt.Fprintf(os.Stderr, "nango: unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+	err := cmd(flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nango: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+//pluginCommand returns the command registered by a plugin as name
+func pluginCommand(name string) (command, bool) {
+	pc, ok := nango.PluginCommandFor(name)
+	if !ok {
+		return nil, false
+	}
+	return func(args []string) error {
+		return pc.Run(openBoard, args)
+	}, true
+}
+
+//printPluginUsage lists the commands registered by plugins
+func printPluginUsage() {
+	caps := nango.Capabilities(nango.CapabilityCommand)
+	if len(caps) == 0 {
+		return
+	}
+	out := flag.CommandLine.Output()
+	fmt.Fprint(out, "\nplugin commands:\n")
+	for _, c := range caps {
+		name := strings.TrimPrefix(c, nango.CapabilityCommand+":")
+		pc, _ := nango.PluginCommandFor(name)
+		fmt.Fprintf(out, "  %s\n", pc.Usage)
+	}
+}
+
+//openSerialBoard connects to the board on the selected port
+func openSerialBoard() (*nango.Board, error) {
+	name, err := selectPort()
+	if err != nil {
+		return nil, err
+	}
+	return nango.OpenBoard(&serial.Config{Name: name, Baud: *baud},
+		nango.WithReadTimeout(*readTimeout),
+		nango.WithSleepAfterConnect(*resetDelay))
+}
+
+//selectPort returns the port given with -port, or the first port found
+func selectPort() (string, error) {
+	if *portName != "" {
+		return *portName, nil
+	}
+	ports, err := listPorts()
+	if err != nil {
+		return "", err
+	}
+	if len(ports) == 0 {
+		return "", fmt.Errorf("no serial ports found: use -port")
+	}
+	return ports[0], nil
+}
+
+func portsCommand(args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return fmt.Errorf("usage: nango ports list")
+	}
+	ports, err := listPorts()
+	if err != nil {
+		return err
+	}
+	for _, p := range ports {
+		fmt.Fprintln(stdout, p)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//useLoopback makes the commands run on a board connected to lb and returns
+//the buffer they print to
+func useLoopback(t *testing.T, lb *nango.Loopback) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	prevStdout, prevOpen := stdout, openBoard
+	stdout, openBoard = &out, func() (*nango.Board, error) {
+		conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+		if err := conn.Open(); err != nil {
+			return nil, err
+		}
+		return nango.NewBoard(conn), nil
+	}
+	t.Cleanup(func() {
+		stdout, openBoard = prevStdout, prevOpen
+	})
+	return &out
+}
+
+func TestPinCommand(t *testing.T) {
+	lb := nango.NewLoopback()
+	var calls []string
+	record := func(response string) nango.LoopbackHandler {
+		return func(c nango.LoopbackCall) (string, bool) {
+			calls = append(calls, c.Method+" "+strings.Join(c.Args, ","))
+			return response, true
+		}
+	}
+	lb.Handle(nango.NamespaceArduino, nango.MethodPinMode, record("0"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodDigitalWrite, record("0"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodAnalogWrite, record("0"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodDigitalRead, record("1"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodAnalogRead, record("512"))
+	out := useLoopback(t, lb)
+
+	for _, args := range [][]string{
+		{"mode", "d13", "OUTPUT"},
+		{"write", "D13", "high"},
+		{"write", "D9", "128"},
+		{"read", "D2"},
+		{"read", "a0"},
+	} {
+		if err := pinCommand(args); err != nil {
+			t.Fatalf("%v: %s", args, err)
+		}
+	}
+	expected := []string{
+		nango.MethodPinMode + " D13,1",
+		nango.MethodDigitalWrite + " D13,1",
+		nango.MethodAnalogWrite + " D9,128",
+		nango.MethodDigitalRead + " D2",
+		nango.MethodAnalogRead + " A0",
+	}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Fatalf("expected calls %q, got %q", expected, calls)
+	}
+	if out.String() != "1\n512\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	for _, args := range [][]string{
+		{"mode", "D13"},
+		{"mode", "D13", "analog"},
+		{"write", "D9", "256"},
+		{"write", "D9", "on"},
+		{"toggle", "D13"},
+	} {
+		if err := pinCommand(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestI2CCommand(t *testing.T) {
+	lb := nango.NewLoopback()
+	var addr string
+	lb.Handle(nango.NamespaceWire, nango.MethodWireBeginTransmission, func(c nango.LoopbackCall) (string, bool) {
+		addr = c.Args[0]
+		return "0", true
+	})
+	lb.Handle(nango.NamespaceWire, nango.MethodWireEndTransmission, func(c nango.LoopbackCall) (string, bool) {
+		if addr == "118" || addr == "119" {
+			return "0", true
+		}
+		return "2", true
+	})
+	out := useLoopback(t, lb)
+	if err := i2cCommand([]string{"scan"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "0x76\n0x77\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if err := i2cCommand(nil); err == nil {
+		t.Fatal("expected a usage error")
+	}
+}
+
+func TestIsAnalog(t *testing.T) {
+	for pin, analog := range map[string]bool{"A0": true, "A15": true, "D13": false, "13": false, "AX": false, "A": false} {
+		if isAnalog(pin) != analog {
+			t.Errorf("isAnalog(%s): expected %v", pin, analog)
+		}
+	}
+}
+
+func TestSelectPort(t *testing.T) {
+	defer func(name string) { *portName = name }(*portName)
+	*portName = "/dev/ttyTEST"
+	if p, err := selectPort(); err != nil || p != "/dev/ttyTEST" {
+		t.Fatalf("expected the port given with -port, got %q, %v", p, err)
+	}
+}
+
+func TestUnknownCommands(t *testing.T) {
+	if _, ok := pluginCommand("no-such-command"); ok {
+		t.Fatal("expected no plugin command")
+	}
+	if err := portsCommand([]string{"show"}); err == nil {
+		t.Fatal("expected a usage error")
+	}
+}
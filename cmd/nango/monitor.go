@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+func monitorCommand(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: nango monitor <pin> [--interval d]")
+	}
+	pin := strings.ToUpper(args[0])
+	fs := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	interval := fs.Duration("interval", 500*time.Millisecond, "time between readings")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	b, err := openBoard()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	api := b.Pins()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	t := time.NewTicker(*interval)
+	defer t.Stop()
+	for {
+		v, err := readPin(api, pin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\t%s\t%d\n", time.Now().Format("15:04:05.000"), pin, v)
+		select {
+		case <-interrupt:
+			return nil
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/justinsantoro/nango"
+)
+
+func pinCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: nango pin mode|write|read <pin> [value]")
+	}
+	b, err := openBoard()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	api := b.Pins()
+	pin := strings.ToUpper(args[1])
+	switch args[0] {
+	case "mode":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: nango pin mode <pin> input|output|input_pullup")
+		}
+		mode, err := nango.ParsePinMode(strings.ToLower(args[2]))
+		if err != nil {
+			return err
+		}
+		return api.PinMode(pin, mode)
+	case "write":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: nango pin write <pin> high|low|0-255")
+		}
+		switch strings.ToLower(args[2]) {
+		case "high":
+			return api.DigitalWrite(pin, nango.PinHigh)
+		case "low":
+			return api.DigitalWrite(pin, nango.PinLow)
+		}
+		v, err := strconv.Atoi(args[2])
+		if err != nil || v < 0 || v > 255 {
+			return fmt.Errorf("invalid pin value %q", args[2])
+		}
+		return api.AnalogWrite(pin, v)
+	case "read":
+		v, err := readPin(api, pin)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, v)
+		return nil
+	}
+	return fmt.Errorf("unknown pin command %q", args[0])
+}
+
+//readPin reads an analog pin if pin names one, otherwise a digital pin
+func readPin(api *nango.ArduinoApi, pin string) (int, error) {
+	if isAnalog(pin) {
+		return api.AnalogRead(pin)
+	}
+	return api.DigitalRead(pin)
+}
+
+func isAnalog(pin string) bool {
+	if !strings.HasPrefix(pin, "A") {
+		return false
+	}
+	_, err := strconv.Atoi(pin[1:])
+	return err == nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+//portPatterns are the device names USB serial adapters and boards appear as
+var portPatterns = map[string][]string{
+	"linux":   {"/dev/ttyACM*", "/dev/ttyUSB*", "/dev/ttyAMA*"},
+	"darwin":  {"/dev/cu.usbmodem*", "/dev/cu.usbserial*", "/dev/cu.wchusbserial*"},
+	"freebsd": {"/dev/cuaU*"},
+}
+
+//listPorts returns the serial ports a board may be connected to
+func listPorts() ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return listWindowsPorts(), nil
+	}
+	var ports []string
+	for _, pattern := range portPatterns[runtime.GOOS] {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, matches...)
+	}
+	sort.Strings(ports)
+	return ports, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+//listWindowsPorts returns the COM ports which can be opened
+func listWindowsPorts() []string {
+	var ports []string
+	for i := 1; i <= 32; i++ {
+		name := fmt.Sprintf("COM%d", i)
+		f, err := os.OpenFile(`\\.\`+name, os.O_RDWR, 0)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		ports = append(ports, name)
+	}
+	return ports
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/serial"
+)
+
+var pinValues = map[string]int{
+	"":     -1,
+	"low":  nango.PinLow,
+	"high": nango.PinHigh,
+}
+
+//Board is a nango Board whose pins and I2C devices are looked up by the names
+//given in its configuration
+type Board struct {
+	*nango.Board
+	Config  *Config
+	pins    map[string]*Pin
+	devices map[string]*I2CDevice
+}
+
+//Pin is a named pin
+type Pin struct {
+	Name string
+	Pin  string
+	Mode int
+	api  *nango.ArduinoApi
+}
+
+func (p *Pin) DigitalWrite(val int) error {
+	return p.api.DigitalWrite(p.Pin, val)
+}
+
+func (p *Pin) DigitalRead() (int, error) {
+	return p.api.DigitalRead(p.Pin)
+}
+
+func (p *Pin) AnalogWrite(val int) error {
+	return p.api.AnalogWrite(p.Pin, val)
+}
+
+func (p *Pin) AnalogRead() (int, error) {
+	return p.api.AnalogRead(p.Pin)
+}
+
+//I2CDevice is a named I2C device. It is registered as a driver on the board's
+//I2C master.
+type I2CDevice struct {
+	Name    string
+	address nango.I2CAddress
+	master  *nango.I2CMaster
+}
+
+func (d *I2CDevice) Address() nango.I2CAddress {
+	return d.address
+}
+
+//Send writes data to the device
+func (d *I2CDevice) Send(data []byte) error {
+	return d.master.Send(d.address, data)
+}
+
+//Request reads quantity bytes from the device
+func (d *I2CDevice) Request(quantity int) ([]byte, error) {
+	return d.master.Request(d.address, quantity)
+}
+
+//Open connects to the board described by c and configures its pins
+func Open(c *Config) (*Board, error) {
+	var opts []nango.Option
+	if c.ReadTimeout > 0 {
+		opts = append(opts, nango.WithReadTimeout(time.Duration(c.ReadTimeout)))
+	}
+	if c.SleepAfterConnect > 0 {
+		opts = append(opts, nango.WithSleepAfterConnect(time.Duration(c.SleepAfterConnect)))
+	}
+	nb, err := nango.OpenBoard(&serial.Config{Name: c.Port, Baud: c.Baud}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(nb, c)
+	if err != nil {
+		nb.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+//New configures the pins and I2C devices described by c on an open board
+func New(nb *nango.Board, c *Config) (*Board, error) {
+	if c.Model != "" {
+		m, ok := nango.BoardModels[c.Model]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown board model %q", c.Model)
+		}
+		nb.Model = m
+	}
+	b := &Board{
+		Board:   nb,
+		Config:  c,
+		pins:    make(map[string]*Pin),
+		devices: make(map[string]*I2CDevice),
+	}
+	api := nb.Pins()
+	for name, pc := range c.Pins {
+		mode, err := nango.ParsePinMode(pc.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("config: pin %q: %w", name, err)
+		}
+		p := &Pin{Name: name, Pin: pc.Pin, Mode: mode, api: api}
+		//the initial value is written first so an output never drives the
+		//wrong level, even briefly
+		if v := pinValues[pc.Initial]; v >= 0 {
+			err = api.DigitalWrite(p.Pin, v)
+			if err != nil {
+				return nil, fmt.Errorf("config: initializing pin %q: %w", name, err)
+			}
+		}
+		err = api.PinMode(p.Pin, p.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("config: setting mode of pin %q: %w", name, err)
+		}
+		b.pins[name] = p
+	}
+	for name, dc := range c.I2C {
+		d := &I2CDevice{Name: name, address: nango.I2CAddress(dc.Address), master: nb.I2C()}
+		register := d.master.Register
+		if dc.Probe {
+			register = d.master.RegisterAndProbe
+		}
+		err := register(d)
+		if err != nil {
+			return nil, fmt.Errorf("config: i2c device %q: %w", name, err)
+		}
+		b.devices[name] = d
+	}
+	return b, nil
+}
+
+//Pin returns the pin with the given name
+func (b *Board) Pin(name string) (*Pin, error) {
+	p, ok := b.pins[name]
+	if !ok {
+		return nil, fmt.Errorf("config: no pin named %q", name)
+	}
+	return p, nil
+}
+
+//I2CDevice returns the I2C device with the given name
+func (b *Board) I2CDevice(name string) (*I2CDevice, error) {
+	d, ok := b.devices[name]
+	if !ok {
+		return nil, fmt.Errorf("config: no i2c device named %q", name)
+	}
+	return d, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+func openLoopback(t *testing.T, lb *nango.Loopback) *nango.Board {
+	t.Helper()
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return nango.NewBoard(conn)
+}
+
+func TestNew(t *testing.T) {
+	lb := nango.NewLoopback()
+	var calls []string
+	for _, m := range []string{nango.MethodPinMode, nango.MethodDigitalWrite} {
+		lb.Handle(nango.NamespaceArduino, m, func(c nango.LoopbackCall) (string, bool) {
+			calls = append(calls, c.Method+" "+strings.Join(c.Args, ","))
+			return "0", true
+		})
+	}
+	c, err := ParseYAML([]byte("port: COM3\nbaud: 9600\nmodel: uno\npins: {relay: {pin: D7, mode: output, initial: high}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(openLoopback(t, lb), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the initial value is written before the pin becomes an output
+	expected := []string{nango.MethodDigitalWrite + " D7,1", nango.MethodPinMode + " D7,1"}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Fatalf("expected calls %q, got %q", expected, calls)
+	}
+	if b.Model != nango.BoardModels["uno"] {
+		t.Fatal("expected the board model set")
+	}
+	if _, err := b.Pin("relay"); err != nil {
+		t.Fatal(err)
+	}
+
+	//pins the model doesn't have are rejected
+	c.Pins["relay"] = PinConfig{Pin: "D20", Mode: "output"}
+	if _, err := New(openLoopback(t, lb), c); err == nil {
+		t.Fatal("expected an error configuring a pin the uno doesn't have")
+	}
+}
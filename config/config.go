@@ -0,0 +1,138 @@
+//Package config materializes a nango Board from a YAML or JSON file
+//describing its port, pins and I2C devices, so the same program can run at
+//sites wired differently.
+//
+//	port: /dev/ttyACM0
+//	baud: 115200
+//	model: uno
+//	pins:
+//	  pump: {pin: D7, mode: output, initial: low}
+//	  level: {pin: A0, mode: input}
+//	i2c:
+//	  rtc: {address: 0x68, probe: true}
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"gopkg.in/yaml.v3"
+)
+
+//Config describes a board and its wiring
+type Config struct {
+	Port string `yaml:"port" json:"port"`
+	Baud int    `yaml:"baud" json:"baud"`
+	//Model is the board model, e.g. uno or mega2560
+	Model string `yaml:"model" json:"model"`
+	//ReadTimeout overrides the default response timeout, e.g. "500ms"
+	ReadTimeout Duration `yaml:"read_timeout" json:"read_timeout"`
+	//SleepAfterConnect is how long to wait for the board to reset on connect
+	SleepAfterConnect Duration             `yaml:"sleep_after_connect" json:"sleep_after_connect"`
+	Pins              map[string]PinConfig `yaml:"pins" json:"pins"`
+	I2C               map[string]I2CConfig `yaml:"i2c" json:"i2c"`
+}
+
+//PinConfig describes a named pin
+type PinConfig struct {
+	Pin string `yaml:"pin" json:"pin"`
+	//Mode is one of input, output or input_pullup
+	Mode string `yaml:"mode" json:"mode"`
+	//Initial, if set, is the value written to an output pin when the board is
+	//opened, low or high
+	Initial string `yaml:"initial" json:"initial"`
+}
+
+//I2CConfig describes a named I2C device
+type I2CConfig struct {
+	Address int `yaml:"address" json:"address"`
+	//Probe checks the device is present when the board is opened
+	Probe bool `yaml:"probe" json:"probe"`
+}
+
+//Duration is a time.Duration written as a string such as "250ms"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+//Load reads the configuration at path, as JSON if it has a .json extension
+//and as YAML otherwise
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON(b)
+	}
+	return ParseYAML(b)
+}
+
+//ParseYAML parses a YAML configuration
+func ParseYAML(b []byte) (*Config, error) {
+	c := new(Config)
+	err := yaml.Unmarshal(b, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+//ParseJSON parses a JSON configuration
+func ParseJSON(b []byte) (*Config, error) {
+	c := new(Config)
+	err := json.Unmarshal(b, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+//Validate checks the configuration is complete and consistent
+func (c *Config) Validate() error {
+	if c.Port == "" {
+		return fmt.Errorf("config: no port given")
+	}
+	if c.Baud <= 0 {
+		return fmt.Errorf("config: invalid baud rate %d", c.Baud)
+	}
+	if _, ok := nango.BoardModels[c.Model]; c.Model != "" && !ok {
+		return fmt.Errorf("config: unknown board model %q", c.Model)
+	}
+	for name, p := range c.Pins {
+		if p.Pin == "" {
+			return fmt.Errorf("config: pin %q has no pin", name)
+		}
+		if _, err := nango.ParsePinMode(p.Mode); err != nil {
+			return fmt.Errorf("config: pin %q has invalid mode %q", name, p.Mode)
+		}
+		if _, ok := pinValues[p.Initial]; !ok {
+			return fmt.Errorf("config: pin %q has invalid initial value %q", name, p.Initial)
+		}
+		if p.Initial != "" && p.Mode != "output" {
+			return fmt.Errorf("config: pin %q has an initial value but is not an output", name)
+		}
+	}
+	for name, d := range c.I2C {
+		if d.Address < 1 || d.Address > 127 {
+			return fmt.Errorf("config: i2c device %q has invalid address 0x%02x", name, d.Address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+const testYAML = `
+port: /dev/ttyACM0
+baud: 115200
+model: uno
+read_timeout: 500ms
+pins:
+  pump: {pin: D7, mode: output, initial: low}
+  level: {pin: A0, mode: input}
+i2c:
+  rtc: {address: 0x68, probe: true}
+`
+
+const testJSON = `{
+	"port": "/dev/ttyACM0",
+	"baud": 115200,
+	"model": "uno",
+	"read_timeout": "500ms",
+	"pins": {
+		"pump": {"pin": "D7", "mode": "output", "initial": "low"},
+		"level": {"pin": "A0", "mode": "input"}
+	},
+	"i2c": {"rtc": {"address": 104, "probe": true}}
+}`
+
+func TestParse(t *testing.T) {
+	y, err := ParseYAML([]byte(testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := ParseJSON([]byte(testJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Config{y, j} {
+		if time.Duration(c.ReadTimeout) != 500*time.Millisecond {
+			t.Errorf("expected 500ms read timeout, got %s", time.Duration(c.ReadTimeout))
+		}
+		if p := c.Pins["pump"]; p.Pin != "D7" || p.Mode != "output" || p.Initial != "low" {
+			t.Errorf("unexpected pump pin %+v", p)
+		}
+		if d := c.I2C["rtc"]; d.Address != 0x68 || !d.Probe {
+			t.Errorf("unexpected rtc device %+v", d)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, s := range []string{
+		"baud: 9600",
+		"port: COM3",
+		"port: COM3\nbaud: 9600\npins: {led: {pin: D13, mode: blink}}",
+		"port: COM3\nbaud: 9600\npins: {button: {pin: D2, mode: input, initial: high}}",
+		"port: COM3\nbaud: 9600\ni2c: {rtc: {address: 200}}",
+		"port: COM3\nbaud: 9600\nmodel: esp32",
+	} {
+		if _, err := ParseYAML([]byte(s)); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package nango
+
+//Conn is the connection a FirmwareClass sends its calls over. It is
+//implemented by FirmwareConnection; other implementations, such as fakes in
+//tests or middleware wrapping a connection, receive each call as a single
+//framed Write followed by a Flush and read the response with ReadLine.
+//
+//Calls on a Conn other than a FirmwareConnection bypass the dispatcher, so
+//such implementations must serialize concurrent calls themselves, and
+//connection level features such as priorities, retries and tracing don't
+//apply to them.
+type Conn interface {
+	Write(b []byte) error
+	Flush() error
+	//ReadLine returns the next response without its line terminator. The
+	//returned slice need only be valid until the next call.
+	ReadLine() ([]byte, error)
+}
+
+//connectionOf returns the FirmwareConnection conn calls the board through,
+//or nil if it isn't one or a Node on one
+func connectionOf(conn Conn) *FirmwareConnection {
+	if n, ok := conn.(*Node); ok {
+		conn = n.bus
+	}
+	s, _ := conn.(*FirmwareConnection)
+	return s
+}
+
+//directCall makes a call on a Conn which is not a FirmwareConnection,
+//returning the response in a pooled buffer the caller must release
+func directCall(conn Conn, namespace string, id int, methodName string, args []interface{}) (*Buffer, error) {
+	b := getBuffer()
+	var err error
+	b.b, err = appendFrame(b.b, namespace, id, methodName, args, &NanpyDialect)
+	if err == nil {
+		err = conn.Write(b.b)
+	}
+	b.Release()
+	if err == nil {
+		err = conn.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readBuffer(conn)
+}
@@ -0,0 +1,88 @@
+package nango
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EdgeRising = iota
+	EdgeFalling
+	EdgeChange
+)
+
+//PulseCount is the number of edges counted on a pin over a period
+type PulseCount struct {
+	Count   int
+	Elapsed time.Duration
+}
+
+//Frequency returns the average edge rate in Hz over the period
+func (p PulseCount) Frequency() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Count) / p.Elapsed.Seconds()
+}
+
+//Counter counts edges on pins from interrupts in firmware, for flow meters,
+//anemometers and encoders whose pulses would be missed by polling over serial
+type Counter struct {
+	*FirmwareClass
+	//Model, if set, is used to reject pins which can't raise interrupts
+	Model *BoardModel
+}
+
+func NewCounter(conn Conn) *Counter {
+	return &Counter{
+		FirmwareClass: &FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceCounter,
+		},
+	}
+}
+
+//Attach starts counting edges of the given kind (EdgeRising, EdgeFalling or
+//EdgeChange) on an interrupt capable pin
+func (c *Counter) Attach(pin string, edge int) error {
+	if err := c.Model.Check(pin, CapInterrupt); err != nil {
+		return err
+	}
+	return c.CallAndReturnNothing(MethodCounterAttach, pin, edge)
+}
+
+//Detach stops counting on pin
+func (c *Counter) Detach(pin string) error {
+	return c.CallAndReturnNothing(MethodCounterDetach, pin)
+}
+
+//Read returns the count on pin since it was last reset, without resetting it
+func (c *Counter) Read(pin string) (int, error) {
+	return c.CallAndReturnInt(MethodCounterRead, pin)
+}
+
+//ReadAndReset atomically reads and clears the count on pin, returning it
+//along with the time elapsed since the previous reset
+func (c *Counter) ReadAndReset(pin string) (PulseCount, error) {
+	s, err := c.call(MethodCounterReadAndReset, pin)
+	if err != nil {
+		return PulseCount{}, err
+	}
+	//count,elapsedMillis
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 {
+		return PulseCount{}, c.responseError(MethodCounterReadAndReset, s, errors.New("expected count,elapsed"))
+	}
+	count, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return PulseCount{}, c.responseError(MethodCounterReadAndReset, s, err)
+	}
+	ms, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return PulseCount{}, c.responseError(MethodCounterReadAndReset, s, err)
+	}
+	return PulseCount{Count: count, Elapsed: time.Duration(ms) * time.Millisecond}, nil
+}
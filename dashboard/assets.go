@@ -0,0 +1,176 @@
+package dashboard
+
+//indexHTML is the dashboard page. It polls state relative to its own URL and
+//posts JSON to control.
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>nango</title>
+<style>
+body { font-family: sans-serif; margin: 1em; color: #222; }
+h1 { font-size: 1.3em; }
+h2 { font-size: 1.05em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+.error { color: #b00; }
+.ok { color: #080; }
+#health span { margin-right: 1.5em; }
+</style>
+</head>
+<body>
+<h1>nango <span id="status"></span></h1>
+<div id="health"></div>
+<h2>Controls</h2>
+<table id="controls"></table>
+<h2>Pins</h2>
+<table id="pins"></table>
+<h2>Readings</h2>
+<table id="readings"></table>
+<h2>Recent calls</h2>
+<table id="calls"></table>
+<script>
+"use strict";
+var editing = {};
+
+function cell(row, text, cls) {
+	var td = document.createElement("td");
+	td.textContent = text === undefined || text === null ? "" : text;
+	if (cls) {
+		td.className = cls;
+	}
+	row.appendChild(td);
+	return td;
+}
+
+function fill(table, headings, rows) {
+	table.innerHTML = "";
+	var head = table.insertRow();
+	headings.forEach(function (h) {
+		var th = document.createElement("th");
+		th.textContent = h;
+		head.appendChild(th);
+	});
+	rows.forEach(function (r) {
+		var row = table.insertRow();
+		r(row);
+	});
+}
+
+function post(pin, value) {
+	fetch("control", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({pin: pin, value: Number(value)})
+	}).then(function (resp) {
+		if (!resp.ok) {
+			resp.text().then(function (t) { alert(t); });
+		}
+	});
+}
+
+function renderControls(controls, pins) {
+	var table = document.getElementById("controls");
+	if (Object.keys(editing).length > 0) {
+		return;
+	}
+	fill(table, ["pin", "value"], controls.map(function (c) {
+		return function (row) {
+			cell(row, c.pin);
+			var td = cell(row, "");
+			var p = pins[c.pin] || {};
+			var input = document.createElement("input");
+			if (c.kind === "toggle") {
+				input.type = "checkbox";
+				input.checked = p.value === 1;
+				input.onchange = function () { post(c.pin, input.checked ? 1 : 0); };
+			} else {
+				input.type = "range";
+				input.min = 0;
+				input.max = 255;
+				input.value = p.duty || 0;
+				input.onpointerdown = function () { editing[c.pin] = true; };
+				input.onpointerup = function () { delete editing[c.pin]; };
+				input.onchange = function () { post(c.pin, input.value); };
+			}
+			td.appendChild(input);
+		};
+	}));
+}
+
+function render(s) {
+	var h = s.health;
+	var health = document.getElementById("health");
+	health.innerHTML = "";
+	[["port", h.port], ["rtt", h.rtt_ms.toFixed(1) + " ms"], ["asleep", h.asleep],
+		["calls", h.calls], ["errors", h.errors]].forEach(function (f) {
+		var span = document.createElement("span");
+		span.textContent = f[0] + ": " + f[1];
+		health.appendChild(span);
+	});
+	if (h.last_error) {
+		var span = document.createElement("span");
+		span.className = "error";
+		span.textContent = "last error: " + h.last_error.namespace + "." + h.last_error.method + ": " + h.last_error.error;
+		health.appendChild(span);
+	}
+	renderControls(s.controls || [], s.pins || {});
+	fill(document.getElementById("pins"), ["pin", "mode", "value", "duty"],
+		Object.keys(s.pins || {}).sort().map(function (name) {
+			var p = s.pins[name];
+			return function (row) {
+				cell(row, name);
+				cell(row, p.mode);
+				cell(row, p.value);
+				cell(row, p.duty);
+			};
+		}));
+	fill(document.getElementById("readings"), ["source", "value", "time"],
+		(s.readings || []).map(function (r) {
+			return function (row) {
+				cell(row, r.source);
+				if (r.error) {
+					cell(row, r.error, "error");
+				} else {
+					cell(row, r.value);
+				}
+				cell(row, new Date(r.time).toLocaleTimeString());
+			};
+		}));
+	fill(document.getElementById("calls"), ["time", "call", "elapsed", "result"],
+		(s.calls || []).slice().reverse().map(function (c) {
+			return function (row) {
+				cell(row, new Date(c.time).toLocaleTimeString());
+				cell(row, c.namespace + "." + c.method + "(" + (c.args || []).join(", ") + ")");
+				cell(row, (c.elapsed / 1e6).toFixed(1) + " ms");
+				if (c.error) {
+					cell(row, c.error, "error");
+				} else {
+					cell(row, "ok", "ok");
+				}
+			};
+		}));
+}
+
+function poll() {
+	var status = document.getElementById("status");
+	fetch("state", {cache: "no-store"}).then(function (resp) {
+		return resp.json();
+	}).then(function (s) {
+		status.className = "";
+		status.textContent = "";
+		render(s);
+	}).catch(function (err) {
+		status.className = "error";
+		status.textContent = "disconnected";
+	}).then(function () {
+		setTimeout(poll, 1000);
+	});
+}
+
+poll();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,107 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Call is a call recorded by a CallLog
+type Call struct {
+	Time      time.Time     `json:"time"`
+	Namespace string        `json:"namespace"`
+	Method    string        `json:"method"`
+	Args      []string      `json:"args,omitempty"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Err       string        `json:"error,omitempty"`
+}
+
+//CallLog keeps the most recent calls made on a connection, along with the
+//number of calls and errors since it was created. Install it on the
+//connection with nango.WithMiddleware(log.Middleware).
+type CallLog struct {
+	mu        sync.Mutex
+	calls     []Call
+	next      int
+	full      bool
+	total     int
+	errors    int
+	lastError *Call
+}
+
+//NewCallLog returns a CallLog keeping the last size calls
+func NewCallLog(size int) *CallLog {
+	if size < 1 {
+		size = 1
+	}
+	return &CallLog{calls: make([]Call, size)}
+}
+
+//Middleware records every call made through it. It is a nango.Middleware.
+func (l *CallLog) Middleware(next nango.CallFunc) nango.CallFunc {
+	return func(ctx context.Context, c *nango.CallInfo) (*nango.Buffer, error) {
+		start := time.Now()
+		b, err := next(ctx, c)
+		call := Call{
+			Time:      start,
+			Namespace: c.Namespace,
+			Method:    c.Method,
+			Elapsed:   time.Since(start),
+		}
+		for _, a := range c.Args {
+			call.Args = append(call.Args, fmt.Sprint(a))
+		}
+		if err != nil {
+			call.Err = err.Error()
+		}
+		l.record(call)
+		return b, err
+	}
+}
+
+func (l *CallLog) record(c Call) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls[l.next] = c
+	l.next++
+	if l.next == len(l.calls) {
+		l.next = 0
+		l.full = true
+	}
+	l.total++
+	if c.Err != "" {
+		l.errors++
+		l.lastError = &c
+	}
+}
+
+//Recent returns the recorded calls, oldest first
+func (l *CallLog) Recent() []Call {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Call(nil), l.calls[:l.next]...)
+	}
+	return append(append([]Call(nil), l.calls[l.next:]...), l.calls[:l.next]...)
+}
+
+//Counts returns the number of calls and of failed calls recorded
+func (l *CallLog) Counts() (calls int, errors int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total, l.errors
+}
+
+//LastError returns the most recent failed call, or nil if none failed
+func (l *CallLog) LastError() *Call {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lastError == nil {
+		return nil
+	}
+	c := *l.lastError
+	return &c
+}
@@ -0,0 +1,251 @@
+//Package dashboard serves a web page for bench testing a nango board. It
+//shows the board's pin states, the latest poller readings, the health of
+//its connection and the calls recently made on it, and has toggles and
+//sliders for the outputs chosen with Toggle and Slider. The page is compiled
+//into the program, so there are no assets to deploy.
+//
+//	calls := dashboard.NewCallLog(50)
+//	b, err := nango.OpenBoard(conf, nango.WithMiddleware(calls.Middleware))
+//	...
+//	d := dashboard.New(b)
+//	d.Calls = calls
+//	d.Attach(poller)
+//	d.Toggle("D13")
+//	d.Slider("D6")
+//	http.ListenAndServe("localhost:8080", d)
+//
+//The controls write to the board on behalf of anyone who can reach the page,
+//so it should only be served on a trusted network. Writes must be posted as
+//JSON, which a page on another site can't send without the browser asking
+//first, so a page merely visited on that network can't drive the outputs.
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Kinds of Control
+const (
+	ControlToggle = "toggle"
+	ControlSlider = "slider"
+)
+
+//Control is an output which can be written from the page
+type Control struct {
+	Pin  string `json:"pin"`
+	Kind string `json:"kind"`
+}
+
+//Dashboard is an http.Handler serving the dashboard of a Board. The page is
+//served at the root of the handler, so mount it with http.StripPrefix to
+//serve it under a path ending in a slash.
+type Dashboard struct {
+	Board *nango.Board
+	//Calls, if set, is shown as the recent calls and used to report the
+	//health of the connection
+	Calls *CallLog
+
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	readings map[string]nango.Reading
+	controls []Control
+}
+
+//New returns a Dashboard for b
+func New(b *nango.Board) *Dashboard {
+	d := &Dashboard{
+		Board:    b,
+		mux:      http.NewServeMux(),
+		readings: make(map[string]nango.Reading),
+	}
+	d.mux.HandleFunc("/", d.serveIndex)
+	d.mux.HandleFunc("/state", d.serveState)
+	d.mux.HandleFunc("/control", d.serveControl)
+	return d
+}
+
+//Attach shows the readings published by p
+func (d *Dashboard) Attach(p *nango.Poller) (detach func()) {
+	return p.Subscribe(d.Observe)
+}
+
+//Observe records r as the latest reading of its source
+func (d *Dashboard) Observe(r nango.Reading) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readings[r.Source] = r
+}
+
+//Toggle adds a switch writing pin high or low. The pin should already be an
+//output.
+func (d *Dashboard) Toggle(pin string) {
+	d.addControl(Control{Pin: pin, Kind: ControlToggle})
+}
+
+//Slider adds a slider writing a PWM duty cycle of 0 to 255 to pin
+func (d *Dashboard) Slider(pin string) {
+	d.addControl(Control{Pin: pin, Kind: ControlSlider})
+}
+
+func (d *Dashboard) addControl(c Control) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, existing := range d.controls {
+		if existing.Pin == c.Pin {
+			d.controls[i] = c
+			return
+		}
+	}
+	d.controls = append(d.controls, c)
+}
+
+//control returns the control of pin
+func (d *Dashboard) control(pin string) (Control, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.controls {
+		if c.Pin == pin {
+			return c, true
+		}
+	}
+	return Control{}, false
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+func (d *Dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+//State is the document served at /state, which the page polls
+type State struct {
+	Pins     map[string]nango.PinSnapshot `json:"pins"`
+	Readings []Reading                    `json:"readings"`
+	Health   Health                       `json:"health"`
+	Calls    []Call                       `json:"calls"`
+	Controls []Control                    `json:"controls"`
+}
+
+//Reading is the latest reading of a poller source
+type Reading struct {
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+	Value  float64   `json:"value"`
+	Err    string    `json:"error,omitempty"`
+}
+
+//Health describes the board's connection
+type Health struct {
+	Port string `json:"port,omitempty"`
+	//RTT is the 95th percentile of recent round trip times in milliseconds
+	RTT    float64 `json:"rtt_ms"`
+	Asleep bool    `json:"asleep"`
+	//Calls, Errors and LastError are only set if the Dashboard has Calls
+	Calls     int   `json:"calls"`
+	Errors    int   `json:"errors"`
+	LastError *Call `json:"last_error,omitempty"`
+}
+
+//State returns what the page currently shows
+func (d *Dashboard) State() *State {
+	s := &State{Pins: d.Board.Pins().PinStates()}
+	if fc, ok := d.Board.Conn().(*nango.FirmwareConnection); ok {
+		s.Health.Port = fc.Name()
+		s.Health.RTT = float64(fc.RTT()) / float64(time.Millisecond)
+		s.Health.Asleep = fc.Asleep()
+	}
+	if d.Calls != nil {
+		s.Calls = d.Calls.Recent()
+		s.Health.Calls, s.Health.Errors = d.Calls.Counts()
+		s.Health.LastError = d.Calls.LastError()
+	}
+	d.mu.Lock()
+	for _, r := range d.readings {
+		sr := Reading{Source: r.Source, Time: r.Time, Value: r.Value}
+		if r.Err != nil {
+			sr.Err = r.Err.Error()
+		}
+		s.Readings = append(s.Readings, sr)
+	}
+	s.Controls = append([]Control(nil), d.controls...)
+	d.mu.Unlock()
+	sort.Slice(s.Readings, func(i, j int) bool { return s.Readings[i].Source < s.Readings[j].Source })
+	return s
+}
+
+func (d *Dashboard) serveState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(d.State())
+}
+
+//controlRequest is the body posted to control
+type controlRequest struct {
+	Pin   string `json:"pin"`
+	Value int    `json:"value"`
+}
+
+//serveControl writes the value posted for one of the controls' pins. Only
+//same-origin JSON requests are accepted, so other sites can't forge them.
+func (d *Dashboard) serveControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		http.Error(w, "cross-site request rejected", http.StatusForbidden)
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		http.Error(w, "expected an application/json body", http.StatusUnsupportedMediaType)
+		return
+	}
+	var req controlRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	pin, v := req.Pin, req.Value
+	c, ok := d.control(pin)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no control for pin %q", pin), http.StatusForbidden)
+		return
+	}
+	max := 255
+	if c.Kind == ControlToggle {
+		max = nango.PinHigh
+	}
+	if v < 0 || v > max {
+		http.Error(w, fmt.Sprintf("invalid value %d for pin %s", v, pin), http.StatusBadRequest)
+		return
+	}
+	api := d.Board.Pins()
+	var err error
+	if c.Kind == ControlToggle {
+		err = api.DigitalWrite(pin, v)
+	} else {
+		err = api.AnalogWrite(pin, v)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
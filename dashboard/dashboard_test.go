@@ -0,0 +1,168 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func openDashboard(t *testing.T) (*nangotest.Simulator, *Dashboard) {
+	sim := nangotest.NewSimulator(nil)
+	calls := NewCallLog(3)
+	conn := nango.NewFirmwareConnection(nil,
+		nango.WithTransport(sim.Dial),
+		nango.WithReadTimeout(100*time.Millisecond),
+		nango.WithMiddleware(calls.Middleware))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	d := New(nango.NewBoard(conn))
+	d.Calls = calls
+	return sim, d
+}
+
+func getState(t *testing.T, srv *httptest.Server) *State {
+	resp, err := http.Get(srv.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var s State
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	return &s
+}
+
+func TestDashboard(t *testing.T) {
+	sim, d := openDashboard(t)
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	api := d.Board.Pins()
+	if err := api.PinMode("D13", nango.PinOutput); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PinMode("D6", nango.PinOutput); err != nil {
+		t.Fatal(err)
+	}
+	d.Toggle("D13")
+	d.Slider("D6")
+	d.Observe(nango.Reading{Source: "A0", Value: 512})
+	d.Observe(nango.Reading{Source: "A1", Err: errors.New("timeout")})
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the page, got %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	post := func(pin string, value int) int {
+		body := fmt.Sprintf(`{"pin": %q, "value": %d}`, pin, value)
+		resp, err := http.Post(srv.URL+"/control", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post("D13", 1); code != http.StatusNoContent || sim.Value("D13") != nango.PinHigh {
+		t.Fatalf("expected D13 high, got %d and %d", code, sim.Value("D13"))
+	}
+	if code := post("D6", 200); code != http.StatusNoContent || sim.Value("D6") != 200 {
+		t.Fatalf("expected D6 at 200, got %d and %d", code, sim.Value("D6"))
+	}
+	if code := post("D13", 2); code != http.StatusBadRequest {
+		t.Fatalf("expected a toggle to reject 2, got %d", code)
+	}
+	if code := post("D7", 1); code != http.StatusForbidden {
+		t.Fatalf("expected pins without controls to be rejected, got %d", code)
+	}
+
+	//a form another site could submit is rejected, as is JSON it sent
+	resp, err = http.PostForm(srv.URL+"/control", url.Values{"pin": {"D13"}, "value": {"0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType || sim.Value("D13") != nango.PinHigh {
+		t.Fatalf("expected a form post to be rejected, got %s", resp.Status)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/control", strings.NewReader(`{"pin": "D13", "value": 0}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || sim.Value("D13") != nango.PinHigh {
+		t.Fatalf("expected a cross-site post to be rejected, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(srv.URL + "/control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET of control to be rejected, got %s", resp.Status)
+	}
+
+	s := getState(t, srv)
+	if p := s.Pins["D13"]; p.Mode != "output" || p.Value == nil || *p.Value != nango.PinHigh {
+		t.Fatalf("expected D13 output high, got %+v", p)
+	}
+	if p := s.Pins["D6"]; p.Duty == nil || *p.Duty != 200 {
+		t.Fatalf("expected D6 duty 200, got %+v", p)
+	}
+	if len(s.Readings) != 2 || s.Readings[0].Value != 512 || s.Readings[1].Err != "timeout" {
+		t.Fatalf("expected readings of A0 and A1, got %+v", s.Readings)
+	}
+	if len(s.Controls) != 2 || s.Controls[0] != (Control{Pin: "D13", Kind: ControlToggle}) {
+		t.Fatalf("expected controls, got %+v", s.Controls)
+	}
+	if s.Health.Port != "transport" || s.Health.Calls != 4 || s.Health.Errors != 0 {
+		t.Fatalf("expected 4 calls without errors, got %+v", s.Health)
+	}
+	if len(s.Calls) != 3 || s.Calls[2].Method != nango.MethodAnalogWrite || strings.Join(s.Calls[2].Args, ",") != "D6,200" {
+		t.Fatalf("expected the last 3 calls, ending with the analog write, got %+v", s.Calls)
+	}
+}
+
+func TestCallLog(t *testing.T) {
+	l := NewCallLog(2)
+	failed := errors.New("failed")
+	call := l.Middleware(func(ctx context.Context, c *nango.CallInfo) (*nango.Buffer, error) {
+		if c.Method == "bad" {
+			return nil, failed
+		}
+		return nil, nil
+	})
+	for _, m := range []string{"a", "bad", "c"} {
+		call(context.Background(), &nango.CallInfo{Namespace: "T", Method: m, Args: []interface{}{1, "x"}})
+	}
+	recent := l.Recent()
+	if len(recent) != 2 || recent[0].Method != "bad" || recent[1].Method != "c" || recent[0].Args[1] != "x" {
+		t.Fatalf("expected the last two calls, got %+v", recent)
+	}
+	if n, errs := l.Counts(); n != 3 || errs != 1 {
+		t.Fatalf("expected 3 calls and 1 error, got %d and %d", n, errs)
+	}
+	if last := l.LastError(); last == nil || last.Method != "bad" || last.Err != "failed" {
+		t.Fatalf("expected the failed call, got %+v", last)
+	}
+}
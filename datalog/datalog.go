@@ -0,0 +1,171 @@
+//Package datalog records the readings of nango pollers and sample streams to
+//CSV files, InfluxDB line protocol or any io.Writer, for unattended data
+//collection.
+//
+//	sink, err := datalog.NewRotatingSink("readings-%s.csv", datalog.NewCSVSink, 10<<20, 24*time.Hour)
+//	l := datalog.New(sink)
+//	defer l.Close()
+//	l.Attach(poller)
+package datalog
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Sink stores batches of readings. Write must not retain readings, whose
+//memory is reused for later batches.
+type Sink interface {
+	Write(readings []nango.Reading) error
+	Close() error
+}
+
+//Logger buffers readings and writes them to a Sink in batches
+type Logger struct {
+	//BatchSize is the number of readings buffered before they are written
+	BatchSize int
+	//OnError, if set, is called with the errors returned by the sink instead
+	//of logging them
+	OnError func(err error)
+
+	sink Sink
+	//writeMu serializes the writes to sink so batches are written in order,
+	//without holding mu and blocking Log meanwhile
+	writeMu sync.Mutex
+	spare   []nango.Reading
+	mu      sync.Mutex
+	buf     []nango.Reading
+	unsubs  []func()
+	streams sync.WaitGroup
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+//DefaultBatchSize is the BatchSize of loggers returned by New
+const DefaultBatchSize = 100
+
+//DefaultFlushInterval is how often loggers returned by New write buffered
+//readings which haven't filled a batch
+const DefaultFlushInterval = 5 * time.Second
+
+//New returns a Logger writing to sink, which it flushes every
+//DefaultFlushInterval
+func New(sink Sink) *Logger {
+	return NewWithFlushInterval(sink, DefaultFlushInterval)
+}
+
+//NewWithFlushInterval returns a Logger writing to sink, which it flushes
+//every interval
+func NewWithFlushInterval(sink Sink, interval time.Duration) *Logger {
+	l := &Logger{
+		BatchSize: DefaultBatchSize,
+		sink:      sink,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go l.flushEvery(interval)
+	return l
+}
+
+func (l *Logger) flushEvery(interval time.Duration) {
+	defer close(l.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.Flush()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+//Log buffers r, writing the batch if it is full
+func (l *Logger) Log(r nango.Reading) {
+	l.mu.Lock()
+	l.buf = append(l.buf, r)
+	full := len(l.buf) >= l.BatchSize
+	l.mu.Unlock()
+	if full {
+		l.Flush()
+	}
+}
+
+//Flush writes the buffered readings. Readings logged while they are being
+//written are buffered for the next batch.
+func (l *Logger) Flush() error {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	l.mu.Lock()
+	batch := l.buf
+	l.buf = l.spare[:0]
+	l.mu.Unlock()
+	if len(batch) == 0 {
+		l.spare = batch
+		return nil
+	}
+	err := l.sink.Write(batch)
+	l.spare = batch[:0]
+	if err != nil {
+		l.report(err)
+	}
+	return err
+}
+
+func (l *Logger) report(err error) {
+	if l.OnError != nil {
+		l.OnError(err)
+		return
+	}
+	log.Printf("datalog: %s", err)
+}
+
+//Attach logs every reading published by p
+func (l *Logger) Attach(p *nango.Poller) {
+	unsub := p.Subscribe(l.Log)
+	l.mu.Lock()
+	l.unsubs = append(l.unsubs, unsub)
+	l.mu.Unlock()
+}
+
+//AttachStream logs the samples delivered by s as readings of source, until
+//s is closed. Samples are timestamped backwards from the time their batch
+//was received, using the sampling interval.
+func (l *Logger) AttachStream(source string, s *nango.SampleStream) {
+	l.streams.Add(1)
+	go func() {
+		defer l.streams.Done()
+		for batch := range s.C {
+			now := time.Now()
+			for i, v := range batch.Samples {
+				t := now.Add(-time.Duration(len(batch.Samples)-1-i) * batch.Interval)
+				l.Log(nango.Reading{Source: source, Time: t, Value: float64(v)})
+			}
+		}
+	}()
+}
+
+//Close detaches the logger from its pollers, waits for its streams to be
+//closed, writes the buffered readings and closes the sink
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	unsubs := l.unsubs
+	l.unsubs = nil
+	l.mu.Unlock()
+	for _, unsub := range unsubs {
+		unsub()
+	}
+	l.streams.Wait()
+	close(l.quit)
+	<-l.done
+	err := l.Flush()
+	errClose := l.sink.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package datalog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+var at = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestCSVSink(t *testing.T) {
+	var b bytes.Buffer
+	s := NewCSVSink(&b)
+	s.Write([]nango.Reading{{Source: "A0", Time: at, Value: 512}})
+	s.Write([]nango.Reading{{Source: "A1", Time: at, Err: errors.New("timeout")}})
+	want := "time,source,value,error\n" +
+		"2021-06-01T12:00:00Z,A0,512,\n" +
+		"2021-06-01T12:00:00Z,A1,,timeout\n"
+	if b.String() != want {
+		t.Fatalf("expected %q, got %q", want, b.String())
+	}
+}
+
+func TestInfluxSink(t *testing.T) {
+	var b bytes.Buffer
+	s := NewInfluxSink(&b, "bench rig")
+	s.Write([]nango.Reading{
+		{Source: "bme280 temp", Time: at, Value: 21.5},
+		{Source: "A1", Time: at, Err: errors.New("timeout")},
+	})
+	want := `bench\ rig,source=bme280\ temp value=21.5 1622548800000000000` + "\n"
+	if b.String() != want {
+		t.Fatalf("expected %q, got %q", want, b.String())
+	}
+}
+
+type memSink struct {
+	batches [][]nango.Reading
+	closed  bool
+}
+
+func (s *memSink) Write(readings []nango.Reading) error {
+	s.batches = append(s.batches, append([]nango.Reading(nil), readings...))
+	return nil
+}
+
+func (s *memSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestLoggerBatches(t *testing.T) {
+	sink := &memSink{}
+	l := NewWithFlushInterval(sink, time.Hour)
+	l.BatchSize = 2
+	for i := 0; i < 5; i++ {
+		l.Log(nango.Reading{Source: "A0", Time: at, Value: float64(i)})
+	}
+	if len(sink.batches) != 2 {
+		t.Fatalf("expected 2 full batches, got %d", len(sink.batches))
+	}
+	l.Close()
+	if len(sink.batches) != 3 || len(sink.batches[2]) != 1 || !sink.closed {
+		t.Fatalf("expected remaining reading flushed and sink closed, got %v", sink.batches)
+	}
+}
+
+//blockingSink blocks writes until released
+type blockingSink struct {
+	memSink
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(readings []nango.Reading) error {
+	s.writing <- struct{}{}
+	<-s.release
+	return s.memSink.Write(readings)
+}
+
+func TestLoggerLogsWhileFlushing(t *testing.T) {
+	sink := &blockingSink{writing: make(chan struct{}), release: make(chan struct{})}
+	l := NewWithFlushInterval(sink, time.Hour)
+	l.Log(nango.Reading{Source: "A0", Time: at, Value: 1})
+	flushed := make(chan error)
+	go func() { flushed <- l.Flush() }()
+	<-sink.writing
+	logged := make(chan struct{})
+	go func() {
+		l.Log(nango.Reading{Source: "A0", Time: at, Value: 2})
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Log not to wait for the sink")
+	}
+	close(sink.release)
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range sink.writing {
+		}
+	}()
+	l.Close()
+	close(sink.writing)
+	if len(sink.batches) != 2 || sink.batches[0][0].Value != 1 || sink.batches[1][0].Value != 2 {
+		t.Fatalf("expected the reading logged while flushing in the next batch, got %v", sink.batches)
+	}
+}
@@ -0,0 +1,103 @@
+package datalog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//rotateLayout formats the time a file was started in rotated file names
+const rotateLayout = "20060102T150405"
+
+//RotatingSink writes to a series of files, starting a new file, with a new
+//inner sink, once the current one reaches a maximum size or age
+type RotatingSink struct {
+	pattern string
+	newSink func(w io.Writer) Sink
+	maxSize int64
+	maxAge  time.Duration
+
+	file    *countingFile
+	sink    Sink
+	started time.Time
+}
+
+//countingFile counts the bytes written to a file
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (f *countingFile) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b)
+	f.n += int64(n)
+	return n, err
+}
+
+//NewRotatingSink returns a RotatingSink writing to files named by pattern,
+//in which %s is replaced by the time the file was started. Each file is
+//written by the sink returned by newSink, e.g. NewCSVSink. A maxSize or
+//maxAge of zero disables rotation on that criterion.
+func NewRotatingSink(pattern string, newSink func(w io.Writer) Sink, maxSize int64, maxAge time.Duration) (*RotatingSink, error) {
+	if !strings.Contains(pattern, "%s") {
+		return nil, fmt.Errorf("datalog: rotating file pattern %q has no %%s", pattern)
+	}
+	s := &RotatingSink{
+		pattern: pattern,
+		newSink: newSink,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+	return s, s.rotate()
+}
+
+//rotate closes the current file, if any, and starts a new one
+func (s *RotatingSink) rotate() error {
+	if s.sink != nil {
+		if err := s.sink.Close(); err != nil {
+			return err
+		}
+	}
+	s.started = time.Now()
+	name := fmt.Sprintf(s.pattern, s.started.Format(rotateLayout))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.sink = nil
+		return err
+	}
+	s.file = &countingFile{File: f}
+	s.sink = s.newSink(s.file)
+	return nil
+}
+
+func (s *RotatingSink) Write(readings []nango.Reading) error {
+	if s.sink == nil ||
+		s.maxSize > 0 && s.file.n >= s.maxSize ||
+		s.maxAge > 0 && time.Since(s.started) >= s.maxAge {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	return s.sink.Write(readings)
+}
+
+func (s *RotatingSink) Close() error {
+	if s.sink == nil {
+		return nil
+	}
+	err := s.sink.Close()
+	s.sink = nil
+	return err
+}
+
+//NewInfluxFileSink returns a function for NewRotatingSink creating
+//InfluxSinks for measurement
+func NewInfluxFileSink(measurement string) func(w io.Writer) Sink {
+	return func(w io.Writer) Sink {
+		return NewInfluxSink(w, measurement)
+	}
+}
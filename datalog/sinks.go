@@ -0,0 +1,96 @@
+package datalog
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//closeWriter closes w if it is an io.Closer
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+//CSVSink writes readings as CSV rows of time, source, value and error,
+//preceded by a header row
+type CSVSink struct {
+	w      io.Writer
+	csv    *csv.Writer
+	header bool
+}
+
+//NewCSVSink returns a Sink writing CSV to w. w is closed with the sink if it
+//is an io.Closer.
+func NewCSVSink(w io.Writer) Sink {
+	return &CSVSink{w: w, csv: csv.NewWriter(w)}
+}
+
+func (s *CSVSink) Write(readings []nango.Reading) error {
+	if !s.header {
+		s.csv.Write([]string{"time", "source", "value", "error"})
+		s.header = true
+	}
+	for _, r := range readings {
+		row := []string{r.Time.Format(time.RFC3339Nano), r.Source, "", ""}
+		if r.Err != nil {
+			row[3] = r.Err.Error()
+		} else {
+			row[2] = strconv.FormatFloat(r.Value, 'g', -1, 64)
+		}
+		s.csv.Write(row)
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+func (s *CSVSink) Close() error {
+	return closeWriter(s.w)
+}
+
+//InfluxSink writes readings in InfluxDB line protocol as the value field of
+//a measurement tagged with the source. Failed readings are skipped.
+type InfluxSink struct {
+	w           io.Writer
+	measurement string
+	buf         []byte
+}
+
+//NewInfluxSink returns a Sink writing line protocol for measurement to w, e.g.
+//a file or the body of requests to the InfluxDB write API. w is closed with
+//the sink if it is an io.Closer.
+func NewInfluxSink(w io.Writer, measurement string) Sink {
+	return &InfluxSink{w: w, measurement: influxEscaper.Replace(measurement)}
+}
+
+//influxEscaper escapes measurement names and tag values
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+func (s *InfluxSink) Write(readings []nango.Reading) error {
+	s.buf = s.buf[:0]
+	for _, r := range readings {
+		if r.Err != nil {
+			continue
+		}
+		s.buf = append(s.buf, s.measurement...)
+		s.buf = append(s.buf, ",source="...)
+		s.buf = append(s.buf, influxEscaper.Replace(r.Source)...)
+		s.buf = append(s.buf, " value="...)
+		s.buf = strconv.AppendFloat(s.buf, r.Value, 'g', -1, 64)
+		s.buf = append(s.buf, ' ')
+		s.buf = strconv.AppendInt(s.buf, r.Time.UnixNano(), 10)
+		s.buf = append(s.buf, '\n')
+	}
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+func (s *InfluxSink) Close() error {
+	return closeWriter(s.w)
+}
@@ -0,0 +1,47 @@
+package nango
+
+//Dialect describes how a firmware implementation encodes the arguments whose
+//representation is a matter of convention
+type Dialect struct {
+	True  string
+	False string
+	//OmitNil drops nil arguments from the call entirely, so optional trailing
+	//arguments can be left out. Otherwise nil is sent as Nil.
+	OmitNil bool
+	Nil     string
+}
+
+//NanpyDialect matches Nanpy compatible firmware, which parses arguments with
+//Python conventions. It is the default.
+var NanpyDialect = Dialect{
+	True:    "True",
+	False:   "False",
+	OmitNil: true,
+}
+
+//CDialect matches firmware which parses booleans as C integers
+var CDialect = Dialect{
+	True:    "1",
+	False:   "0",
+	OmitNil: true,
+}
+
+//WithDialect sets the argument encoding the firmware expects
+func WithDialect(d Dialect) Option {
+	return func(s *FirmwareConnection) {
+		s.dialect = &d
+	}
+}
+
+//dialectOf returns the dialect of conn if it is a FirmwareConnection,
+//otherwise NanpyDialect
+func dialectOf(conn Conn) *Dialect {
+	if s := connectionOf(conn); s != nil && s.dialect != nil {
+		return s.dialect
+	}
+	return &NanpyDialect
+}
+
+func (d *Dialect) skip(arg interface{}) bool {
+	return arg == nil && d.OmitNil
+}
@@ -0,0 +1,233 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+//request is a call, or a pipeline of calls, waiting to be sent by a
+//connection's dispatcher
+type request struct {
+	ctx      context.Context
+	priority int
+	//frame holds n encoded calls, which produce n responses
+	frame   []byte
+	n       int
+	results []callResult
+	done    chan struct{}
+	//cancelled is set by the dispatcher if ctx was done before r was sent
+	cancelled error
+	//key, if set, lets r replace a queued request with the same key which has
+	//not been sent yet
+	key string
+	//sleep, if set, is the sleep window the call starts once acknowledged
+	sleep *sleepWindow
+	//node, if set, is the node of a multi-drop bus the call is addressed to
+	node *Node
+}
+
+type callResult struct {
+	value *Buffer
+	err   error
+}
+
+//requests are pooled along with their frame buffers so single calls don't
+//allocate on the hot path
+var requestPool = sync.Pool{
+	New: func() interface{} {
+		return &request{done: make(chan struct{}, 1)}
+	},
+}
+
+func getRequest(ctx context.Context, priority int) *request {
+	r := requestPool.Get().(*request)
+	r.ctx = ctx
+	r.priority = priority
+	r.frame = r.frame[:0]
+	r.n = 0
+	r.results = r.results[:0]
+	r.cancelled = nil
+	r.key = ""
+	r.sleep = nil
+	r.node = nil
+	return r
+}
+
+func putRequest(r *request) {
+	r.ctx = nil
+	requestPool.Put(r)
+}
+
+//fail completes the request without sending it
+func (r *request) fail(err error) {
+	for len(r.results) < r.n {
+		r.results = append(r.results, callResult{err: err})
+	}
+	r.done <- struct{}{}
+}
+
+//do queues r for the dispatcher and waits for it to complete. If ctx is
+//cancelled while r is still queued it is withdrawn without being sent; once
+//the dispatcher has started sending r it always runs to completion so the
+//protocol stays in sync.
+func (s *FirmwareConnection) do(r *request) {
+	s.inFlight.add()
+	defer s.inFlight.done()
+	q := s.activeQueue()
+	if q == nil {
+		r.fail(portClosed())
+		<-r.done
+		return
+	}
+	if s.limiter != nil {
+		if err := s.limiter.admit(r.ctx, clockOf(s), r.n, len(r.frame)); err != nil {
+			if err == ErrRateLimited {
+				statRateLimited.Add(1)
+			}
+			r.fail(err)
+			<-r.done
+			return
+		}
+	}
+	err := q.push(r)
+	if err != nil {
+		r.fail(err)
+		<-r.done
+		return
+	}
+	select {
+	case <-r.done:
+	case <-r.ctx.Done():
+		if q.remove(r) {
+			r.fail(r.ctx.Err())
+		}
+		<-r.done
+	}
+}
+
+//dispatch serves requests from the queue until quit is closed. It is the only
+//goroutine writing requests to or reading responses from the port.
+func (s *FirmwareConnection) dispatch(q *callQueue, quit chan struct{}, done chan struct{}) {
+	defer close(done)
+	batch := make([]*request, 0, 8)
+	for {
+		r := q.pop()
+		if r == nil {
+			select {
+			case <-q.notify:
+				continue
+			case <-quit:
+				return
+			}
+		}
+		batch = append(batch[:0], r)
+		//time-sensitive calls are never held back to be coalesced
+		if s.CoalesceWindow > 0 && r.priority <= PriorityNormal {
+			select {
+			case <-clockOf(s).After(s.CoalesceWindow):
+			case <-quit:
+			}
+			for r = q.pop(); r != nil; r = q.pop() {
+				batch = append(batch, r)
+			}
+		}
+		s.send(batch)
+	}
+}
+
+//send writes the frames of every request in the batch as one transfer and
+//then reads their responses in order
+func (s *FirmwareConnection) send(batch []*request) {
+	s.awaitBusQuiet()
+	err := s.wake()
+	for _, r := range batch {
+		if err != nil {
+			break
+		}
+		r.cancelled = r.ctx.Err()
+		if r.cancelled != nil {
+			continue
+		}
+		err = s.Write(r.frame)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = s.Flush()
+	}
+	sent := clockOf(s).Now()
+	for _, r := range batch {
+		if r.cancelled != nil {
+			r.fail(r.cancelled)
+			continue
+		}
+		for i := 0; i < r.n; i++ {
+			var v *Buffer
+			callErr := err
+			if err == nil {
+				if r.node != nil {
+					v, callErr = s.readNodeBuffer(r.node)
+				} else {
+					v, callErr = readBuffer(s)
+				}
+				if r.node != nil && errors.Is(callErr, ErrTimeout) {
+					s.busQuietUntil = clockOf(s).Now().Add(s.busTurnaround)
+				}
+				//the firmware carries on after an exception, so only the
+				//call which raised it fails
+				if _, ok := callErr.(*FirmwareException); !ok {
+					err = callErr
+				}
+			}
+			r.results = append(r.results, callResult{value: v, err: callErr})
+		}
+		if r.sleep != nil && r.results[0].err == nil {
+			s.fallAsleep(*r.sleep)
+		}
+		//only lone calls give a clean round trip time. A timeout is counted
+		//as a round trip of at least the timeout, so the adaptive timeout
+		//recovers when the link slows down.
+		if (err == nil || errors.Is(err, ErrTimeout)) && len(batch) == 1 && r.n == 1 {
+			s.rtt.observe(clockOf(s).Now().Sub(sent))
+		}
+		r.done <- struct{}{}
+	}
+}
+
+//activeQueue returns the queue of the running dispatcher, nil if it isn't
+//running
+func (s *FirmwareConnection) activeQueue() *callQueue {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	return s.queue
+}
+
+//startDispatcher starts serving calls on a newly opened port
+func (s *FirmwareConnection) startDispatcher() {
+	q := newCallQueue()
+	s.quit = make(chan struct{})
+	s.dispatcherDone = make(chan struct{})
+	go s.dispatch(q, s.quit, s.dispatcherDone)
+	s.queueMu.Lock()
+	s.queue = q
+	s.queueMu.Unlock()
+}
+
+//stopDispatcher fails all queued calls and waits for the call being sent, if
+//any, to complete
+func (s *FirmwareConnection) stopDispatcher() {
+	s.queueMu.Lock()
+	q := s.queue
+	s.queue = nil
+	s.queueMu.Unlock()
+	if q == nil {
+		return
+	}
+	for _, r := range q.close() {
+		r.fail(portClosed())
+	}
+	close(s.quit)
+	<-s.dispatcherDone
+}
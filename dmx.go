@@ -0,0 +1,129 @@
+package nango
+
+import (
+	"fmt"
+	"sync"
+)
+
+//DmxChannels is the number of channels in a DMX512 universe
+const DmxChannels = 512
+
+//dmxWriteChunk is the maximum number of channel values sent per writeRange call
+const dmxWriteChunk = 32
+
+//Dmx gives access to a DMX512 transmitter driven by the DmxSimple library.
+//The firmware keeps refreshing the universe; the host only sends changes.
+//
+//Values can be written immediately with Write, or staged with Set and sent in
+//batches of contiguous channels with Flush.
+//https://code.google.com/archive/p/tinkerit/wikis/DmxSimple.wiki
+type Dmx struct {
+	*FirmwareClass
+	mu    sync.Mutex
+	frame [DmxChannels]byte
+	dirty [DmxChannels]bool
+}
+
+func NewDmx(conn Conn) *Dmx {
+	return &Dmx{
+		FirmwareClass: &FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceDmx,
+		},
+	}
+}
+
+//UsePin sets the digital pin used to transmit the DMX signal
+func (d *Dmx) UsePin(pin string) error {
+	return d.CallAndReturnNothing(MethodDmxUsePin, pin)
+}
+
+//MaxChannel limits the number of channels refreshed by the firmware, which
+//increases the refresh rate when only a few channels are in use
+func (d *Dmx) MaxChannel(n int) error {
+	if n < 1 || n > DmxChannels {
+		return dmxChannelError(n)
+	}
+	return d.CallAndReturnNothing(MethodDmxMaxChannel, n)
+}
+
+//Write sets channel (1-512) to value immediately
+func (d *Dmx) Write(channel int, value byte) error {
+	if channel < 1 || channel > DmxChannels {
+		return dmxChannelError(channel)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.CallAndReturnNothing(MethodDmxWrite, channel, int(value))
+	if err != nil {
+		return err
+	}
+	d.frame[channel-1] = value
+	d.dirty[channel-1] = false
+	return nil
+}
+
+//Set stages a value for channel (1-512) to be sent by the next Flush
+func (d *Dmx) Set(channel int, value byte) error {
+	if channel < 1 || channel > DmxChannels {
+		return dmxChannelError(channel)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.frame[channel-1] != value {
+		d.frame[channel-1] = value
+		d.dirty[channel-1] = true
+	}
+	return nil
+}
+
+//SetRange stages consecutive values starting at channel
+func (d *Dmx) SetRange(channel int, values []byte) error {
+	for i, v := range values {
+		err := d.Set(channel+i, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Get returns the last value set for channel
+func (d *Dmx) Get(channel int) (byte, error) {
+	if channel < 1 || channel > DmxChannels {
+		return 0, dmxChannelError(channel)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.frame[channel-1], nil
+}
+
+//Flush sends every staged change, batching runs of consecutive channels into
+//single calls
+func (d *Dmx) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; i < DmxChannels; {
+		if !d.dirty[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < DmxChannels && d.dirty[i] && i-start < dmxWriteChunk {
+			i++
+		}
+		err := d.CallAndReturnNothing(MethodDmxWriteRange, start+1, d.frame[start:i])
+		if err != nil {
+			return err
+		}
+		for j := start; j < i; j++ {
+			d.dirty[j] = false
+		}
+	}
+	return nil
+}
+
+func dmxChannelError(channel int) error {
+	return fmt.Errorf("dmx channel %d out of range 1-%d", channel, DmxChannels)
+}
@@ -0,0 +1,71 @@
+package nango
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDmxFlush(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var frames []string
+	fail := false
+	lb.Handle(NamespaceDmx, MethodDmxWriteRange, func(c LoopbackCall) (string, bool) {
+		b, _ := hex.DecodeString(c.Args[1])
+		frames = append(frames, fmt.Sprintf("%s:%d", c.Args[0], len(b)))
+		if fail {
+			return "!ERR busy\tok\t1", true
+		}
+		return "", true
+	})
+	d := NewDmx(conn)
+	flush := func(expected ...string) {
+		t.Helper()
+		frames = nil
+		if err := d.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(frames, expected) {
+			t.Fatalf("expected frames %v, got %v", expected, frames)
+		}
+	}
+
+	values := make([]byte, 40)
+	for i := range values {
+		values[i] = byte(i + 1)
+	}
+	//a run of 40 channels is split at the chunk size, the sparse channels
+	//are sent on their own and an unchanged value isn't sent at all
+	d.SetRange(20, values)
+	d.Set(100, 1)
+	d.Set(101, 0)
+	d.Set(103, 3)
+	flush("20:32", "52:8", "100:1", "103:1")
+	flush()
+
+	//only the channels that changed within the run are resent
+	d.SetRange(20, values)
+	d.Set(50, 0xFF)
+	d.Set(52, 0xFF)
+	flush("50:1", "52:1")
+
+	//a value written immediately is no longer pending
+	d.Set(200, 5)
+	if err := d.Write(200, 6); err != nil {
+		t.Fatal(err)
+	}
+	flush()
+
+	//a failed batch stays pending and is sent again by the next flush
+	d.Set(300, 1)
+	fail = true
+	if err := d.Flush(); err == nil {
+		t.Fatal("expected the firmware error")
+	}
+	fail = false
+	flush("300:1")
+	if v, _ := d.Get(52); v != 0xFF {
+		t.Fatalf("expected channel 52 to be 0xFF, got %d", v)
+	}
+}
@@ -0,0 +1,123 @@
+package nango
+
+import (
+	"fmt"
+)
+
+//firmwareRxBuffer is the size of the serial receive buffer of AVR boards. A
+//request which doesn't fit in it is overrun before the firmware reads it.
+const firmwareRxBuffer = 64
+
+//TransferProgress is called after each chunk of a bulk transfer with the
+//number of bytes transferred so far and the total
+type TransferProgress func(done, total int)
+
+//EEPROM gives access to the arduino EEPROM library
+//https://www.arduino.cc/en/Reference/EEPROM
+type EEPROM struct {
+	*FirmwareClass
+}
+
+func NewEEPROM(conn Conn) *EEPROM {
+	return &EEPROM{
+		&FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceEEPROM,
+		},
+	}
+}
+
+//Size returns the size of the EEPROM in bytes
+func (e *EEPROM) Size() (int, error) {
+	return e.CallAndReturnInt(MethodEEPROMSize)
+}
+
+//Read returns the byte at address
+func (e *EEPROM) Read(address int) (byte, error) {
+	v, err := e.CallAndReturnInt(MethodEEPROMRead, address)
+	if err != nil {
+		return 0, err
+	}
+	return byte(v), nil
+}
+
+//Write writes b to address
+func (e *EEPROM) Write(address int, b byte) error {
+	return e.CallAndReturnNothing(MethodEEPROMWrite, address, int(b))
+}
+
+//chunkSize returns the most bytes a writeBytes call at address can carry in a
+//frame which fits the firmware's receive buffer, each byte taking two hex
+//digits. readBytes calls move the same amount so their responses stay as
+//small.
+func (e *EEPROM) chunkSize(address int) int {
+	frame, _ := appendFrame(nil, e.Namespace, e.Id, MethodEEPROMWriteBytes, []interface{}{address, []byte{}}, dialectOf(e.Conn))
+	n := (firmwareRxBuffer - len(frame)) / 2
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+//ReadBytes fills b with the contents of the EEPROM starting at address,
+//calling progress, if not nil, after each chunk
+func (e *EEPROM) ReadBytes(address int, b []byte, progress TransferProgress) error {
+	for done := 0; done < len(b); {
+		n := len(b) - done
+		if max := e.chunkSize(address + done); n > max {
+			n = max
+		}
+		r, err := e.CallAndBorrowBytes(MethodEEPROMReadBytes, address+done, n)
+		if err != nil {
+			return err
+		}
+		if len(r.Bytes()) != n {
+			err = fmt.Errorf("eeprom readBytes: requested %d bytes at %d but received %d", n, address+done, len(r.Bytes()))
+			r.Release()
+			return err
+		}
+		copy(b[done:], r.Bytes())
+		r.Release()
+		done += n
+		if progress != nil {
+			progress(done, len(b))
+		}
+	}
+	return nil
+}
+
+//WriteBytes writes b to the EEPROM starting at address, calling progress, if
+//not nil, after each chunk. The firmware only writes bytes which differ from
+//the stored value, sparing EEPROM wear.
+func (e *EEPROM) WriteBytes(address int, b []byte, progress TransferProgress) error {
+	for done := 0; done < len(b); {
+		n := len(b) - done
+		if max := e.chunkSize(address + done); n > max {
+			n = max
+		}
+		err := e.CallAndReturnNothing(MethodEEPROMWriteBytes, address+done, b[done:done+n])
+		if err != nil {
+			return err
+		}
+		done += n
+		if progress != nil {
+			progress(done, len(b))
+		}
+	}
+	return nil
+}
+
+//Dump returns the whole contents of the EEPROM
+func (e *EEPROM) Dump(progress TransferProgress) ([]byte, error) {
+	size, err := e.Size()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, size)
+	err = e.ReadBytes(0, b, progress)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,125 @@
+package nango
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+//fakeEEPROM answers the EEPROM calls of a loopback from memory and records the
+//size of each request frame
+type fakeEEPROM struct {
+	mem    [1024]byte
+	frames []int
+	//short, if set, makes readBytes return a byte less than requested from
+	//this address on
+	short int
+	//fail, if set, makes writeBytes fail from this address on
+	fail int
+}
+
+func (f *fakeEEPROM) attach(lb *Loopback) {
+	args := func(c LoopbackCall) (int, int) {
+		size := len(c.Namespace) + len(strconv.Itoa(c.Id)) + len(strconv.Itoa(len(c.Args))) + len(c.Method) + 4
+		for _, a := range c.Args {
+			size += len(a) + 1
+		}
+		f.frames = append(f.frames, size)
+		addr, _ := strconv.Atoi(c.Args[0])
+		return addr, size
+	}
+	lb.Handle(NamespaceEEPROM, MethodEEPROMReadBytes, func(c LoopbackCall) (string, bool) {
+		addr, _ := args(c)
+		n, _ := strconv.Atoi(c.Args[1])
+		if f.short > 0 && addr+n > f.short {
+			n--
+		}
+		return hex.EncodeToString(f.mem[addr : addr+n]), true
+	})
+	lb.Handle(NamespaceEEPROM, MethodEEPROMWriteBytes, func(c LoopbackCall) (string, bool) {
+		addr, _ := args(c)
+		if f.fail > 0 && addr >= f.fail {
+			return "!ERR write failed\taddr < 1024\t10", true
+		}
+		b, _ := hex.DecodeString(c.Args[1])
+		copy(f.mem[addr:], b)
+		return "", true
+	})
+}
+
+func TestEEPROMChunks(t *testing.T) {
+	lb, conn := openLoopback(t)
+	f := &fakeEEPROM{}
+	f.attach(lb)
+	e := NewEEPROM(conn)
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var progress, totals []int
+	record := func(done, total int) {
+		progress = append(progress, done)
+		totals = append(totals, total)
+	}
+
+	//a full chunk fills the receive buffer without overrunning it
+	if err := e.WriteBytes(990, data[:30], record); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.frames) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(f.frames))
+	}
+	if size := f.frames[0]; size > firmwareRxBuffer || size < firmwareRxBuffer-1 {
+		t.Fatalf("expected the first frame to fill the %d byte buffer, got %v", firmwareRxBuffer, f.frames)
+	}
+
+	f.frames, progress = nil, nil
+	if err := e.WriteBytes(0, data, record); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) < 2 || progress[len(progress)-1] != len(data) || totals[len(totals)-1] != len(data) {
+		t.Fatalf("expected progress after each chunk up to %d, got %v of %v", len(data), progress, totals)
+	}
+	for i, size := range f.frames {
+		if size > firmwareRxBuffer {
+			t.Fatalf("frame %d of %d bytes overruns the buffer", i, size)
+		}
+	}
+	read := make([]byte, len(data))
+	progress = nil
+	if err := e.ReadBytes(0, read, record); err != nil {
+		t.Fatal(err)
+	}
+	if string(read) != string(data) || progress[len(progress)-1] != len(data) {
+		t.Fatalf("expected the data read back with progress, got %v and %v", read, progress)
+	}
+}
+
+func TestEEPROMChunkErrors(t *testing.T) {
+	lb, conn := openLoopback(t)
+	f := &fakeEEPROM{short: 50, fail: 50}
+	f.attach(lb)
+	e := NewEEPROM(conn)
+	first := e.chunkSize(0)
+	var progress []int
+	record := func(done, total int) { progress = append(progress, done) }
+
+	//a short chunk fails the transfer after the chunks before it
+	if err := e.ReadBytes(0, make([]byte, 100), record); err == nil {
+		t.Fatal("expected an error for a short chunk")
+	}
+	if len(progress) == 0 || progress[0] != first || progress[len(progress)-1] >= 50 {
+		t.Fatalf("expected progress only for the chunks ending before address 50, got %v", progress)
+	}
+
+	//a failed write stops the transfer at the chunk starting at or after 50
+	progress = nil
+	var exc *FirmwareException
+	if err := e.WriteBytes(0, make([]byte, 100), record); !errors.As(err, &exc) {
+		t.Fatalf("expected the firmware's error, got %v", err)
+	}
+	if n := len(progress); n < 2 || progress[n-1] < 50 || progress[n-2] >= 50 {
+		t.Fatalf("expected progress up to the chunk starting past address 50, got %v", progress)
+	}
+}
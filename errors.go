@@ -0,0 +1,166 @@
+package nango
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//ErrPortClosed is returned by calls made on a connection which is not open
+var ErrPortClosed = errors.New("port is not opened: must call Open() first")
+
+//ErrTimeout matches, using errors.Is, every error caused by the firmware not
+//responding in time
+var ErrTimeout = errors.New("timeout")
+
+//TransportError reports the failure of an operation on a connection's port.
+//Like net.OpError it has Timeout and Temporary methods, which defer to the
+//underlying error.
+type TransportError struct {
+	Port string
+	//Op is the operation which failed: "read", "write" or "flush"
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s on port %s: %s", e.Op, e.Port, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+//Timeout reports whether the operation timed out
+func (e *TransportError) Timeout() bool {
+	t, ok := e.Err.(interface{ Timeout() bool })
+	return ok && t.Timeout()
+}
+
+//Temporary reports whether the operation may succeed if retried
+func (e *TransportError) Temporary() bool {
+	t, ok := e.Err.(interface{ Temporary() bool })
+	return ok && t.Temporary()
+}
+
+//FirmwareError reports a response from the firmware which could not be
+//interpreted as the result of the call
+type FirmwareError struct {
+	Namespace string
+	Method    string
+	Response  string
+	Err       error
+}
+
+func (e *FirmwareError) Error() string {
+	if e.Response == "" {
+		return fmt.Sprintf("%s.%s: unexpected response: %s", e.Namespace, e.Method, e.Err)
+	}
+	return fmt.Sprintf("%s.%s: unexpected response %q: %s", e.Namespace, e.Method, e.Response, e.Err)
+}
+
+func (e *FirmwareError) Unwrap() error {
+	return e.Err
+}
+
+//responseError reports that the response s to methodName on f could not be
+//parsed because of err
+func (f *FirmwareClass) responseError(methodName string, s string, err error) error {
+	return &FirmwareError{Namespace: f.Namespace, Method: methodName, Response: s, Err: err}
+}
+
+//recoverResponse turns a panic raised while handling the response to
+//methodName on f into a FirmwareError returned in *err. It must be deferred
+//directly.
+func (f *FirmwareClass) recoverResponse(methodName string, err *error) {
+	if r := recover(); r != nil {
+		*err = &FirmwareError{Namespace: f.Namespace, Method: methodName, Err: fmt.Errorf("panic handling response: %v", r)}
+	}
+}
+
+//Recover calls fn, returning a panic raised by it as an error, so that code
+//parsing responses in custom firmware class wrappers can't crash the host
+//process when it receives a malformed response
+func Recover(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("nango: recovered from panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+//exceptionPrefix starts the response the firmware sends in place of a
+//result when a call fails with an exception or assertion. It is followed by
+//the method, the reason and optionally the free memory in bytes, separated by
+//tabs.
+const exceptionPrefix = "!ERR "
+
+//FirmwareException is returned by calls the firmware failed to complete
+//because of an exception or failed assertion on the board
+type FirmwareException struct {
+	Method string
+	Reason string
+	//FreeMemory is the free memory in bytes when the exception was raised,
+	//or -1 if the firmware didn't report it
+	FreeMemory int
+}
+
+func (e *FirmwareException) Error() string {
+	if e.FreeMemory < 0 {
+		return fmt.Sprintf("firmware exception in %s: %s", e.Method, e.Reason)
+	}
+	return fmt.Sprintf("firmware exception in %s: %s (%d bytes free)", e.Method, e.Reason, e.FreeMemory)
+}
+
+//parseException decodes line if it is an exception payload, otherwise it
+//returns nil
+func parseException(line []byte) *FirmwareException {
+	if !bytes.HasPrefix(line, []byte(exceptionPrefix)) {
+		return nil
+	}
+	fields := strings.SplitN(string(line[len(exceptionPrefix):]), "\t", 3)
+	e := &FirmwareException{Method: fields[0], FreeMemory: -1}
+	if len(fields) > 1 {
+		e.Reason = fields[1]
+	}
+	if len(fields) > 2 {
+		if n, err := strconv.Atoi(fields[2]); err == nil {
+			e.FreeMemory = n
+		}
+	}
+	return e
+}
+
+//Error codes returned by the arduino Wire library's endTransmission
+const (
+	I2CDataTooLong = iota + 1
+	I2CAddressNack
+	I2CDataNack
+	I2COther
+)
+
+//I2CError reports a transmission to a slave which failed on the bus
+type I2CError struct {
+	Address I2CAddress
+	//Code is one of I2CDataTooLong, I2CAddressNack, I2CDataNack or I2COther
+	Code int
+}
+
+func (e *I2CError) Error() string {
+	return fmt.Sprintf("i2c transmission to 0x%02x: %s", int(e.Address), i2cCommunicationError(e.Code))
+}
+
+//I2CShortReadError is returned, along with the bytes received, when a slave
+//sends fewer bytes than requested
+type I2CShortReadError struct {
+	Address   I2CAddress
+	Requested int
+	Received  int
+}
+
+func (e *I2CShortReadError) Error() string {
+	return fmt.Sprintf("i2c request from 0x%02x: received %d of %d bytes", int(e.Address), e.Received, e.Requested)
+}
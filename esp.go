@@ -0,0 +1,163 @@
+package nango
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//espSendChunk is the most data the ESP-AT firmware accepts per send
+const espSendChunk = 2048
+
+//ESP8266 drives an ESP8266 WiFi co-processor running the ESP-AT firmware,
+//attached to a UART on the board, giving the board network reach
+type ESP8266 struct {
+	at *atModem
+	//Timeout is how long to wait for the response to a command
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	received bytes.Buffer
+	closed   bool
+}
+
+//NewESP8266 initializes the module on serial, which must already have been
+//begun at the module's baud rate, as a station with a single connection
+func NewESP8266(conn Conn, serial AttachedSerial) (*ESP8266, error) {
+	e := &ESP8266{at: newATModem(serial, clockOf(conn)), Timeout: 5 * time.Second}
+	e.at.data = e.receive
+	e.at.unsolicited = e.notify
+	e.at.isResponse = func(cmd string, lines []string, line string) bool {
+		return line != "CLOSED" && line != "WIFI DISCONNECT"
+	}
+	for _, cmd := range []string{"AT", "ATE0", "AT+CWMODE=1", "AT+CIPMUX=0"} {
+		if _, err := e.at.command(cmd, e.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *ESP8266) receive(b []byte) {
+	e.mu.Lock()
+	e.received.Write(b)
+	e.mu.Unlock()
+}
+
+func (e *ESP8266) notify(line string) {
+	if line == "CLOSED" {
+		e.mu.Lock()
+		e.closed = true
+		e.mu.Unlock()
+	}
+}
+
+//Join connects to the WiFi network ssid
+func (e *ESP8266) Join(ssid string, password string) error {
+	_, err := e.at.command(fmt.Sprintf("AT+CWJAP=%q,%q", ssid, password), 20*time.Second)
+	return err
+}
+
+//Leave disconnects from the WiFi network
+func (e *ESP8266) Leave() error {
+	_, err := e.at.command("AT+CWQAP", e.Timeout)
+	return err
+}
+
+//LocalIP returns the module's IP address on the network
+func (e *ESP8266) LocalIP() (string, error) {
+	lines, err := e.at.command("AT+CIFSR", e.Timeout)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+CIFSR:STAIP,") {
+			return strings.Trim(strings.TrimPrefix(line, "+CIFSR:STAIP,"), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("missing station IP in response %q", lines)
+}
+
+//Dial opens a connection to port on host. network is "tcp" or "udp". The
+//module supports one connection at a time.
+func (e *ESP8266) Dial(network string, host string, port int) (*ESPConn, error) {
+	_, err := e.at.command(fmt.Sprintf("AT+CIPSTART=%q,%q,%d", strings.ToUpper(network), host, port), 20*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.received.Reset()
+	e.closed = false
+	e.mu.Unlock()
+	return &ESPConn{esp: e, ReadTimeout: 2 * time.Second}, nil
+}
+
+//ESPConn is a connection opened by an ESP8266
+type ESPConn struct {
+	esp *ESP8266
+	//ReadTimeout is how long Read waits for data before returning a
+	//SerialTimeoutError
+	ReadTimeout time.Duration
+}
+
+//Write sends b over the connection
+func (c *ESPConn) Write(b []byte) (i int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > espSendChunk {
+			chunk = chunk[:espSendChunk]
+		}
+		_, err = c.esp.at.commandData("AT+CIPSEND="+strconv.Itoa(len(chunk)), string(chunk), "", c.esp.Timeout)
+		if err != nil {
+			return
+		}
+		i += len(chunk)
+		b = b[len(chunk):]
+	}
+	return
+}
+
+//Read reads the data received on the connection into b. It blocks until
+//data is available, the peer closes the connection, which returns io.EOF,
+//or ReadTimeout elapses.
+func (c *ESPConn) Read(b []byte) (int, error) {
+	deadline := c.esp.at.clock.Now().Add(c.ReadTimeout)
+	for {
+		c.esp.mu.Lock()
+		if c.esp.received.Len() > 0 {
+			n, _ := c.esp.received.Read(b)
+			c.esp.mu.Unlock()
+			return n, nil
+		}
+		closed := c.esp.closed
+		c.esp.mu.Unlock()
+		if closed {
+			return 0, io.EOF
+		}
+		err := c.esp.at.poll(deadline)
+		if errors.Is(err, ErrTimeout) {
+			return 0, SerialTimeoutError{Port: "ESP8266", Op: "Read"}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+//Close closes the connection
+func (c *ESPConn) Close() error {
+	c.esp.mu.Lock()
+	closed := c.esp.closed
+	c.esp.closed = true
+	c.esp.mu.Unlock()
+	if closed {
+		return nil
+	}
+	_, err := c.esp.at.command("AT+CIPCLOSE", c.esp.Timeout)
+	return err
+}
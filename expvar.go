@@ -0,0 +1,30 @@
+package nango
+
+import "expvar"
+
+//connection counters published via expvar under the "nango" map for
+//debugging long running services, e.g. through /debug/vars
+var (
+	statOpenPorts     = new(expvar.Int)
+	statInFlightCalls = new(expvar.Int)
+	statCalls         = new(expvar.Int)
+	statCallErrors    = new(expvar.Int)
+	statTimeouts      = new(expvar.Int)
+	statReconnects    = new(expvar.Int)
+	statBytesWritten  = new(expvar.Int)
+	statBytesRead     = new(expvar.Int)
+	statRateLimited   = new(expvar.Int)
+)
+
+func init() {
+	m := expvar.NewMap("nango")
+	m.Set("open_ports", statOpenPorts)
+	m.Set("in_flight_calls", statInFlightCalls)
+	m.Set("calls", statCalls)
+	m.Set("call_errors", statCallErrors)
+	m.Set("timeouts", statTimeouts)
+	m.Set("reconnects", statReconnects)
+	m.Set("bytes_written", statBytesWritten)
+	m.Set("bytes_read", statBytesRead)
+	m.Set("rate_limited", statRateLimited)
+}
@@ -0,0 +1,57 @@
+package nango
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//FanOutResult is the outcome of a FanOut function on one connection
+type FanOutResult struct {
+	Conn  *FirmwareConnection
+	Value interface{}
+	Err   error
+}
+
+//FanOut runs fn against every connection concurrently, e.g. to drive a bank
+//of identical boards, and returns the results in the same order as conns
+func FanOut(conns []*FirmwareConnection, fn func(conn *FirmwareConnection) (interface{}, error)) []FanOutResult {
+	results := make([]FanOutResult, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn *FirmwareConnection) {
+			defer wg.Done()
+			v, err := fn(conn)
+			results[i] = FanOutResult{Conn: conn, Value: v, Err: err}
+		}(i, conn)
+	}
+	wg.Wait()
+	return results
+}
+
+//FanOutError lists the connections on which a FanOut function failed
+type FanOutError []FanOutResult
+
+func (e FanOutError) Error() string {
+	msgs := make([]string, len(e))
+	for i, r := range e {
+		msgs[i] = fmt.Sprintf("%s: %s", r.Conn.Name(), r.Err)
+	}
+	return fmt.Sprintf("%d connections failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+//FanOutErr returns a FanOutError holding the failed results, or nil if every
+//connection succeeded
+func FanOutErr(results []FanOutResult) error {
+	var failed FanOutError
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
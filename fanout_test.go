@@ -0,0 +1,102 @@
+package nango
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//openBank opens n loopback boards, each answering digitalRead with its index
+func openBank(t *testing.T, n int) ([]*Loopback, []*FirmwareConnection) {
+	t.Helper()
+	lbs := make([]*Loopback, n)
+	conns := make([]*FirmwareConnection, n)
+	for i := range conns {
+		lbs[i], conns[i] = openLoopback(t)
+		lbs[i].Respond(NamespaceArduino, MethodDigitalRead, strconv.Itoa(i))
+	}
+	return lbs, conns
+}
+
+func digitalRead(conn *FirmwareConnection) (interface{}, error) {
+	return NewArduinoApi(conn).DigitalRead("D2")
+}
+
+func TestFanOut(t *testing.T) {
+	_, conns := openBank(t, 3)
+	//every call must be running at once for any of them to proceed
+	var mu sync.Mutex
+	started := 0
+	all := make(chan struct{})
+	results := FanOut(conns, func(conn *FirmwareConnection) (interface{}, error) {
+		mu.Lock()
+		started++
+		if started == len(conns) {
+			close(all)
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("the calls did not run concurrently")
+		}
+		return digitalRead(conn)
+	})
+	if err := FanOutErr(results); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r.Conn != conns[i] || r.Value != i {
+			t.Fatalf("expected board %d to return %d, got %v", i, i, r.Value)
+		}
+	}
+}
+
+func TestFanOutSlowBoard(t *testing.T) {
+	lbs, conns := openBank(t, 3)
+	lbs[1].Handle(NamespaceArduino, MethodDigitalRead, func(LoopbackCall) (string, bool) { return "", false })
+	var mu sync.Mutex
+	var finished []*FirmwareConnection
+	results := FanOut(conns, func(conn *FirmwareConnection) (interface{}, error) {
+		v, err := digitalRead(conn)
+		mu.Lock()
+		finished = append(finished, conn)
+		mu.Unlock()
+		return v, err
+	})
+	//the boards that answer aren't held up by the one that doesn't
+	if len(finished) != 3 || finished[2] != conns[1] {
+		t.Fatal("expected the silent board to finish last")
+	}
+	if results[0].Value != 0 || results[2].Value != 2 {
+		t.Fatalf("expected the other boards' values, got %v and %v", results[0].Value, results[2].Value)
+	}
+	var failed FanOutError
+	if !errors.As(FanOutErr(results), &failed) || len(failed) != 1 || failed[0].Conn != conns[1] {
+		t.Fatalf("expected only the silent board to fail, got %v", failed)
+	}
+	if !errors.Is(failed[0].Err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", failed[0].Err)
+	}
+}
+
+func TestFanOutClosedBoard(t *testing.T) {
+	_, conns := openBank(t, 3)
+	conns[2].Close()
+	results := FanOut(conns, digitalRead)
+	for i, r := range results[:2] {
+		if r.Err != nil || r.Value != i {
+			t.Fatalf("expected board %d to return %d, got %v, %v", i, i, r.Value, r.Err)
+		}
+	}
+	err := FanOutErr(results)
+	if !errors.Is(results[2].Err, ErrPortClosed) || err == nil {
+		t.Fatalf("expected the closed board to fail, got %v", results[2].Err)
+	}
+	if !strings.HasPrefix(err.Error(), "1 connections failed: transport: ") {
+		t.Fatalf("unexpected error message %q", err)
+	}
+}
@@ -0,0 +1,252 @@
+package nango
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//Packet identifiers of the R30x protocol
+const (
+	fpCommand = 0x01
+	fpAck     = 0x07
+)
+
+//Instructions of the R30x protocol
+const (
+	fpGenImg      = 0x01
+	fpImg2Tz      = 0x02
+	fpSearch      = 0x04
+	fpRegModel    = 0x05
+	fpStore       = 0x06
+	fpDeleteChar  = 0x0c
+	fpEmpty       = 0x0d
+	fpVfyPwd      = 0x13
+	fpTemplateNum = 0x1d
+)
+
+//Confirmation codes of R30x modules referred to by the driver
+const (
+	FingerprintOK       = 0x00
+	FingerprintNoFinger = 0x02
+	FingerprintNotFound = 0x09
+)
+
+//FingerprintError is a failure reported by a fingerprint module
+type FingerprintError struct {
+	Instruction byte
+	//Code is the module's confirmation code
+	Code byte
+}
+
+func (e *FingerprintError) Error() string {
+	return fmt.Sprintf("fingerprint instruction 0x%02x failed with code 0x%02x", e.Instruction, e.Code)
+}
+
+//ErrNoMatch is returned by Search when the finger matches no template
+var ErrNoMatch = errors.New("fingerprint: no matching template")
+
+//EnrollStep reports the progress of an enrollment
+type EnrollStep int
+
+const (
+	EnrollPlaceFinger EnrollStep = iota
+	EnrollRemoveFinger
+	EnrollPlaceAgain
+	EnrollStoring
+)
+
+//Fingerprint drives an R30x/ZFM series fingerprint module attached to a UART
+//on the board
+type Fingerprint struct {
+	serial AttachedSerial
+	clock  Clock
+	//Address is the module's address, 0xffffffff by default
+	Address uint32
+	//Capacity is the number of templates the module stores
+	Capacity int
+	//PollInterval is how often the module is asked for an image while
+	//waiting for a finger
+	PollInterval time.Duration
+}
+
+//NewFingerprint verifies password with the module on serial, which must
+//already have been begun at the module's baud rate, 57600 by default
+func NewFingerprint(conn Conn, serial AttachedSerial, password uint32) (*Fingerprint, error) {
+	f := &Fingerprint{
+		serial:       serial,
+		clock:        clockOf(conn),
+		Address:      0xffffffff,
+		Capacity:     162,
+		PollInterval: 100 * time.Millisecond,
+	}
+	var pw [4]byte
+	binary.BigEndian.PutUint32(pw[:], password)
+	if _, err := f.command(fpVfyPwd, pw[:]...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//exchange sends an instruction and returns the module's confirmation code
+//and the parameters following it
+func (f *Fingerprint) exchange(instruction byte, params ...byte) (byte, []byte, error) {
+	packet := make([]byte, 0, 12+len(params))
+	packet = append(packet, 0xef, 0x01)
+	packet = append(packet, byte(f.Address>>24), byte(f.Address>>16), byte(f.Address>>8), byte(f.Address))
+	n := len(params) + 3
+	packet = append(packet, fpCommand, byte(n>>8), byte(n), instruction)
+	packet = append(packet, params...)
+	sum := fpChecksum(packet[6:])
+	packet = append(packet, byte(sum>>8), byte(sum))
+	if _, err := f.serial.Write(packet); err != nil {
+		return 0, nil, err
+	}
+
+	var header [9]byte
+	if _, err := io.ReadFull(f.serial, header[:]); err != nil {
+		return 0, nil, err
+	}
+	if header[0] != 0xef || header[1] != 0x01 || header[6] != fpAck {
+		return 0, nil, fmt.Errorf("fingerprint: malformed response header % x", header)
+	}
+	n = int(binary.BigEndian.Uint16(header[7:]))
+	if n < 3 {
+		return 0, nil, fmt.Errorf("fingerprint: response length %d too short", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(f.serial, body); err != nil {
+		return 0, nil, err
+	}
+	if fpChecksum(append(header[6:], body[:n-2]...)) != binary.BigEndian.Uint16(body[n-2:]) {
+		return 0, nil, errors.New("fingerprint: response checksum mismatch")
+	}
+	return body[0], body[1 : n-2], nil
+}
+
+//fpChecksum sums the packet identifier, length and contents of a packet
+func fpChecksum(b []byte) uint16 {
+	var sum uint16
+	for _, v := range b {
+		sum += uint16(v)
+	}
+	return sum
+}
+
+//command sends an instruction, returning a FingerprintError if it fails
+func (f *Fingerprint) command(instruction byte, params ...byte) ([]byte, error) {
+	code, data, err := f.exchange(instruction, params...)
+	if err != nil {
+		return nil, err
+	}
+	if code != FingerprintOK {
+		return nil, &FingerprintError{Instruction: instruction, Code: code}
+	}
+	return data, nil
+}
+
+//waitFinger polls the module until a finger is present, or absent if
+//present is false
+func (f *Fingerprint) waitFinger(ctx context.Context, present bool) error {
+	for {
+		code, _, err := f.exchange(fpGenImg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case code == FingerprintOK && present, code == FingerprintNoFinger && !present:
+			return nil
+		case code != FingerprintOK && code != FingerprintNoFinger:
+			return &FingerprintError{Instruction: fpGenImg, Code: code}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.clock.After(f.PollInterval):
+		}
+	}
+}
+
+//Enroll records a finger, placed twice, as the template at page. progress,
+//if not nil, is called as the user should be prompted.
+func (f *Fingerprint) Enroll(ctx context.Context, page int, progress func(EnrollStep)) error {
+	report := func(s EnrollStep) {
+		if progress != nil {
+			progress(s)
+		}
+	}
+	report(EnrollPlaceFinger)
+	if err := f.waitFinger(ctx, true); err != nil {
+		return err
+	}
+	if _, err := f.command(fpImg2Tz, 1); err != nil {
+		return err
+	}
+	report(EnrollRemoveFinger)
+	if err := f.waitFinger(ctx, false); err != nil {
+		return err
+	}
+	report(EnrollPlaceAgain)
+	if err := f.waitFinger(ctx, true); err != nil {
+		return err
+	}
+	if _, err := f.command(fpImg2Tz, 2); err != nil {
+		return err
+	}
+	report(EnrollStoring)
+	if _, err := f.command(fpRegModel); err != nil {
+		return err
+	}
+	_, err := f.command(fpStore, 1, byte(page>>8), byte(page))
+	return err
+}
+
+//Search waits for a finger and returns the page of the template it matches
+//and the confidence of the match
+func (f *Fingerprint) Search(ctx context.Context) (page int, score int, err error) {
+	if err = f.waitFinger(ctx, true); err != nil {
+		return
+	}
+	if _, err = f.command(fpImg2Tz, 1); err != nil {
+		return
+	}
+	code, data, err := f.exchange(fpSearch, 1, 0, 0, byte(f.Capacity>>8), byte(f.Capacity))
+	switch {
+	case err != nil:
+		return
+	case code == FingerprintNotFound:
+		return 0, 0, ErrNoMatch
+	case code != FingerprintOK:
+		return 0, 0, &FingerprintError{Instruction: fpSearch, Code: code}
+	case len(data) < 4:
+		return 0, 0, errors.New("fingerprint: short search response")
+	}
+	return int(binary.BigEndian.Uint16(data)), int(binary.BigEndian.Uint16(data[2:])), nil
+}
+
+//Delete deletes the template at page
+func (f *Fingerprint) Delete(page int) error {
+	_, err := f.command(fpDeleteChar, byte(page>>8), byte(page), 0, 1)
+	return err
+}
+
+//Clear deletes every template
+func (f *Fingerprint) Clear() error {
+	_, err := f.command(fpEmpty)
+	return err
+}
+
+//Count returns the number of templates stored
+func (f *Fingerprint) Count() (int, error) {
+	data, err := f.command(fpTemplateNum)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 2 {
+		return 0, errors.New("fingerprint: short template count response")
+	}
+	return int(binary.BigEndian.Uint16(data)), nil
+}
@@ -0,0 +1,106 @@
+package nango
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeSensor is an AttachedSerial emulating an R30x fingerprint module
+type fakeSensor struct {
+	mu        sync.Mutex
+	fingers   []bool
+	templates map[int]bool
+	response  bytes.Buffer
+}
+
+func (s *fakeSensor) Begin(baud int) error { return nil }
+
+func (s *fakeSensor) Available() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.response.Len(), nil
+}
+
+func (s *fakeSensor) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.response.Len() == 0 {
+		return 0, SerialTimeoutError{Port: "sensor", Op: "Read"}
+	}
+	return s.response.Read(b)
+}
+
+func (s *fakeSensor) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code, data := byte(FingerprintOK), []byte(nil)
+	switch b[9] {
+	case fpGenImg:
+		finger := false
+		if len(s.fingers) > 0 {
+			finger, s.fingers = s.fingers[0], s.fingers[1:]
+		}
+		if !finger {
+			code = FingerprintNoFinger
+		}
+	case fpStore:
+		s.templates[int(b[11])<<8|int(b[12])] = true
+	case fpSearch:
+		code = FingerprintNotFound
+		for page := range s.templates {
+			code, data = FingerprintOK, []byte{byte(page >> 8), byte(page), 0, 90}
+		}
+	case fpTemplateNum:
+		data = []byte{0, byte(len(s.templates))}
+	case fpDeleteChar:
+		delete(s.templates, int(b[10])<<8|int(b[11]))
+	}
+	n := len(data) + 3
+	packet := []byte{0xef, 0x01, 0xff, 0xff, 0xff, 0xff, fpAck, byte(n >> 8), byte(n), code}
+	packet = append(packet, data...)
+	sum := fpChecksum(packet[6:])
+	s.response.Write(append(packet, byte(sum>>8), byte(sum)))
+	return len(b), nil
+}
+
+func TestFingerprint(t *testing.T) {
+	_, conn := openLoopback(t)
+	s := &fakeSensor{templates: map[int]bool{}}
+	f, err := NewFingerprint(conn, s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.PollInterval = 0
+	ctx := context.Background()
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := f.Search(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected search without a finger to be cancelled, got %v", err)
+	}
+	s.fingers = []bool{false, true, true, false, true}
+	var steps []EnrollStep
+	if err := f.Enroll(ctx, 7, func(s EnrollStep) { steps = append(steps, s) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 4 || steps[3] != EnrollStoring {
+		t.Fatalf("unexpected enrollment progress %v", steps)
+	}
+	if n, err := f.Count(); err != nil || n != 1 {
+		t.Fatalf("expected 1 template, got %d (%v)", n, err)
+	}
+	s.fingers = []bool{true}
+	if page, score, err := f.Search(ctx); err != nil || page != 7 || score != 90 {
+		t.Fatalf("expected match on page 7, got %d %d (%v)", page, score, err)
+	}
+	if err := f.Delete(7); err != nil {
+		t.Fatal(err)
+	}
+	s.fingers = []bool{true}
+	if _, _, err := f.Search(ctx); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+}
@@ -2,64 +2,219 @@ package nango
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/justinsantoro/nango/serial"
+	"io"
 	"log"
 	"strconv"
 	"sync"
 	"time"
 )
 
-type SerialTimeoutError string
+//SerialTimeoutError reports that the firmware didn't respond in time
+type SerialTimeoutError struct {
+	//Port names the port or firmware class the operation was made on
+	Port string
+	Op   string
+}
 
 func (t SerialTimeoutError) String() string {
-	return string(t)
+	return t.Port + " " + t.Op + " timeout"
 }
 
 func (t SerialTimeoutError) Error() string {
 	return t.String()
 }
 
-var mutex = new(sync.Mutex)
+//Is reports whether target is ErrTimeout
+func (t SerialTimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+//Timeout reports true, as net.Error does for timeouts
+func (t SerialTimeoutError) Timeout() bool {
+	return true
+}
+
+//Temporary reports true, as a later call may succeed
+func (t SerialTimeoutError) Temporary() bool {
+	return true
+}
+
+//CallObserver is notified of the outcome of every method call made on a
+//connection, e.g. to collect metrics
+type CallObserver interface {
+	ObserveCall(namespace string, method string, elapsed time.Duration, err error)
+}
+
+//readPollInterval bounds how long a single read of the port blocks, so that
+//ReadLine can notice its deadline has passed while no data is arriving
+const readPollInterval = 100 * time.Millisecond
+
+//deadlineReader reads from the port until its deadline passes. The port is
+//opened with a read timeout so reads return periodically even when the
+//firmware is silent, which lets ReadLine give up without leaving a blocked
+//reader behind to steal the response of a later call.
+type deadlineReader struct {
+	r        io.Reader
+	name     string
+	clock    Clock
+	deadline time.Time
+}
+
+func (d *deadlineReader) Read(b []byte) (int, error) {
+	for {
+		n, err := d.r.Read(b)
+		statBytesRead.Add(int64(n))
+		//a read timing out with no data is reported as io.EOF
+		if n > 0 || err != nil && err != io.EOF {
+			return n, err
+		}
+		if !d.clock.Now().Before(d.deadline) {
+			return 0, SerialTimeoutError{Port: d.name, Op: "ReadLine"}
+		}
+	}
+}
 
 type FirmwareConnection struct {
 	readWriter        *bufio.ReadWriter
+	reader            *deadlineReader
+	line              []byte //response currently being received
 	SerialConfig      *serial.Config
 	SleepAfterConnect time.Duration
 	ReadTimeout       time.Duration
-	port              *serial.Port
+	port              Transport
+	opened            bool
+	//Observer, if set, is notified of every call made on the connection
+	Observer CallObserver
+	//CoalesceWindow, if non-zero, is how long the dispatcher waits for more
+	//calls after picking up a call, so calls issued by several goroutines can
+	//be sent to the firmware as one transfer, amortizing the USB frame latency
+	//which dominates small writes
+	CoalesceWindow time.Duration
+	//SlowCallThreshold, if non-zero, logs every call taking longer than the
+	//threshold along with a summary of its arguments
+	SlowCallThreshold time.Duration
+	//RecordLatency enables the per-method histograms returned by Latencies
+	RecordLatency bool
+	//AdaptiveTimeout, if set, replaces ReadTimeout with a timeout derived
+	//from the round trip times observed on the connection
+	AdaptiveTimeout *AdaptiveTimeout
+	rtt             rttTracker
+	latencies       latencies
+	queueMu         sync.Mutex
+	queue           *callQueue //nil while the dispatcher isn't running
+	quit            chan struct{}
+	dispatcherDone  chan struct{}
+	logger          *log.Logger
+	retry           retryPolicy
+	tracer          Tracer
+	dial            DialFunc
+	clock           Clock //nil for the system clock
+	requiredClasses []string
+	dialect         *Dialect //nil for NanpyDialect
+	middleware      []Middleware
+	handler         CallFunc //middleware chain built from middleware, nil if none
+	inFlight        inFlight
+	hooksMu         sync.Mutex
+	//hooks are the OnClose hooks and safe states, in the order registered
+	hooks         []safeState
+	sleep         sleepState
+	wakeDelay     time.Duration //set with WithWakeDelay
+	busQuietUntil time.Time
+	busTurnaround time.Duration //set with WithBusTurnaround
+	secret        []byte        //nil if the connection isn't authenticated
+	limiter       *rateLimiter
 }
 
-func NewFirmwareConnection(serialConf *serial.Config) *FirmwareConnection {
-	return &FirmwareConnection{
+//NewFirmwareConnection returns an unopened connection to the firmware on the
+//serial port described by serialConf, configured by opts. serialConf may be
+//nil if a transport is given with WithTransport.
+func NewFirmwareConnection(serialConf *serial.Config, opts ...Option) *FirmwareConnection {
+	s := &FirmwareConnection{
 		SerialConfig:      serialConf,
 		SleepAfterConnect: 0,
 		port:              nil,
 		ReadTimeout:       2 * time.Second,
+		wakeDelay:         defaultWakeDelay,
+		busTurnaround:     defaultBusTurnaround,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.handler = s.chain()
+	return s
+}
+
+//Name returns the name of the connection's serial port
+func (s *FirmwareConnection) Name() string {
+	if s.SerialConfig == nil {
+		return "transport"
+	}
+	return s.SerialConfig.Name
+}
+
+//openTransport opens the connection's transport, by default its serial port
+func (s *FirmwareConnection) openTransport() (Transport, error) {
+	if s.dial != nil {
+		return s.dial()
+	}
+	conf := *s.SerialConfig
+	if conf.ReadTimeout <= 0 || conf.ReadTimeout > readPollInterval {
+		conf.ReadTimeout = readPollInterval
+	}
+	p, err := serial.OpenPort(&conf)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 func (s *FirmwareConnection) Open() error {
 	//log.Printf("opening port:%v [%v baud]\n", s.SerialConfig.Name, s.SerialConfig.Baud)
 	var err error
-	s.port, err = serial.OpenPort(s.SerialConfig)
+	s.port, err = s.openTransport()
 	if err != nil {
+		s.port = nil
 		return err
 	}
-	s.readWriter = bufio.NewReadWriter(bufio.NewReader(s.port), bufio.NewWriter(s.port))
+	statOpenPorts.Add(1)
+	if s.opened {
+		statReconnects.Add(1)
+	}
+	s.opened = true
+	s.reader = &deadlineReader{r: s.port, name: s.Name(), clock: clockOf(s)}
+	s.readWriter = bufio.NewReadWriter(bufio.NewReader(s.reader), bufio.NewWriter(s.port))
 	//log.Println("port opened successfully")
-	time.Sleep(s.SleepAfterConnect)
-	return s.port.Flush()
+	clockOf(s).Sleep(s.SleepAfterConnect)
+	err = s.port.Flush()
+	if err != nil {
+		return err
+	}
+	s.startDispatcher()
+	err = s.authenticate()
+	if err == nil {
+		err = s.checkClasses()
+	}
+	if err != nil {
+		s.Close()
+		return err
+	}
+	return nil
 }
 
 func (s *FirmwareConnection) Write(b []byte) error {
 	if s.port == nil {
 		return portClosed()
 	}
-	_, err := s.readWriter.Write(b)
+	n, err := s.readWriter.Write(b)
+	statBytesWritten.Add(int64(n))
 	if err != nil {
-		return err
+		return &TransportError{Port: s.Name(), Op: "write", Err: err}
 	}
 	//log.Printf("successfully wrote %v bytes to port %v\n", i, s.SerialConfig.Name)
 	return nil
@@ -69,191 +224,434 @@ func (s *FirmwareConnection) Flush() error {
 	if s.port == nil {
 		return portClosed()
 	}
-	return s.readWriter.Flush()
+	if err := s.readWriter.Flush(); err != nil {
+		return &TransportError{Port: s.Name(), Op: "flush", Err: err}
+	}
+	return nil
 }
 
+//ReadLine returns the next line received from the firmware without its line
+//terminator. Bytes received beyond the end of the line stay buffered on the
+//connection for the next call. The returned slice is only valid until the
+//next call to ReadLine.
 func (s *FirmwareConnection) ReadLine() (b []byte, err error) {
 	if s.port == nil {
 		err = portClosed()
 		return
 	}
-	scanner := bufio.NewScanner(s.readWriter.Reader)
-	b = make([]byte, 0)
-	errChan := make(chan error)
-	go func() {
-		scanner.Scan()
-		errChan <- scanner.Err()
-	}()
-	select {
-	case err = <-errChan:
-		if err != nil {
-			err = errors.New(fmt.Sprintf("error scanning bytes from port %s\n: %s", s.SerialConfig.Name, err))
+	s.reader.deadline = clockOf(s).Now().Add(s.readTimeout())
+	for {
+		var chunk []byte
+		chunk, err = s.readWriter.ReadSlice('\n')
+		s.line = append(s.line, chunk...)
+		if err != bufio.ErrBufferFull {
+			break
 		}
-	case <-time.After(s.ReadTimeout):
-		err = SerialTimeoutError(s.SerialConfig.Name + " ReadLine timeout")
 	}
-	//if there was an error, flush the port
-	if err != nil {
-		errFlush := s.port.Flush()
-		if errFlush != nil {
-			log.Printf("error flushing serial port %s: %s", s.SerialConfig.Name, errFlush)
-		}
+	if err == nil {
+		//log.Printf("successfully read line of %v bytes from port %v\n", len(s.line), s.SerialConfig.Name)
+		b = bytes.TrimRight(s.line, "\r\n")
+		s.line = s.line[:0]
 		return
 	}
-	//log.Printf("successfully read line of %v bytes from port %v\n", len(b), s.SerialConfig.Name)
-	b = scanner.Bytes()
+	if !errors.Is(err, ErrTimeout) {
+		err = &TransportError{Port: s.Name(), Op: "read", Err: err}
+	}
+	//if there was an error, flush the port so a late response can't be
+	//mistaken for the response to the next call
+	errFlush := s.FlushPort()
+	if errFlush != nil {
+		s.logf("error flushing serial port %s: %s", s.Name(), errFlush)
+	}
 	return
 }
 
+//FlushPort discards all data pending on the port, including anything already
+//buffered by the connection
 func (s *FirmwareConnection) FlushPort() error {
 	if s.port == nil {
 		return portClosed()
 	}
+	if len(s.line) > 0 || s.readWriter.Reader.Buffered() > 0 {
+		s.logf("discarding %d buffered bytes from port %s", len(s.line)+s.readWriter.Reader.Buffered(), s.Name())
+	}
+	s.line = s.line[:0]
+	s.readWriter.Reader.Reset(s.reader)
 	return s.port.Flush()
 }
 
+//closeTimeout bounds how long Close spends applying the safe states, so
+//closing a connection to a board which has stopped responding doesn't hang
+const closeTimeout = 5 * time.Second
+
+//Close applies the connection's safe states, giving up after a few seconds,
+//and closes the port. Unlike CloseWithContext it doesn't wait for the calls
+//in flight.
 func (s *FirmwareConnection) Close() error {
 	if s.port == nil {
 		return nil
 	}
-	return s.port.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	s.ApplySafeStates(ctx)
+	return s.closePort()
+}
+
+//closePort stops the dispatcher, flushes what it has written and closes the
+//port
+func (s *FirmwareConnection) closePort() error {
+	s.stopDispatcher()
+	errFlush := s.Flush()
+	err := s.port.Close()
+	s.port = nil
+	statOpenPorts.Add(-1)
+	if errFlush != nil {
+		return errFlush
+	}
+	return err
+}
+
+func (s *FirmwareConnection) observe(namespace string, method string, args []interface{}, start time.Time, err error) {
+	elapsed := clockOf(s).Now().Sub(start)
+	if s.RecordLatency {
+		s.latencies.observe(namespace, method, elapsed)
+	}
+	if s.SlowCallThreshold > 0 && elapsed > s.SlowCallThreshold {
+		s.logSlowCall(namespace, method, args, elapsed, err)
+	}
+	statCalls.Add(1)
+	if err != nil {
+		statCallErrors.Add(1)
+		if errors.Is(err, ErrTimeout) {
+			statTimeouts.Add(1)
+		}
+	}
+	if s.Observer != nil {
+		s.Observer.ObserveCall(namespace, method, elapsed, err)
+	}
 }
 
 func portClosed() error {
-	return errors.New("port is not opened: must call Open() first")
+	return ErrPortClosed
 }
 
-func write(data interface{}, conn *FirmwareConnection) error {
-	var s string
+//appendArg appends the null terminated wire representation of data to b
+func appendArg(b []byte, data interface{}, d *Dialect) ([]byte, error) {
 	switch v := data.(type) {
+	case nil:
+		b = append(b, d.Nil...)
 	case string:
-		s = v
+		b = append(b, v...)
 	case int:
-		s = strconv.Itoa(v)
+		b = strconv.AppendInt(b, int64(v), 10)
+	case byte:
+		b = strconv.AppendInt(b, int64(v), 10)
+	case []byte:
+		//encode byte buffers as hex so they survive the null-terminated framing
+		n := len(b)
+		b = append(b, make([]byte, hex.EncodedLen(len(v)))...)
+		hex.Encode(b[n:], v)
 	case bool:
-		//encode bool types as Python string representations of booleans
 		switch v {
 		case true:
-			s = "True"
+			b = append(b, d.True...)
 		default:
-			s = "False"
+			b = append(b, d.False...)
 		}
 	default:
-		return errors.New(fmt.Sprintf("Firmware Write: Unsupported type %T", v))
+		return b, fmt.Errorf("Firmware Write: Unsupported type %T", v)
 	}
-	//fmt.Printf(s)
-	return conn.Write([]byte(s + "\000"))
+	return append(b, 0), nil
 }
 
-func returnValue(conn *FirmwareConnection) (v string, err error) {
-	b, err := conn.ReadLine()
-	if err != nil {
-		return
+//appendFrame appends the complete request for a method call to b: namespace,
+//object id, argument count, method name and arguments. Arguments which are
+//themselves []interface{} are flattened and nil arguments are encoded as
+//the dialect specifies.
+func appendFrame(b []byte, namespace string, id int, methodName string, args []interface{}, d *Dialect) ([]byte, error) {
+	nargs := 0
+	for _, arg := range args {
+		if ls, ok := arg.([]interface{}); ok {
+			for _, el := range ls {
+				if !d.skip(el) {
+					nargs++
+				}
+			}
+		} else if !d.skip(arg) {
+			nargs++
+		}
 	}
-	v = string(b)
-	return
-}
-
-func call(namespace string, id int, args []interface{}, conn *FirmwareConnection) (v string, err error) {
-	toprint := []interface{}{}
-	nel := 0
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	err = write(namespace, conn)
-	if err != nil {
-		return
-	}
-	err = write(id, conn)
-	if err != nil {
-		return
-	}
+	b = append(b, namespace...)
+	b = append(b, 0)
+	b = strconv.AppendInt(b, int64(id), 10)
+	b = append(b, 0)
+	b = strconv.AppendInt(b, int64(nargs), 10)
+	b = append(b, 0)
+	b = append(b, methodName...)
+	b = append(b, 0)
 
+	var err error
 	for _, arg := range args {
 		if ls, ok := arg.([]interface{}); ok {
 			for _, el := range ls {
-				if el != nil {
-					toprint = append(toprint, el)
-					nel++
+				if !d.skip(el) {
+					b, err = appendArg(b, el, d)
+					if err != nil {
+						return b, err
+					}
 				}
 			}
-		} else {
-			if arg != nil {
-				toprint = append(toprint, arg)
-				nel++
+		} else if !d.skip(arg) {
+			b, err = appendArg(b, arg, d)
+			if err != nil {
+				return b, err
 			}
 		}
 	}
+	return b, nil
+}
 
-	err = write(nel-1, conn)
+//call sends a method call and waits for its response, which is returned in a
+//pooled buffer the caller must release
+func call(ctx context.Context, c *CallInfo, conn *FirmwareConnection) (v *Buffer, err error) {
+	r := getRequest(ctx, c.Priority)
+	defer putRequest(r)
+	r.key = c.Key
+	r.sleep = c.sleep
+	if c.Node != nil {
+		r.node = c.Node
+		r.frame = appendNodeAddress(r.frame, c.Node.Address)
+	}
+	r.frame, err = appendFrame(r.frame, c.Namespace, c.Id, c.Method, c.Args, dialectOf(conn))
 	if err != nil {
 		return
 	}
+	r.n = 1
+	conn.do(r)
+	return r.results[0].value, r.results[0].err
+}
 
-	for _, elprint := range toprint {
-		err = write(elprint, conn)
-		if err != nil {
-			return
-		}
-	}
-	err = conn.Flush()
+func ArduinoMethodCall(f *FirmwareClass, methodName string, args ...interface{}) (string, error) {
+	return methodCall(context.Background(), f, methodName, args)
+}
+
+//ArduinoMethodCallContext is like ArduinoMethodCall but the call is abandoned
+//if ctx is done before it has been sent to the firmware
+func ArduinoMethodCallContext(ctx context.Context, f *FirmwareClass, methodName string, args ...interface{}) (string, error) {
+	return methodCall(ctx, f, methodName, args)
+}
+
+func methodCall(ctx context.Context, f *FirmwareClass, methodName string, args []interface{}) (string, error) {
+	b, err := methodCallBuffer(ctx, f, methodName, args)
 	if err != nil {
-		return
+		return "", err
 	}
-	return returnValue(conn)
+	v := b.String()
+	b.Release()
+	return v, nil
 }
 
-func prependName(args []interface{}, name string) []interface{} {
-	args = append(args, 0)
-	copy(args[1:], args)
-	args[0] = name
-	return args
+func methodCallBuffer(ctx context.Context, f *FirmwareClass, methodName string, args []interface{}) (*Buffer, error) {
+	return keyedMethodCall(ctx, f, "", methodName, args)
 }
 
-func ArduinoMethodCall(f *FirmwareClass, methodName string, args ...interface{}) (string, error) {
-	return call(f.Namespace, f.Id, prependName(args, methodName), f.conn())
+//keyedMethodCall makes a call which, if key is not empty, replaces any call
+//with the same key still waiting to be sent. The replaced call returns
+//without error. This suits writes where only the latest value matters.
+func keyedMethodCall(ctx context.Context, f *FirmwareClass, key string, methodName string, args []interface{}) (v *Buffer, err error) {
+	conn, ok := f.Conn.(*FirmwareConnection)
+	node, isNode := f.Conn.(*Node)
+	if isNode {
+		conn, ok = node.bus, true
+	}
+	if !ok {
+		return directCall(f.Conn, f.Namespace, f.Id, methodName, args)
+	}
+	c := &CallInfo{
+		Namespace: f.Namespace,
+		Id:        f.Id,
+		Method:    methodName,
+		Args:      args,
+		Priority:  f.Priority,
+		Key:       key,
+		Node:      node,
+	}
+	return conn.handle(ctx, c)
+}
+
+//invoke makes a call with the connection's tracing and retries
+func (s *FirmwareConnection) invoke(ctx context.Context, c *CallInfo) (v *Buffer, err error) {
+	statInFlightCalls.Add(1)
+	defer statInFlightCalls.Add(-1)
+	start := clockOf(s).Now()
+	if s.tracer != nil {
+		var end func(error)
+		ctx, end = s.tracer.StartCall(ctx, c.Namespace, c.Method)
+		defer func() { end(err) }()
+	}
+	v, err = s.retry.do(ctx, clockOf(s), func() (*Buffer, error) {
+		return call(ctx, c, s)
+	})
+	s.observe(c.Namespace, c.Method, c.Args, start, err)
+	return v, err
 }
 
 type FirmwareClass struct {
-	Conn      *FirmwareConnection
+	Conn      Conn
 	Id        int
 	Namespace string
+	//Priority orders calls waiting for a shared connection, e.g. so an
+	//emergency stop can overtake queued bulk transfers. Defaults to PriorityNormal.
+	Priority int
 }
 
-func (f *FirmwareClass) conn() *FirmwareConnection {
-	return f.Conn
+//NewFirmwareObject constructs a new instance of the firmware class namespace,
+//passing args to its constructor, and returns a FirmwareClass bound to the
+//instance id assigned by the firmware
+func NewFirmwareObject(conn Conn, namespace string, args ...interface{}) (*FirmwareClass, error) {
+	f := &FirmwareClass{
+		Conn:      conn,
+		Id:        StaticId,
+		Namespace: namespace,
+	}
+	id, err := f.CallAndReturnInt(MethodNew, args...)
+	if err != nil {
+		return nil, err
+	}
+	f.Id = id
+	return f, nil
+}
+
+//Remove destroys the firmware instance created by NewFirmwareObject
+func (f *FirmwareClass) Remove() error {
+	return f.CallAndReturnNothing(MethodRemove)
 }
 
 func (f *FirmwareClass) call(methodName string, args ...interface{}) (string, error) {
-	return ArduinoMethodCall(f, methodName, args)
+	return methodCall(context.Background(), f, methodName, args)
+}
+
+//callBuffer calls methodName and returns the response in a pooled buffer the
+//caller must release
+func (f *FirmwareClass) callBuffer(methodName string, args ...interface{}) (*Buffer, error) {
+	return methodCallBuffer(context.Background(), f, methodName, args)
+}
+
+//CallContext calls methodName and returns the raw response. The call is
+//abandoned if ctx is done before it has been sent to the firmware.
+func (f *FirmwareClass) CallContext(ctx context.Context, methodName string, args ...interface{}) (string, error) {
+	return methodCall(ctx, f, methodName, args)
 }
 
-func (f *FirmwareClass) CallAndReturnByte(methodName string, args ...interface{}) (byte, error) {
-	s, err := ArduinoMethodCall(f, methodName, args)
+func (f *FirmwareClass) CallAndReturnByte(methodName string, args ...interface{}) (v byte, err error) {
+	defer f.recoverResponse(methodName, &err)
+	s, err := methodCall(context.Background(), f, methodName, args)
+	if err != nil {
+		return 0, err
+	}
+	if len(s) == 0 {
+		return 0, &FirmwareError{Namespace: f.Namespace, Method: methodName, Err: errors.New("empty response")}
+	}
 	if len(s) > 1 {
 		log.Println("warning: callAndReturnByte received more than 1 byte")
 	}
-	return s[0], err
+	return s[0], nil
 }
 
 func (f *FirmwareClass) CallAndReturnInt(methodName string, args ...interface{}) (int, error) {
-	s, err := ArduinoMethodCall(f, methodName, args)
+	s, err := methodCall(context.Background(), f, methodName, args)
 	if err != nil {
 		return -1, err
 	}
-	return strconv.Atoi(s)
+	return f.parseInt(methodName, s)
+}
+
+//parseInt parses the response s to methodName as an int
+func (f *FirmwareClass) parseInt(methodName string, s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, &FirmwareError{Namespace: f.Namespace, Method: methodName, Response: s, Err: err}
+	}
+	return v, nil
 }
 
 func (f *FirmwareClass) CallAndReturnFloat(methodName string, args ...interface{}) (float64, error) {
-	s, err := ArduinoMethodCall(f, methodName, args)
+	s, err := methodCall(context.Background(), f, methodName, args)
 	if err != nil {
 		return -1, err
 	}
-	return strconv.ParseFloat(s, 64)
+	return f.parseFloat(methodName, s)
+}
+
+//parseFloat parses the response s to methodName as a float64
+func (f *FirmwareClass) parseFloat(methodName string, s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return -1, &FirmwareError{Namespace: f.Namespace, Method: methodName, Response: s, Err: err}
+	}
+	return v, nil
+}
+
+//parseBool parses the response s to methodName as a bool encoded as the
+//connection's dialect specifies, or as a C integer
+func (f *FirmwareClass) parseBool(methodName string, s string) (bool, error) {
+	d := dialectOf(f.Conn)
+	switch s {
+	case d.True:
+		return true, nil
+	case d.False:
+		return false, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return false, &FirmwareError{Namespace: f.Namespace, Method: methodName, Response: s, Err: err}
+	}
+	return v != 0, nil
+}
+
+//CallAndReturnBytes calls methodName and decodes the hex encoded response
+//into a new slice owned by the caller
+func (f *FirmwareClass) CallAndReturnBytes(methodName string, args ...interface{}) ([]byte, error) {
+	b, err := f.CallAndBorrowBytes(methodName, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Release()
+	return b.Copy(), nil
+}
+
+//CallAndBorrowBytes calls methodName and decodes the hex encoded response
+//into a pooled Buffer, avoiding an allocation per call in tight polling loops.
+//The caller must Release the buffer once done with it.
+func (f *FirmwareClass) CallAndBorrowBytes(methodName string, args ...interface{}) (b *Buffer, err error) {
+	//runs after recoverResponse so a buffer isn't leaked by a panic either
+	defer func() {
+		if err != nil {
+			b.Release()
+			b = nil
+		}
+	}()
+	defer f.recoverResponse(methodName, &err)
+	b, err = methodCallBuffer(context.Background(), f, methodName, args)
+	if err != nil {
+		return
+	}
+	err = f.decodeHex(methodName, b)
+	return
+}
+
+//decodeHex decodes the hex encoded response b to methodName in place
+func (f *FirmwareClass) decodeHex(methodName string, b *Buffer) error {
+	err := b.decodeHex()
+	if err != nil {
+		//the response was partially overwritten by decoding in place
+		return &FirmwareError{Namespace: f.Namespace, Method: methodName, Err: err}
+	}
+	return nil
 }
 
 func (f *FirmwareClass) CallAndReturnNothing(methodName string, args ...interface{}) error {
-	_, err := ArduinoMethodCall(f, methodName, args)
+	_, err := methodCall(context.Background(), f, methodName, args)
 	return err
 }
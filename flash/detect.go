@@ -0,0 +1,57 @@
+package flash
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+//usbModels maps USB vendor:product ids to board models. Boards using generic
+//USB serial adapters such as the CH340 can't be told apart and must be given
+//explicitly.
+var usbModels = map[string]string{
+	"2341:0043": "uno",
+	"2341:0001": "uno",
+	"2a03:0043": "uno",
+	"2341:0010": "mega2560",
+	"2341:0042": "mega2560",
+	"2a03:0042": "mega2560",
+	"2341:8036": "leonardo",
+	"2341:0036": "leonardo",
+	"2341:8037": "micro",
+	"2341:0037": "micro",
+}
+
+//ttyClass is the sysfs directory holding the serial ports
+var ttyClass = "/sys/class/tty"
+
+//Detect returns the board model connected to port from its USB ids. It is
+//only supported on linux.
+func Detect(port string) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("flash: board detection is not supported on %s: give the board model", runtime.GOOS)
+	}
+	//the tty's device links to the USB interface, whose parent holds the
+	//ids. The link is resolved first as a lexical .. would leave it.
+	iface, err := filepath.EvalSymlinks(filepath.Join(ttyClass, filepath.Base(port), "device"))
+	if err != nil {
+		return "", fmt.Errorf("flash: %s is not a USB device: give the board model", port)
+	}
+	dev := filepath.Dir(iface)
+	vid, err := ioutil.ReadFile(filepath.Join(dev, "idVendor"))
+	if err != nil {
+		return "", fmt.Errorf("flash: %s is not a USB device: give the board model", port)
+	}
+	pid, err := ioutil.ReadFile(filepath.Join(dev, "idProduct"))
+	if err != nil {
+		return "", fmt.Errorf("flash: %s is not a USB device: give the board model", port)
+	}
+	id := strings.TrimSpace(string(vid)) + ":" + strings.TrimSpace(string(pid))
+	model, ok := usbModels[id]
+	if !ok {
+		return "", fmt.Errorf("flash: unrecognized USB device %s on %s: give the board model", id, port)
+	}
+	return model, nil
+}
@@ -0,0 +1,147 @@
+//Package flash uploads firmware to a board with avrdude or arduino-cli, so a
+//blank board can be brought up to run the nango firmware from Go, and updates
+//boards already running it over their existing connection.
+package flash
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Board describes how to upload firmware to a board model
+type Board struct {
+	//FQBN is the fully qualified board name used by arduino-cli
+	FQBN string
+	//MCU, Programmer and Baud are the avrdude part, programmer type and
+	//bootloader baud rate
+	MCU        string
+	Programmer string
+	Baud       int
+	//Touch1200 is set for boards with native USB, which enter their
+	//bootloader when the port is opened at 1200 baud and closed. Only
+	//arduino-cli is able to follow such boards to their bootloader port.
+	Touch1200 bool
+}
+
+//Boards holds the upload settings of the supported board models
+var Boards = map[string]Board{
+	"uno":      {FQBN: "arduino:avr:uno", MCU: "atmega328p", Programmer: "arduino", Baud: 115200},
+	"nano":     {FQBN: "arduino:avr:nano:cpu=atmega328", MCU: "atmega328p", Programmer: "arduino", Baud: 115200},
+	"nano-old": {FQBN: "arduino:avr:nano:cpu=atmega328old", MCU: "atmega328p", Programmer: "arduino", Baud: 57600},
+	"mega2560": {FQBN: "arduino:avr:mega:cpu=atmega2560", MCU: "atmega2560", Programmer: "wiring", Baud: 115200},
+	"leonardo": {FQBN: "arduino:avr:leonardo", MCU: "atmega32u4", Programmer: "avr109", Baud: 57600, Touch1200: true},
+	"micro":    {FQBN: "arduino:avr:micro", MCU: "atmega32u4", Programmer: "avr109", Baud: 57600, Touch1200: true},
+}
+
+const (
+	ToolAvrdude    = "avrdude"
+	ToolArduinoCli = "arduino-cli"
+)
+
+//Options describes an upload
+type Options struct {
+	Port string
+	//Model is a key of Boards. If empty it is detected from the port's USB ids.
+	Model string
+	//Hex is the path of the firmware in Intel hex format
+	Hex string
+	//Tool is ToolAvrdude or ToolArduinoCli. If empty, whichever is installed
+	//is used, preferring arduino-cli.
+	Tool string
+	//Output receives the output of the upload tool, if not nil
+	Output io.Writer
+}
+
+//Flash uploads the firmware described by opts
+func Flash(opts Options) error {
+	if opts.Hex == "" {
+		return fmt.Errorf("flash: no firmware given")
+	}
+	if _, err := os.Stat(opts.Hex); err != nil {
+		return fmt.Errorf("flash: %w", err)
+	}
+	model := opts.Model
+	if model == "" {
+		var err error
+		model, err = Detect(opts.Port)
+		if err != nil {
+			return err
+		}
+	}
+	board, ok := Boards[model]
+	if !ok {
+		return fmt.Errorf("flash: unknown board model %q", model)
+	}
+	tool := opts.Tool
+	if tool == "" {
+		tool = findTool()
+		if tool == "" {
+			return fmt.Errorf("flash: neither %s nor %s found in PATH", ToolArduinoCli, ToolAvrdude)
+		}
+	}
+	cmd, err := command(tool, board, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Output != nil {
+		cmd.Stdout = opts.Output
+		cmd.Stderr = opts.Output
+	}
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("flash: %s: %w", tool, err)
+	}
+	return nil
+}
+
+//Reflash closes conn, uploads the firmware described by opts and reopens conn,
+//waiting wait for the board to boot
+func Reflash(conn *nango.FirmwareConnection, opts Options, wait time.Duration) error {
+	err := conn.Close()
+	if err != nil {
+		return err
+	}
+	err = Flash(opts)
+	if err != nil {
+		return err
+	}
+	time.Sleep(wait)
+	return conn.Open()
+}
+
+func findTool() string {
+	for _, tool := range []string{ToolArduinoCli, ToolAvrdude} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool
+		}
+	}
+	return ""
+}
+
+//command returns the upload command for tool
+func command(tool string, board Board, opts Options) (*exec.Cmd, error) {
+	switch tool {
+	case ToolArduinoCli:
+		return exec.Command(ToolArduinoCli, "upload",
+			"-p", opts.Port,
+			"--fqbn", board.FQBN,
+			"--input-file", opts.Hex), nil
+	case ToolAvrdude:
+		if board.Touch1200 {
+			return nil, fmt.Errorf("flash: boards with native USB must be flashed with %s", ToolArduinoCli)
+		}
+		return exec.Command(ToolAvrdude,
+			"-p", board.MCU,
+			"-c", board.Programmer,
+			"-P", opts.Port,
+			"-b", fmt.Sprint(board.Baud),
+			"-D",
+			"-U", "flash:w:"+opts.Hex+":i"), nil
+	}
+	return nil, fmt.Errorf("flash: unknown tool %q", tool)
+}
@@ -0,0 +1,80 @@
+package flash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	opts := Options{Port: "/dev/ttyACM0", Hex: "nango.hex"}
+	for _, c := range []struct {
+		tool, model, args string
+	}{
+		{ToolArduinoCli, "uno", "arduino-cli upload -p /dev/ttyACM0 --fqbn arduino:avr:uno --input-file nango.hex"},
+		{ToolArduinoCli, "leonardo", "arduino-cli upload -p /dev/ttyACM0 --fqbn arduino:avr:leonardo --input-file nango.hex"},
+		{ToolAvrdude, "nano-old", "avrdude -p atmega328p -c arduino -P /dev/ttyACM0 -b 57600 -D -U flash:w:nango.hex:i"},
+		{ToolAvrdude, "mega2560", "avrdude -p atmega2560 -c wiring -P /dev/ttyACM0 -b 115200 -D -U flash:w:nango.hex:i"},
+	} {
+		cmd, err := command(c.tool, Boards[c.model], opts)
+		if err != nil {
+			t.Fatalf("%s %s: %s", c.tool, c.model, err)
+		}
+		if args := strings.Join(cmd.Args, " "); args != c.args {
+			t.Errorf("%s %s: expected %q, got %q", c.tool, c.model, c.args, args)
+		}
+	}
+	if _, err := command(ToolAvrdude, Boards["micro"], opts); err == nil {
+		t.Error("expected an error flashing a native USB board with avrdude")
+	}
+	if _, err := command("esptool", Boards["uno"], opts); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("board detection is only supported on linux")
+	}
+	dir := tempDir(t)
+	defer func(class string) { ttyClass = class }(ttyClass)
+	ttyClass = filepath.Join(dir, "class", "tty")
+	//lay out the sysfs links of a tty whose USB device has the given ids
+	port := func(name, vid, pid string) {
+		dev := filepath.Join(dir, "devices", name)
+		iface := filepath.Join(dev, name+":1.0")
+		tty := filepath.Join(ttyClass, name)
+		for _, d := range []string{iface, tty} {
+			if err := os.MkdirAll(d, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Symlink(iface, filepath.Join(tty, "device")); err != nil {
+			t.Fatal(err)
+		}
+		for f, id := range map[string]string{"idVendor": vid, "idProduct": pid} {
+			if err := ioutil.WriteFile(filepath.Join(dev, f), []byte(id+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	port("ttyACM0", "2341", "0043")
+	port("ttyACM1", "2a03", "0042")
+	port("ttyUSB0", "1a86", "7523")
+
+	for p, model := range map[string]string{"/dev/ttyACM0": "uno", "/dev/ttyACM1": "mega2560"} {
+		m, err := Detect(p)
+		if err != nil || m != model {
+			t.Errorf("%s: expected %s, got %q, %v", p, model, m, err)
+		}
+	}
+	if _, err := Detect("/dev/ttyUSB0"); err == nil || !strings.Contains(err.Error(), "1a86:7523") {
+		t.Errorf("expected an unrecognized device error, got %v", err)
+	}
+	if _, err := Detect("/dev/ttyS0"); err == nil {
+		t.Error("expected an error for a port which isn't a USB device")
+	}
+}
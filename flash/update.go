@@ -0,0 +1,174 @@
+package flash
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//UpdateOptions describes a firmware update over a board's existing link
+type UpdateOptions struct {
+	//Options describes an upload through the bootloader, used unless Image
+	//is set
+	Options
+	//Image, if set, is the path of a raw firmware binary for a board which
+	//updates itself, such as an ESP32 running the nango OTA class. It is
+	//pushed through the connection and verified by the board before it
+	//reboots into it.
+	Image string
+	//ChunkSize is the number of bytes of Image sent per call
+	ChunkSize int
+	//Progress, if set, is called as the image is pushed with the number of
+	//bytes sent so far
+	Progress func(sent int, total int)
+	//Boot is how long the board takes to boot the new firmware
+	Boot time.Duration
+	//Reconnect is how long to keep trying to reconnect after Boot before
+	//giving up
+	Reconnect time.Duration
+}
+
+//Update installs new firmware on the board behind conn and reconnects to it
+//once it responds. Without an Image the connection is closed and the hex
+//file handed to the board's bootloader with avrdude or arduino-cli, which
+//verify the flash after writing it; the file is checked before the board is
+//touched. The connection is reopened even if the upload fails.
+func Update(conn *nango.FirmwareConnection, opts UpdateOptions) error {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 128
+	}
+	if opts.Boot <= 0 {
+		opts.Boot = 2 * time.Second
+	}
+	if opts.Reconnect <= 0 {
+		opts.Reconnect = 30 * time.Second
+	}
+	if opts.Image != "" {
+		if err := pushImage(conn, opts); err != nil {
+			return err
+		}
+		if err := conn.Close(); err != nil {
+			return err
+		}
+	} else {
+		if err := verifyHex(opts.Hex); err != nil {
+			return err
+		}
+		if err := conn.Close(); err != nil {
+			return err
+		}
+		if err := Flash(opts.Options); err != nil {
+			//the board is most likely still running the previous firmware,
+			//so don't leave the caller with a closed connection
+			time.Sleep(opts.Boot)
+			if errReconnect := reconnect(conn, opts.Reconnect); errReconnect != nil {
+				return fmt.Errorf("flash: update failed: %w (%s)", err, errReconnect)
+			}
+			return fmt.Errorf("flash: update failed and the connection was reopened: %w", err)
+		}
+	}
+	time.Sleep(opts.Boot)
+	return reconnect(conn, opts.Reconnect)
+}
+
+//pushImage writes Image to the board's OTA partition
+func pushImage(conn *nango.FirmwareConnection, opts UpdateOptions) error {
+	image, err := ioutil.ReadFile(opts.Image)
+	if err != nil {
+		return fmt.Errorf("flash: %w", err)
+	}
+	sum := md5.Sum(image)
+	ota := &nango.FirmwareClass{Conn: conn, Id: nango.StaticId, Namespace: nango.NamespaceOTA}
+	if err := ota.CallAndReturnNothing(nango.MethodOTABegin, len(image), hex.EncodeToString(sum[:])); err != nil {
+		return fmt.Errorf("flash: starting update: %w", err)
+	}
+	for sent := 0; sent < len(image); {
+		chunk := image[sent:]
+		if len(chunk) > opts.ChunkSize {
+			chunk = chunk[:opts.ChunkSize]
+		}
+		n, err := ota.CallAndReturnInt(nango.MethodOTAWrite, chunk)
+		if err == nil && n != len(chunk) {
+			err = fmt.Errorf("board wrote %d of %d bytes", n, len(chunk))
+		}
+		if err != nil {
+			//leave the running firmware in place
+			ota.CallAndReturnNothing(nango.MethodOTAAbort)
+			return fmt.Errorf("flash: writing image at offset %d: %w", sent, err)
+		}
+		sent += n
+		if opts.Progress != nil {
+			opts.Progress(sent, len(image))
+		}
+	}
+	//the board checks the image against its md5 before switching to it
+	if err := ota.CallAndReturnNothing(nango.MethodOTAEnd); err != nil {
+		return fmt.Errorf("flash: verifying image: %w", err)
+	}
+	return nil
+}
+
+//reconnect reopens conn until the firmware responds or timeout elapses
+func reconnect(conn *nango.FirmwareConnection, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := conn.Open()
+		if err == nil {
+			_, err = nango.NewArduinoApi(conn).Millis()
+			if err == nil {
+				return nil
+			}
+			conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("flash: board did not respond after the update: %w", err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+//verifyHex checks the record checksums of an Intel hex file and that it is
+//terminated by an end of file record
+func verifyHex(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("flash: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if !strings.HasPrefix(text, ":") {
+			return fmt.Errorf("flash: %s:%d: missing record start", path, line)
+		}
+		rec, err := hex.DecodeString(text[1:])
+		if err != nil || len(rec) < 5 || len(rec) != int(rec[0])+5 {
+			return fmt.Errorf("flash: %s:%d: malformed record", path, line)
+		}
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		if sum != 0 {
+			return fmt.Errorf("flash: %s:%d: checksum mismatch", path, line)
+		}
+		if bytes.Equal(rec, []byte{0, 0, 0, 1, 0xff}) {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("flash: %w", err)
+	}
+	return fmt.Errorf("flash: %s: missing end of file record, the image may be truncated", path)
+}
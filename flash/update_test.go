@@ -0,0 +1,121 @@
+package flash
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+const goodHex = ":100000000C9434000C9446000C9446000C9446006A\n:00000001FF\n"
+
+func TestVerifyHex(t *testing.T) {
+	dir := tempDir(t)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	if err := verifyHex(write("good.hex", goodHex)); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyHex(write("corrupt.hex", ":100000000C9434000C9446000C9446000C9446006B\n:00000001FF\n")); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+	if err := verifyHex(write("truncated.hex", goodHex[:44])); err == nil {
+		t.Fatal("expected missing end of file record")
+	}
+}
+
+func TestUpdateImage(t *testing.T) {
+	lb := nango.NewLoopback()
+	var written []byte
+	var size int
+	lb.Handle(nango.NamespaceOTA, nango.MethodOTABegin, func(c nango.LoopbackCall) (string, bool) {
+		size, _ = strconv.Atoi(c.Args[0])
+		return "0", true
+	})
+	lb.Handle(nango.NamespaceOTA, nango.MethodOTAWrite, func(c nango.LoopbackCall) (string, bool) {
+		b, _ := hex.DecodeString(c.Args[0])
+		written = append(written, b...)
+		return strconv.Itoa(len(b)), true
+	})
+	lb.Respond(nango.NamespaceOTA, nango.MethodOTAEnd, "0")
+	lb.Respond(nango.NamespaceArduino, nango.MethodMillis, "42")
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	image := make([]byte, 300)
+	for i := range image {
+		image[i] = byte(i)
+	}
+	path := filepath.Join(tempDir(t), "firmware.bin")
+	if err := ioutil.WriteFile(path, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var progress []int
+	err := Update(conn, UpdateOptions{
+		Image:     path,
+		Progress:  func(sent, total int) { progress = append(progress, sent) },
+		Boot:      time.Millisecond,
+		Reconnect: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(image) || string(written) != string(image) {
+		t.Fatalf("expected the image written, got %d of %d bytes", len(written), size)
+	}
+	if len(progress) != 3 || progress[2] != len(image) {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+	if _, err := nango.NewArduinoApi(conn).Millis(); err != nil {
+		t.Fatalf("expected the connection reopened, got %v", err)
+	}
+}
+
+func TestUpdateFlashFailure(t *testing.T) {
+	lb := nango.NewLoopback()
+	lb.Respond(nango.NamespaceArduino, nango.MethodMillis, "42")
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	path := filepath.Join(tempDir(t), "firmware.hex")
+	if err := ioutil.WriteFile(path, []byte(goodHex), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Update(conn, UpdateOptions{
+		Options:   Options{Port: "/dev/null", Model: "uno", Hex: path, Tool: "missing"},
+		Boot:      time.Millisecond,
+		Reconnect: time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown tool "missing"`) {
+		t.Fatalf("expected the upload error, got %v", err)
+	}
+	if _, err := nango.NewArduinoApi(conn).Millis(); err != nil {
+		t.Fatalf("expected the connection reopened, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+//go:build go1.18
+// +build go1.18
+
+package nango
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+//readerTransport replays a fixed byte stream, then reports no data
+type readerTransport struct {
+	r io.Reader
+}
+
+func (t *readerTransport) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	if err == io.EOF {
+		time.Sleep(time.Millisecond)
+	}
+	return n, err
+}
+func (t *readerTransport) Write(b []byte) (int, error) { return len(b), nil }
+func (t *readerTransport) Flush() error                { return nil }
+func (t *readerTransport) Close() error                { return nil }
+
+func FuzzReadLine(f *testing.F) {
+	f.Add([]byte("1\r\n"))
+	f.Add([]byte("\r\n\r\n"))
+	f.Add([]byte("partial"))
+	f.Add([]byte(strings.Repeat("a", 5000) + "\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dial := func() (Transport, error) {
+			return &readerTransport{r: bytes.NewReader(data)}, nil
+		}
+		conn := NewFirmwareConnection(nil, WithTransport(dial), WithReadTimeout(5*time.Millisecond))
+		if err := conn.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		for {
+			line, err := conn.ReadLine()
+			if err != nil {
+				break
+			}
+			if bytes.ContainsAny(line, "\n") {
+				t.Fatalf("line %q contains a line terminator", line)
+			}
+		}
+	})
+}
+
+//FuzzResponse feeds arbitrary responses to the calls which parse them
+func FuzzResponse(f *testing.F) {
+	for _, s := range []string{"", "1", "-1", "2.5", "deadbeef", "abc", "1,2", "1,0,0,ff", "1500,20;1000,2000", "0,0100"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, response string) {
+		if strings.ContainsAny(response, "\r\n") {
+			//would be read as several responses
+			return
+		}
+		lb := NewLoopback()
+		conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithReadTimeout(50*time.Millisecond))
+		if err := conn.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		lb.Respond(NamespaceWire, "endTransmission", response)
+		for _, ns := range []string{"T", "Counter", "CAN", "RC", "Sampler", "EEPROM"} {
+			for _, m := range []string{"get", "readAndReset", "receive", "readAll", "fetch", "readBytes"} {
+				lb.Respond(ns, m, response)
+			}
+		}
+		//the errors are printed too, since formatting an error built from
+		//the response must not panic either
+		check := func(err error) {
+			if err != nil {
+				_ = err.Error()
+			}
+		}
+		fc := &FirmwareClass{Conn: conn, Namespace: "T"}
+		_, err := fc.CallAndReturnByte("get")
+		check(err)
+		_, err = fc.CallAndReturnInt("get")
+		check(err)
+		_, err = fc.CallAndReturnFloat("get")
+		check(err)
+		_, err = fc.CallAndReturnBytes("get")
+		check(err)
+		p := conn.Pipeline()
+		r := p.Call(fc, "get")
+		check(p.Exec())
+		_, err = r.Int()
+		check(err)
+		_, err = r.Float()
+		check(err)
+		_, err = NewCounter(conn).ReadAndReset("D2")
+		check(err)
+		_, _, err = NewCan(conn).Receive()
+		check(err)
+		_, err = NewRcReceiver(conn).Channels()
+		check(err)
+		_, err = NewAnalogSampler(conn).Fetch(16)
+		check(err)
+		check(NewEEPROM(conn).ReadBytes(0, make([]byte, 40), nil))
+		check(NewI2cMaster(NewWire(conn)).Send(0x20, []byte{1}))
+		check(conn.Transaction().I2CWrite(0x20, []byte{1}).Commit())
+	})
+}
@@ -0,0 +1,277 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//knot is a knot in metres per second
+const knot = 1852.0 / 3600
+
+//GPSFix is a position reported by a GPS receiver
+type GPSFix struct {
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+	//Speed is the ground speed in metres per second
+	Speed float64
+	//Course is the track over ground in degrees from true north
+	Course float64
+	//Altitude is the height above mean sea level in metres
+	Altitude float64
+	//Quality is the GGA fix quality: 0 invalid, 1 GPS, 2 DGPS and so on
+	Quality    int
+	Satellites int
+	HDOP       float64
+}
+
+//GPSFilter discards fixes too poor to use. Zero fields don't filter.
+type GPSFilter struct {
+	MinQuality    int
+	MinSatellites int
+	MaxHDOP       float64
+}
+
+func (f GPSFilter) accept(fix GPSFix) bool {
+	return fix.Quality >= f.MinQuality &&
+		fix.Satellites >= f.MinSatellites &&
+		(f.MaxHDOP == 0 || fix.HDOP <= f.MaxHDOP)
+}
+
+//GPS reads NMEA sentences from a receiver attached to a UART on the board,
+//such as a SoftwareSerial instance, and delivers a fix on C for every valid
+//RMC sentence, combined with the GGA sentence of the same epoch. Fixes are
+//dropped if the consumer falls behind.
+type GPS struct {
+	C <-chan GPSFix
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+	err    error
+}
+
+//NewGPS starts reading NMEA from serial, which must already have been begun
+//at the receiver's baud rate, delivering the fixes accepted by filter
+func NewGPS(serial AttachedSerial, filter GPSFilter) *GPS {
+	c := make(chan GPSFix, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &GPS{C: c, cancel: cancel, done: make(chan struct{})}
+	go g.run(ctx, newLineReader(serial), c, filter)
+	return g
+}
+
+func (g *GPS) run(ctx context.Context, r *lineReader, c chan<- GPSFix, filter GPSFilter) {
+	defer close(g.done)
+	defer close(c)
+	var gga GPSFix
+	for ctx.Err() == nil {
+		line, err := r.readLine()
+		if errors.Is(err, ErrTimeout) {
+			//the receiver is quiet, e.g. between epochs
+			continue
+		}
+		if err != nil {
+			g.mu.Lock()
+			g.err = err
+			g.mu.Unlock()
+			return
+		}
+		fields, err := parseNMEA(line)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(fields[0], "GGA"):
+			var ok bool
+			gga, ok, err = parseGGA(fields)
+			if err != nil || !ok {
+				gga = GPSFix{}
+			}
+		case strings.HasSuffix(fields[0], "RMC"):
+			fix, ok, err := parseRMC(fields)
+			if err != nil || !ok {
+				continue
+			}
+			if gga.Quality > 0 && timeOfDay(gga.Time) == timeOfDay(fix.Time) {
+				fix.Altitude = gga.Altitude
+				fix.Quality = gga.Quality
+				fix.Satellites = gga.Satellites
+				fix.HDOP = gga.HDOP
+			}
+			if !filter.accept(fix) {
+				continue
+			}
+			select {
+			case c <- fix:
+			default:
+			}
+		}
+	}
+}
+
+//Err returns the error which stopped the GPS, if any
+func (g *GPS) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+//Close stops reading, waiting for the read in progress to return
+func (g *GPS) Close() {
+	g.cancel()
+	<-g.done
+}
+
+//parseNMEA verifies the checksum of an NMEA sentence and returns its fields,
+//starting with the talker and sentence type, e.g. GPRMC
+func parseNMEA(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("nmea: missing $ in %q", line)
+	}
+	star := strings.LastIndexByte(line, '*')
+	if star < 0 || star+3 != len(line) {
+		return nil, fmt.Errorf("nmea: missing checksum in %q", line)
+	}
+	want, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("nmea: malformed checksum in %q", line)
+	}
+	var sum byte
+	for i := 1; i < star; i++ {
+		sum ^= line[i]
+	}
+	if sum != byte(want) {
+		return nil, fmt.Errorf("nmea: checksum mismatch in %q", line)
+	}
+	return strings.Split(line[1:star], ","), nil
+}
+
+//parseCoordinate parses a [d]ddmm.mmmm coordinate and its hemisphere
+func parseCoordinate(v string, hemisphere string) (float64, error) {
+	dot := strings.IndexByte(v, '.')
+	if dot < 3 {
+		return 0, fmt.Errorf("nmea: malformed coordinate %q", v)
+	}
+	deg, err := strconv.Atoi(v[:dot-2])
+	if err != nil {
+		return 0, err
+	}
+	min, err := strconv.ParseFloat(v[dot-2:], 64)
+	if err != nil {
+		return 0, err
+	}
+	c := float64(deg) + min/60
+	if hemisphere == "S" || hemisphere == "W" {
+		c = -c
+	}
+	return c, nil
+}
+
+//parseTime parses an hhmmss.ss time of day, and a ddmmyy date if not empty
+func parseTime(tod string, date string) (time.Time, error) {
+	if len(tod) < 6 {
+		return time.Time{}, fmt.Errorf("nmea: malformed time %q", tod)
+	}
+	t, err := time.Parse("150405", tod[:6])
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(tod) > 7 && tod[6] == '.' {
+		frac, err := strconv.ParseFloat("0"+tod[6:], 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = t.Add(time.Duration(frac * float64(time.Second)))
+	}
+	if date == "" {
+		return t, nil
+	}
+	d, err := time.Parse("020106", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return d.Add(t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))), nil
+}
+
+//parseRMC parses the fields of an RMC sentence. ok is false if the receiver
+//has no fix.
+func parseRMC(f []string) (fix GPSFix, ok bool, err error) {
+	//type,time,status,lat,N/S,lon,E/W,speed,course,date,...
+	if len(f) < 10 {
+		return fix, false, fmt.Errorf("nmea: short RMC sentence")
+	}
+	if f[2] != "A" {
+		return fix, false, nil
+	}
+	if fix.Time, err = parseTime(f[1], f[9]); err != nil {
+		return
+	}
+	if fix.Latitude, err = parseCoordinate(f[3], f[4]); err != nil {
+		return
+	}
+	if fix.Longitude, err = parseCoordinate(f[5], f[6]); err != nil {
+		return
+	}
+	if f[7] != "" {
+		knots, err := strconv.ParseFloat(f[7], 64)
+		if err != nil {
+			return fix, false, err
+		}
+		fix.Speed = knots * knot
+	}
+	if f[8] != "" {
+		if fix.Course, err = strconv.ParseFloat(f[8], 64); err != nil {
+			return
+		}
+	}
+	return fix, true, nil
+}
+
+//timeOfDay returns the time elapsed since the start of t's day, which is
+//all GGA sentences carry
+func timeOfDay(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+//parseGGA parses the fields of a GGA sentence. ok is false if the receiver
+//has no fix.
+func parseGGA(f []string) (fix GPSFix, ok bool, err error) {
+	//type,time,lat,N/S,lon,E/W,quality,satellites,hdop,altitude,M,...
+	if len(f) < 10 {
+		return fix, false, fmt.Errorf("nmea: short GGA sentence")
+	}
+	if f[6] == "0" {
+		return fix, false, nil
+	}
+	if fix.Time, err = parseTime(f[1], ""); err != nil {
+		return
+	}
+	if fix.Quality, err = strconv.Atoi(f[6]); err != nil {
+		return
+	}
+	if fix.Latitude, err = parseCoordinate(f[2], f[3]); err != nil {
+		return
+	}
+	if fix.Longitude, err = parseCoordinate(f[4], f[5]); err != nil {
+		return
+	}
+	if fix.Satellites, err = strconv.Atoi(f[7]); err != nil {
+		return
+	}
+	if fix.HDOP, err = strconv.ParseFloat(f[8], 64); err != nil {
+		return
+	}
+	if fix.Altitude, err = strconv.ParseFloat(f[9], 64); err != nil {
+		return
+	}
+	return fix, true, nil
+}
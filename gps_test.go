@@ -0,0 +1,118 @@
+package nango
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseNMEA(t *testing.T) {
+	rmc, err := parseNMEA("$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fix, ok, err := parseRMC(rmc)
+	if err != nil || !ok {
+		t.Fatalf("expected fix, got %v %v", ok, err)
+	}
+	if want := time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC); !fix.Time.Equal(want) {
+		t.Errorf("expected time %s, got %s", want, fix.Time)
+	}
+	if math.Abs(fix.Latitude-48.1173) > 1e-4 || math.Abs(fix.Longitude-11.516667) > 1e-4 {
+		t.Errorf("unexpected position %v,%v", fix.Latitude, fix.Longitude)
+	}
+	if math.Abs(fix.Speed-22.4*knot) > 1e-9 || fix.Course != 84.4 {
+		t.Errorf("unexpected speed %v or course %v", fix.Speed, fix.Course)
+	}
+
+	gga, err := parseNMEA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fix, ok, err = parseGGA(gga)
+	if err != nil || !ok || fix.Quality != 1 || fix.Satellites != 8 || fix.HDOP != 0.9 || fix.Altitude != 545.4 {
+		t.Fatalf("unexpected GGA fix %+v (%v)", fix, err)
+	}
+	if !(GPSFilter{MinSatellites: 4, MaxHDOP: 2}).accept(fix) || (GPSFilter{MinSatellites: 9}).accept(fix) {
+		t.Error("filter misapplied")
+	}
+
+	if _, err := parseNMEA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48"); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+
+	gga, err = parseNMEA(nmea("GPGGA,123519,,,,,0,00,99.9,,M,,M,,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := parseGGA(gga); ok || err != nil {
+		t.Fatalf("expected no fix without error, got %v %v", ok, err)
+	}
+}
+
+//nmea returns the sentence with body and its checksum
+func nmea(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X\r\n", body, sum)
+}
+
+func TestGPS(t *testing.T) {
+	m := &fakeModem{}
+	g := NewGPS(m, GPSFilter{})
+	defer g.Close()
+	//send writes sentences to the receiver's UART, waiting for the GPS to
+	//time out reading first
+	send := func(s string) {
+		m.mu.Lock()
+		timeouts := m.timeouts
+		m.mu.Unlock()
+		for {
+			m.mu.Lock()
+			if m.timeouts > timeouts {
+				m.response.WriteString(s)
+				m.mu.Unlock()
+				return
+			}
+			m.mu.Unlock()
+			time.Sleep(time.Millisecond)
+		}
+	}
+	next := func() GPSFix {
+		select {
+		case fix := <-g.C:
+			return fix
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a fix")
+		}
+		return GPSFix{}
+	}
+
+	//the RMC sentence of the next epoch is not combined with the GGA one
+	send(nmea("GPGGA,123519.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,") +
+		nmea("GPRMC,123519.50,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W"))
+	if fix := next(); fix.Quality != 0 || fix.Altitude != 0 || fix.Time.Nanosecond() != 5e8 {
+		t.Fatalf("expected a fix without a GGA sentence from the same epoch, got %+v", fix)
+	}
+
+	send(nmea("GPGGA,123520.00,4807.038,N,01131.000,E,0,00,99.9,,M,,M,,") +
+		nmea("GPRMC,123520.00,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W"))
+	if fix := next(); fix.Quality != 0 || fix.Satellites != 0 {
+		t.Fatalf("expected no fix quality from a GGA sentence without a fix, got %+v", fix)
+	}
+
+	//a sentence cut by a read timeout is completed once the rest arrives
+	rmc := nmea("GPRMC,123521.00,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W")
+	send(nmea("GPGGA,123521.00,4807.038,N,01131.000,E,2,09,0.8,546.0,M,46.9,M,,") + rmc[:20])
+	send(rmc[20:])
+	if fix := next(); fix.Quality != 2 || fix.Satellites != 9 || fix.Altitude != 546 {
+		t.Fatalf("expected the fix combined with its GGA sentence, got %+v", fix)
+	}
+	g.Close()
+	if err := g.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
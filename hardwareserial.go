@@ -0,0 +1,31 @@
+package nango
+
+import (
+	"fmt"
+)
+
+//HardwareSerial gives access to one of the additional hardware UARTs found on
+//boards such as the Mega and ESP32 as an AttachedSerial
+//http://arduino.cc/en/Reference/Serial
+type HardwareSerial struct {
+	*uart
+}
+
+//NewHardwareSerial returns the hardware UART with the given index, 1 through
+//3 for Serial1 to Serial3. Serial (index 0) carries the nango protocol itself
+//and cannot be used.
+func NewHardwareSerial(conn Conn, index int) (*HardwareSerial, error) {
+	if index < 1 || index > 3 {
+		return nil, fmt.Errorf("invalid hardware serial index %d: must be between 1 and 3", index)
+	}
+	return &HardwareSerial{newUart(&FirmwareClass{
+		Conn:      conn,
+		Id:        StaticId,
+		Namespace: fmt.Sprintf("%s%d", NamespaceHardwareSerial, index),
+	})}, nil
+}
+
+//End disables the UART, releasing its pins for general use
+func (s *HardwareSerial) End() error {
+	return s.CallAndReturnNothing(MethodStreamEnd)
+}
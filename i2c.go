@@ -1,6 +1,15 @@
 package nango
 
-import "log"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//I2CBufferSize is the size of the arduino Wire library's receive buffer and so
+//the maximum number of bytes a single requestFrom transaction can return
+const I2CBufferSize = 32
 
 type I2CAddress int
 
@@ -13,13 +22,19 @@ func (addr *I2CAddress) Value() interface{} {
 
 type i2cCommunicationError int
 
+var i2cCommunicationErrors = [...]string{
+	"data too long to fit in transmit buffer",
+	"received NACK on transmit of address",
+	"received NACK on transmit of data",
+	"other error",
+}
+
 func (d i2cCommunicationError) String() string {
-	return [...]string{
-		"data too long to fit in transmit buffer",
-		"received NACK on transmit of address",
-		"received NACK on transmit of data",
-		"other error",
-	}[d-1]
+	//newer Wire libraries add codes, e.g. 5 for a bus timeout on AVR
+	if d < 1 || int(d) > len(i2cCommunicationErrors) {
+		return fmt.Sprintf("unknown error %d", int(d))
+	}
+	return i2cCommunicationErrors[d-1]
 }
 
 func (d i2cCommunicationError) Error() string {
@@ -47,30 +62,150 @@ func (b *i2cbase) begin() error {
 	return nil
 }
 
+//I2CDriver is implemented by device drivers that talk to a slave on the bus
+//through an I2CMaster
+type I2CDriver interface {
+	Address() I2CAddress
+}
+
+//I2CAddressConflictError is returned when registering a driver whose address
+//is already claimed by another driver on the same bus
+type I2CAddressConflictError struct {
+	Address  I2CAddress
+	Existing I2CDriver
+	New      I2CDriver
+}
+
+func (e *I2CAddressConflictError) Error() string {
+	return fmt.Sprintf("i2c address 0x%02x already registered to %T: cannot register %T", int(e.Address), e.Existing, e.New)
+}
+
 type I2CMaster struct {
 	*i2cbase
+	driversMu sync.Mutex
+	drivers   map[I2CAddress]I2CDriver
 }
 
 func NewI2cMaster(wire *wire) *I2CMaster {
 	return &I2CMaster{
-		newI2cBase(wire, nil),
+		i2cbase: newI2cBase(wire, nil),
+		drivers: make(map[I2CAddress]I2CDriver),
 	}
 }
 
-func (m *I2CMaster) Request(address I2CAddress, quantity int) ([]byte, error) {
-	err := m.begin()
+//Register claims the driver's address on the bus. An I2CAddressConflictError
+//is returned if another driver has already claimed the same address. The
+//drivers in this package register themselves when constructed; other drivers
+//are only checked for conflicts if their callers register them.
+func (m *I2CMaster) Register(d I2CDriver) error {
+	m.driversMu.Lock()
+	defer m.driversMu.Unlock()
+	addr := d.Address()
+	if existing, ok := m.drivers[addr]; ok {
+		return &I2CAddressConflictError{Address: addr, Existing: existing, New: d}
+	}
+	m.drivers[addr] = d
+	return nil
+}
+
+//RegisterAndProbe registers the driver and then checks that a device acknowledges
+//its address. The registration is rolled back if the device is not present.
+func (m *I2CMaster) RegisterAndProbe(d I2CDriver) error {
+	err := m.Register(d)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	n, err := m.wire.RequestFrom(address, quantity, true)
-	if n < quantity {
-		log.Println("i2cMaster: slave sent less bytes than requested")
+	ok, err := m.Probe(d.Address())
+	if err == nil && !ok {
+		err = fmt.Errorf("no i2c device acknowledged address 0x%02x for %T", int(d.Address()), d)
 	}
-	buf := make([]byte, n)
-	_, err = m.wire.Read(buf)
+	if err != nil {
+		m.Unregister(d)
+		return err
+	}
+	return nil
+}
+
+//Unregister releases the address claimed by the driver
+func (m *I2CMaster) Unregister(d I2CDriver) {
+	m.driversMu.Lock()
+	defer m.driversMu.Unlock()
+	if existing, ok := m.drivers[d.Address()]; ok && sameDriver(existing, d) {
+		delete(m.drivers, d.Address())
+	}
+}
+
+//sameDriver reports whether a and b are the same driver. Drivers of types
+//which can't be compared with == are compared by value instead of panicking.
+func sameDriver(a, b I2CDriver) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if t.Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+//Drivers returns the drivers currently registered on the bus
+func (m *I2CMaster) Drivers() []I2CDriver {
+	m.driversMu.Lock()
+	defer m.driversMu.Unlock()
+	ds := make([]I2CDriver, 0, len(m.drivers))
+	for _, d := range m.drivers {
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+//Probe reports whether a device acknowledges the given address
+func (m *I2CMaster) Probe(address I2CAddress) (bool, error) {
+	err := m.Send(address, make([]byte, 0))
+	if err != nil {
+		var i2cErr *I2CError
+		if errors.As(err, &i2cErr) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+//Request reads quantity bytes from the slave at address. Requests larger than
+//I2CBufferSize are split into multiple requestFrom transactions joined by
+//repeated starts and reassembled on the host. If the slave sends fewer bytes,
+//those received are returned with an I2CShortReadError.
+func (m *I2CMaster) Request(address I2CAddress, quantity int) ([]byte, error) {
+	if quantity < 0 {
+		return nil, fmt.Errorf("i2c: invalid quantity %d", quantity)
+	}
+	err := m.begin()
 	if err != nil {
 		return nil, err
 	}
+	buf := make([]byte, 0, quantity)
+	for remaining := quantity; remaining > 0; {
+		chunk := remaining
+		if chunk > I2CBufferSize {
+			chunk = I2CBufferSize
+		}
+		remaining -= chunk
+		//only release the bus after the final chunk
+		n, err := m.wire.RequestFrom(address, chunk, remaining == 0)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = m.wire.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+		if n < chunk {
+			return buf, &I2CShortReadError{Address: address, Requested: quantity, Received: len(buf)}
+		}
+	}
 	return buf, nil
 }
 
@@ -81,7 +216,7 @@ func (m *I2CMaster) Send(address I2CAddress, data []byte) error {
 	}
 	err = m.wire.BeginTransmission(address)
 	if err != nil {
-		return nil
+		return err
 	}
 	_, err = m.wire.Write(data)
 	if err != nil {
@@ -92,7 +227,7 @@ func (m *I2CMaster) Send(address I2CAddress, data []byte) error {
 		return err
 	}
 	if c != 0 {
-		return i2cCommunicationError(c)
+		return &I2CError{Address: address, Code: c}
 	}
 	return nil
 }
@@ -105,18 +240,14 @@ func (m *I2CMaster) Scan() ([]I2CAddress, error) {
 	addrs := make([]I2CAddress, 0)
 	for i := 1; i <= 128; i++ {
 		addr := I2CAddress(i)
-		err = m.Send(addr, make([]byte, 0))
+		ok, err := m.Probe(addr)
 		if err != nil {
-			switch err.(type) {
-			case i2cCommunicationError:
-				//no response
-				continue
-			default:
-				//firmware communication error
-				return nil, err
-			}
+			//firmware communication error
+			return nil, err
+		}
+		if ok {
+			addrs = append(addrs, addr)
 		}
-		addrs = append(addrs, addr)
 	}
 	return addrs, nil
 }
@@ -158,12 +289,12 @@ type wire struct {
 
 //NewWire returns a wire struct giving access to the arduino wire library
 //http://arduino.cc/en/reference/wire
-func NewWire(conn *FirmwareConnection) *wire {
+func NewWire(conn Conn) *wire {
 	return &wire{
 		&FirmwareClass{
 			Conn:      conn,
-			Id:        0,
-			Namespace: "Wire",
+			Id:        StaticId,
+			Namespace: NamespaceWire,
 		},
 	}
 }
@@ -172,26 +303,26 @@ func NewWire(conn *FirmwareConnection) *wire {
 //This should normally only be called once.
 //Pass nil to join the bus as a master
 func (w *wire) Begin(address *I2CAddress) error {
-	return w.CallAndReturnNothing("begin", address.Value())
+	return w.CallAndReturnNothing(MethodWireBegin, address.Value())
 }
 
 func (w *wire) RequestFrom(address I2CAddress, quantity int, stop bool) (int, error) {
-	return w.CallAndReturnInt("requestFrom", address.Value(), quantity, stop)
+	return w.CallAndReturnInt(MethodWireRequestFrom, address.Value(), quantity, stop)
 }
 
 func (w *wire) BeginTransmission(address I2CAddress) error {
-	_, err := w.call("beginTransmission", address.Value())
+	_, err := w.call(MethodWireBeginTransmission, address.Value())
 	return err
 }
 
 func (w *wire) EndTransmission(stop bool) (int, error) {
-	return w.CallAndReturnInt("endTransmission", stop)
+	return w.CallAndReturnInt(MethodWireEndTransmission, stop)
 }
 
 func (w *wire) Write(b []byte) (i int, err error) {
 	var v byte
 	for i, v = range b {
-		err = w.CallAndReturnNothing("write", v)
+		err = w.CallAndReturnNothing(MethodWireWrite, v)
 		if err != nil {
 			return
 		}
@@ -200,13 +331,13 @@ func (w *wire) Write(b []byte) (i int, err error) {
 }
 
 func (w *wire) Available() (int, error) {
-	return w.CallAndReturnInt("available")
+	return w.CallAndReturnInt(MethodWireAvailable)
 }
 
 func (w *wire) Read(b []byte) (i int, err error) {
 	var v byte
 	for i = 0; i < len(b); i++ {
-		v, err = w.CallAndReturnByte("read")
+		v, err = w.CallAndReturnByte(MethodWireRead)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,160 @@
+package nango
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestI2CErrorCodes(t *testing.T) {
+	cases := map[int]string{
+		0:              "i2c transmission to 0x20: unknown error 0",
+		I2CAddressNack: "i2c transmission to 0x20: received NACK on transmit of address",
+		5:              "i2c transmission to 0x20: unknown error 5",
+		255:            "i2c transmission to 0x20: unknown error 255",
+	}
+	for code, expected := range cases {
+		err := &I2CError{Address: 0x20, Code: code}
+		if err.Error() != expected {
+			t.Errorf("expected %q for code %d, got %q", expected, code, err.Error())
+		}
+	}
+}
+
+func TestI2CRequestInvalidQuantity(t *testing.T) {
+	lb, conn := openLoopback(t)
+	requests := 0
+	lb.Handle(NamespaceWire, MethodWireRequestFrom, func(c LoopbackCall) (string, bool) {
+		requests++
+		return "0", true
+	})
+	if _, err := NewBoard(conn).I2C().Request(0x20, -1); err == nil {
+		t.Fatal("expected an error requesting a negative quantity")
+	}
+	if requests != 0 {
+		t.Fatalf("expected no request sent, got %d", requests)
+	}
+}
+
+//bufferedDriver is a driver type which can't be compared with ==
+type bufferedDriver struct {
+	addr I2CAddress
+	buf  []byte
+}
+
+func (d bufferedDriver) Address() I2CAddress {
+	return d.addr
+}
+
+func TestI2CUnregister(t *testing.T) {
+	m := NewI2cMaster(nil)
+	d := bufferedDriver{addr: 0x20, buf: []byte{1}}
+	if err := m.Register(d); err != nil {
+		t.Fatal(err)
+	}
+	//another driver on the same address is left registered
+	m.Unregister(bufferedDriver{addr: 0x20, buf: []byte{2}})
+	if len(m.Drivers()) != 1 {
+		t.Fatal("expected a different driver not to be unregistered")
+	}
+	m.Unregister(d)
+	if len(m.Drivers()) != 0 {
+		t.Fatal("expected the driver unregistered")
+	}
+}
+
+func TestI2CProbeFailure(t *testing.T) {
+	lb, conn := openLoopback(t)
+	//the firmware never answers beginTransmission
+	lb.Handle(NamespaceWire, MethodWireBeginTransmission, func(c LoopbackCall) (string, bool) {
+		return "", false
+	})
+	m := NewBoard(conn).I2C()
+	if ok, err := m.Probe(0x20); ok || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected the timeout returned, got %v, %v", ok, err)
+	}
+	if err := m.RegisterAndProbe(bufferedDriver{addr: 0x20}); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected the timeout returned, got %v", err)
+	}
+	if len(m.Drivers()) != 0 {
+		t.Fatal("expected the registration rolled back")
+	}
+}
+
+//i2cSlave answers the Wire calls of a loopback for a slave sending
+//consecutive printable bytes from slaveByte, recording the quantity and stop flag of each request
+type i2cSlave struct {
+	requests []string
+	next     int
+	//limit, if set, is the most bytes the slave sends in all
+	limit int
+	sent  int
+}
+
+func (s *i2cSlave) attach(lb *Loopback) {
+	lb.Handle(NamespaceWire, MethodWireRequestFrom, func(c LoopbackCall) (string, bool) {
+		s.requests = append(s.requests, c.Args[1]+","+c.Args[2])
+		n, _ := strconv.Atoi(c.Args[1])
+		if s.limit > 0 && s.sent+n > s.limit {
+			n = s.limit - s.sent
+		}
+		s.sent += n
+		return strconv.Itoa(n), true
+	})
+	lb.Handle(NamespaceWire, MethodWireRead, func(c LoopbackCall) (string, bool) {
+		s.next++
+		return string([]byte{slaveByte(s.next - 1)}), true
+	})
+}
+
+//slaveByte is the ith byte sent by an i2cSlave; wire reads are answered
+//with the raw byte, which mustn't be a line ending
+func slaveByte(i int) byte {
+	return byte('!' + i%90)
+}
+
+func TestI2CRequestChunks(t *testing.T) {
+	for _, c := range []struct {
+		quantity int
+		requests string
+	}{
+		{0, ""},
+		{32, "32,True"},
+		{33, "32,False;1,True"},
+		{70, "32,False;32,False;6,True"},
+	} {
+		lb, conn := openLoopback(t)
+		s := &i2cSlave{}
+		s.attach(lb)
+		b, err := NewBoard(conn).I2C().Request(0x20, c.quantity)
+		if err != nil {
+			t.Fatalf("%d: %s", c.quantity, err)
+		}
+		if r := strings.Join(s.requests, ";"); r != c.requests {
+			t.Errorf("%d: expected requests %q, got %q", c.quantity, c.requests, r)
+		}
+		if len(b) != c.quantity {
+			t.Fatalf("%d: expected %d bytes, got %d", c.quantity, c.quantity, len(b))
+		}
+		for i, v := range b {
+			if v != slaveByte(i) {
+				t.Fatalf("%d: expected the chunks reassembled in order, got %v", c.quantity, b)
+			}
+		}
+	}
+}
+
+func TestI2CRequestShortRead(t *testing.T) {
+	lb, conn := openLoopback(t)
+	s := &i2cSlave{limit: 40}
+	s.attach(lb)
+	b, err := NewBoard(conn).I2C().Request(0x20, 70)
+	var short *I2CShortReadError
+	if !errors.As(err, &short) || short.Requested != 70 || short.Received != 40 {
+		t.Fatalf("expected a short read of 40 of 70 bytes, got %v", err)
+	}
+	if len(b) != 40 || strings.Join(s.requests, ";") != "32,False;32,False" {
+		t.Fatalf("expected the 40 bytes received and no further request, got %d bytes after %v", len(b), s.requests)
+	}
+}
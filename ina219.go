@@ -0,0 +1,76 @@
+package nango
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+//INA219Address is the default address of an INA219, with A0 and A1 grounded
+const INA219Address I2CAddress = 0x40
+
+const (
+	ina219RegShunt = 0x01
+	ina219RegBus   = 0x02
+)
+
+//INA219 measures the bus voltage and, across a shunt resistor, the current of
+//a supply with a TI INA219 on the I2C bus. It is used in its power on
+//configuration of a 32V bus range and continuous 12 bit conversions.
+type INA219 struct {
+	master  *I2CMaster
+	address I2CAddress
+	//Shunt is the resistance of the shunt in ohms
+	Shunt float64
+}
+
+//NewINA219 registers an INA219 at address on the bus measuring across a shunt
+//of the given resistance
+func NewINA219(m *I2CMaster, address I2CAddress, shunt float64) (*INA219, error) {
+	d := &INA219{master: m, address: address, Shunt: shunt}
+	if err := m.RegisterAndProbe(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+//Address implements I2CDriver
+func (d *INA219) Address() I2CAddress {
+	return d.address
+}
+
+func (d *INA219) readRegister(reg byte) (uint16, error) {
+	if err := d.master.Send(d.address, []byte{reg}); err != nil {
+		return 0, err
+	}
+	b, err := d.master.Request(d.address, 2)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 2 {
+		return 0, fmt.Errorf("ina219: read %d of 2 bytes from register 0x%02x", len(b), reg)
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+//Voltage returns the bus voltage in volts
+func (d *INA219) Voltage() (float64, error) {
+	v, err := d.readRegister(ina219RegBus)
+	if err != nil {
+		return 0, err
+	}
+	//bits 3-15 in 4mV steps; bit 0 flags an overflowed product
+	if v&0x01 != 0 {
+		return 0, fmt.Errorf("ina219: math overflow at 0x%02x", int(d.address))
+	}
+	return float64(v>>3) * 0.004, nil
+}
+
+//Current returns the current through the shunt in amps
+func (d *INA219) Current() (float64, error) {
+	v, err := d.readRegister(ina219RegShunt)
+	if err != nil {
+		return 0, err
+	}
+	//10µV steps
+	return float64(int16(v)) * 10e-6 / d.Shunt, nil
+}
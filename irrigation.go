@@ -0,0 +1,280 @@
+package nango
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//SoilMoisture reads a capacitive or resistive soil moisture probe on an
+//analog pin as a percentage, from the raw readings of the probe in dry air
+//and in water
+func SoilMoisture(api *ArduinoApi, pin string, dry int, wet int) ReadFunc {
+	return func(ctx context.Context) (float64, error) {
+		v, err := api.AnalogRead(pin)
+		if err != nil {
+			return 0, err
+		}
+		return clamp(float64(v-dry)/float64(wet-dry)*100, 0, 100), nil
+	}
+}
+
+//WateringWindow is a period of the day in which a zone may be watered
+type WateringWindow struct {
+	//From is the start of the window as the time since local midnight
+	From     time.Duration
+	Duration time.Duration
+	//Days are the days of the week the window applies to, every day if empty
+	Days []time.Weekday
+}
+
+//contains reports whether t falls within an occurrence of the window,
+//including one which started the previous day
+func (w WateringWindow) contains(t time.Time) bool {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	for _, start := range []time.Time{midnight.Add(w.From), midnight.AddDate(0, 0, -1).Add(w.From)} {
+		if t.Before(start) || !t.Before(start.Add(w.Duration)) {
+			continue
+		}
+		if len(w.Days) == 0 {
+			return true
+		}
+		for _, day := range w.Days {
+			if day == start.Weekday() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//IrrigationZone is a valve watering an area on a schedule
+type IrrigationZone struct {
+	Name string
+	//Valve is the name of the zone's relay in the controller's bank
+	Valve    string
+	Schedule []WateringWindow
+	//Moisture, if set, reads the soil moisture of the zone in percent. The
+	//zone is only watered once it is below DryBelow, until it reaches
+	//WetAbove. If the reading fails the zone waters on its schedule alone.
+	Moisture ReadFunc
+	DryBelow float64
+	WetAbove float64
+	//MaxRuntime, if non-zero, locks the valve off with ErrMaxRuntime if it
+	//stays open for longer, e.g. because a probe has failed dry
+	MaxRuntime time.Duration
+}
+
+//ZoneState is a snapshot of an irrigation zone
+type ZoneState struct {
+	Updated  time.Time
+	Watering bool
+	//Since is when the valve was last opened, zero if it is closed
+	Since       time.Time
+	Moisture    float64
+	MoistureErr error
+	//Override is the manual state of the valve, if overridden, until
+	//OverrideUntil, or indefinitely if that is zero
+	Override      *bool
+	OverrideUntil time.Time
+	//Fault is set once the zone has been locked off, until ClearFault is
+	//called
+	Fault error
+}
+
+//Irrigation waters zones through the valves of a relay bank, running a
+//shared pump while any valve is open
+type Irrigation struct {
+	Bank *RelayBank
+	//Pump is the name of the pump's relay in Bank, empty if there is none
+	Pump  string
+	Zones []IrrigationZone
+	//Interval is how often the zones are updated
+	Interval time.Duration
+
+	mu     sync.Mutex
+	states map[string]*ZoneState
+	sched  *Scheduler
+	name   string
+}
+
+func (ir *Irrigation) state(name string) *ZoneState {
+	if ir.states == nil {
+		ir.states = make(map[string]*ZoneState)
+	}
+	s, ok := ir.states[name]
+	if !ok {
+		s = &ZoneState{}
+		ir.states[name] = s
+	}
+	return s
+}
+
+func (ir *Irrigation) zone(name string) (*IrrigationZone, error) {
+	for i := range ir.Zones {
+		if ir.Zones[i].Name == name {
+			return &ir.Zones[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown irrigation zone %s", name)
+}
+
+//Step reads the zones' moisture and opens and closes their valves at now.
+//Valves are closed before others are opened, and the pump is only run while
+//a valve is open.
+func (ir *Irrigation) Step(ctx context.Context, now time.Time) error {
+	moisture := make([]float64, len(ir.Zones))
+	moistureErr := make([]error, len(ir.Zones))
+	for i, z := range ir.Zones {
+		if z.Moisture != nil {
+			moisture[i], moistureErr[i] = z.Moisture(ctx)
+		}
+	}
+
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	want := make([]bool, len(ir.Zones))
+	for i := range ir.Zones {
+		z := &ir.Zones[i]
+		s := ir.state(z.Name)
+		s.Updated = now
+		s.Moisture, s.MoistureErr = moisture[i], moistureErr[i]
+		if s.Override != nil && !s.OverrideUntil.IsZero() && !now.Before(s.OverrideUntil) {
+			s.Override, s.OverrideUntil = nil, time.Time{}
+		}
+		switch {
+		case s.Fault != nil:
+		case s.Override != nil:
+			want[i] = *s.Override
+		default:
+			want[i] = z.scheduled(now)
+			if want[i] && z.Moisture != nil && s.MoistureErr == nil {
+				threshold := z.DryBelow
+				if s.Watering && z.WetAbove > threshold {
+					threshold = z.WetAbove
+				}
+				want[i] = s.Moisture < threshold
+			}
+		}
+		if want[i] && s.Watering && z.MaxRuntime > 0 && now.Sub(s.Since) >= z.MaxRuntime {
+			s.Fault = ErrMaxRuntime
+			want[i] = false
+		}
+	}
+
+	//close first so the pump never runs against closed valves
+	var err error
+	keep := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	for i, z := range ir.Zones {
+		if s := ir.state(z.Name); s.Watering && !want[i] {
+			if e := ir.Bank.Off(z.Valve); e != nil {
+				keep(e)
+				continue
+			}
+			s.Watering, s.Since = false, time.Time{}
+		}
+	}
+	for i, z := range ir.Zones {
+		if s := ir.state(z.Name); !s.Watering && want[i] {
+			if e := ir.Bank.On(z.Valve); e != nil {
+				keep(e)
+				continue
+			}
+			s.Watering, s.Since = true, now
+		}
+	}
+	//the pump follows the valves which actually opened, not those wanted
+	open := false
+	for i, z := range ir.Zones {
+		open = open || want[i] && ir.state(z.Name).Watering
+	}
+	if ir.Pump != "" {
+		if open {
+			keep(ir.Bank.On(ir.Pump))
+		} else {
+			keep(ir.Bank.Off(ir.Pump))
+		}
+	}
+	return err
+}
+
+//scheduled reports whether now is within one of the zone's windows
+func (z *IrrigationZone) scheduled(now time.Time) bool {
+	for _, w := range z.Schedule {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+//Override manually opens or closes the valve of a zone, ignoring its schedule
+//and moisture, for d or until ClearOverride is called if d is zero. Opened
+//valves are still closed after the zone's MaxRuntime. The valve is switched
+//at the next Step.
+func (ir *Irrigation) Override(zone string, on bool, d time.Duration) error {
+	if _, err := ir.zone(zone); err != nil {
+		return err
+	}
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	s := ir.state(zone)
+	s.Override, s.OverrideUntil = &on, time.Time{}
+	if d > 0 {
+		s.OverrideUntil = ir.Bank.clock.Now().Add(d)
+	}
+	return nil
+}
+
+//ClearOverride returns a zone to its schedule
+func (ir *Irrigation) ClearOverride(zone string) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	s := ir.state(zone)
+	s.Override, s.OverrideUntil = nil, time.Time{}
+}
+
+//ClearFault releases a zone after a fault
+func (ir *Irrigation) ClearFault(zone string) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	ir.state(zone).Fault = nil
+}
+
+//Snapshot returns the state of a zone
+func (ir *Irrigation) Snapshot(zone string) ZoneState {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	return *ir.state(zone)
+}
+
+//Start runs the controller every Interval on s as the task called name
+func (ir *Irrigation) Start(s *Scheduler, name string) {
+	ir.mu.Lock()
+	ir.sched, ir.name = s, name
+	ir.mu.Unlock()
+	s.Every(name, ir.Interval, func(ctx context.Context) error {
+		return ir.Step(ctx, s.clock.Now())
+	})
+}
+
+//Stop stops the controller and closes every valve and the pump
+func (ir *Irrigation) Stop() error {
+	ir.mu.Lock()
+	s, name := ir.sched, ir.name
+	ir.sched = nil
+	for _, st := range ir.states {
+		st.Watering, st.Since = false, time.Time{}
+	}
+	ir.mu.Unlock()
+	if s != nil {
+		s.Remove(name)
+	}
+	return ir.Bank.AllOff()
+}
@@ -0,0 +1,130 @@
+package nango
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIrrigation(t *testing.T) {
+	lb, conn := openLoopback(t)
+	pins := map[string]string{}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, func(c LoopbackCall) (string, bool) {
+		pins[c.Args[0]] = c.Args[1]
+		return "", true
+	})
+	b, err := NewRelayBank(NewArduinoApi(conn),
+		Relay{Name: "pump", Pin: "D4"},
+		Relay{Name: "beds", Pin: "D5"},
+		Relay{Name: "lawn", Pin: "D6"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moisture := 50.0
+	ir := &Irrigation{
+		Bank: b,
+		Pump: "pump",
+		Zones: []IrrigationZone{{
+			Name:       "beds",
+			Valve:      "beds",
+			Schedule:   []WateringWindow{{From: 6 * time.Hour, Duration: time.Hour}},
+			Moisture:   func(ctx context.Context) (float64, error) { return moisture, nil },
+			DryBelow:   30,
+			WetAbove:   40,
+			MaxRuntime: 20 * time.Minute,
+		}, {
+			Name:     "lawn",
+			Valve:    "lawn",
+			Schedule: []WateringWindow{{From: 23 * time.Hour, Duration: 2 * time.Hour, Days: []time.Weekday{time.Monday}}},
+		}},
+	}
+	ctx := context.Background()
+	//a Tuesday
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	step := func(at time.Duration) {
+		t.Helper()
+		if err := ir.Step(ctx, day.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//Monday's lawn window carries on past midnight
+	step(30 * time.Minute)
+	if !ir.Snapshot("lawn").Watering || pins["D6"] != "1" || pins["D4"] != "1" {
+		t.Fatalf("expected lawn and pump on, got %v", pins)
+	}
+	step(time.Hour)
+	if ir.Snapshot("lawn").Watering || pins["D4"] != "0" {
+		t.Fatalf("expected lawn and pump off, got %v", pins)
+	}
+
+	//the beds are too wet to water, then water until they reach WetAbove
+	step(6 * time.Hour)
+	if ir.Snapshot("beds").Watering {
+		t.Fatal("expected wet beds not watered")
+	}
+	moisture = 25
+	step(6*time.Hour + time.Minute)
+	moisture = 35
+	step(6*time.Hour + 2*time.Minute)
+	if !ir.Snapshot("beds").Watering || pins["D5"] != "1" {
+		t.Fatalf("expected beds watered until wet, got %v", pins)
+	}
+	step(6*time.Hour + 21*time.Minute)
+	if s := ir.Snapshot("beds"); s.Watering || s.Fault != ErrMaxRuntime || pins["D5"] != "0" {
+		t.Fatalf("expected max runtime cutoff, got %+v", s)
+	}
+
+	//manual override opens a valve outside its schedule
+	ir.ClearFault("beds")
+	if err := ir.Override("lawn", true, 0); err != nil {
+		t.Fatal(err)
+	}
+	step(12 * time.Hour)
+	if !ir.Snapshot("lawn").Watering || pins["D4"] != "1" {
+		t.Fatalf("expected overridden lawn on, got %v", pins)
+	}
+	ir.ClearOverride("lawn")
+	step(12*time.Hour + time.Minute)
+	if ir.Snapshot("lawn").Watering {
+		t.Fatal("expected lawn off once the override is cleared")
+	}
+	if err := ir.Override("patio", true, 0); err == nil {
+		t.Fatal("expected unknown zone error")
+	}
+}
+
+func TestIrrigationValveFailure(t *testing.T) {
+	lb, conn := openLoopback(t)
+	pins := map[string]string{}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, func(c LoopbackCall) (string, bool) {
+		if c.Args[0] == "D5" && c.Args[1] == "1" {
+			return "!ERR dw\tvalve stuck\t100", true
+		}
+		pins[c.Args[0]] = c.Args[1]
+		return "", true
+	})
+	b, err := NewRelayBank(NewArduinoApi(conn),
+		Relay{Name: "pump", Pin: "D4"},
+		Relay{Name: "beds", Pin: "D5"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir := &Irrigation{
+		Bank:  b,
+		Pump:  "pump",
+		Zones: []IrrigationZone{{Name: "beds", Valve: "beds"}},
+	}
+	if err := ir.Override("beds", true, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ir.Step(context.Background(), time.Now()); err == nil {
+		t.Fatal("expected the valve error")
+	}
+	//the pump must not run against the valve which failed to open
+	if ir.Snapshot("beds").Watering || pins["D4"] != "0" {
+		t.Fatalf("expected pump off with the valve closed, got %v", pins)
+	}
+}
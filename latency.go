@@ -0,0 +1,134 @@
+package nango
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//latencyBuckets are the upper bounds of the LatencyHistogram buckets
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+//LatencyHistogram summarizes the round trip times of one firmware method
+type LatencyHistogram struct {
+	Count int
+	Sum   time.Duration
+	Max   time.Duration
+	//Buckets counts calls by latency. Buckets[i] counts calls taking at most
+	//LatencyBucketBound(i); the final bucket counts slower calls.
+	Buckets []int
+}
+
+//LatencyBucketBound returns the upper bound of histogram bucket i, or -1 for
+//the final unbounded bucket
+func LatencyBucketBound(i int) time.Duration {
+	if i >= len(latencyBuckets) {
+		return -1
+	}
+	return latencyBuckets[i]
+}
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	if h.Buckets == nil {
+		h.Buckets = make([]int, len(latencyBuckets)+1)
+	}
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.Buckets[i]++
+}
+
+//Mean returns the average latency
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+//Quantile returns the upper bound of the bucket containing quantile q (0-1)
+//of calls, or Max if it falls in the final bucket
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	target := int(q*float64(h.Count) + 0.5)
+	n := 0
+	for i, c := range h.Buckets {
+		n += c
+		if n >= target && n > 0 {
+			if i < len(latencyBuckets) {
+				return latencyBuckets[i]
+			}
+			break
+		}
+	}
+	return h.Max
+}
+
+//latencies tracks a LatencyHistogram per namespace and method
+type latencies struct {
+	mu    sync.Mutex
+	hists map[string]*LatencyHistogram
+}
+
+func (l *latencies) observe(namespace string, method string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hists == nil {
+		l.hists = make(map[string]*LatencyHistogram)
+	}
+	key := namespace + "." + method
+	h, ok := l.hists[key]
+	if !ok {
+		h = new(LatencyHistogram)
+		l.hists[key] = h
+	}
+	h.observe(d)
+}
+
+//Latencies returns a snapshot of the latency histograms recorded while
+//RecordLatency is set, keyed by "namespace.method"
+func (s *FirmwareConnection) Latencies() map[string]LatencyHistogram {
+	s.latencies.mu.Lock()
+	defer s.latencies.mu.Unlock()
+	m := make(map[string]LatencyHistogram, len(s.latencies.hists))
+	for k, h := range s.latencies.hists {
+		c := *h
+		c.Buckets = append([]int(nil), h.Buckets...)
+		m[k] = c
+	}
+	return m
+}
+
+//ResetLatencies discards all recorded latency histograms
+func (s *FirmwareConnection) ResetLatencies() {
+	s.latencies.mu.Lock()
+	defer s.latencies.mu.Unlock()
+	s.latencies.hists = nil
+}
+
+//logSlowCall logs a call which exceeded SlowCallThreshold
+func (s *FirmwareConnection) logSlowCall(namespace string, method string, args []interface{}, elapsed time.Duration, err error) {
+	summary := fmt.Sprint(args...)
+	if len(summary) > 64 {
+		summary = summary[:61] + "..."
+	}
+	s.logf("slow call on %s: %s.%s(%s) took %s (err: %v)", s.Name(), namespace, method, summary, elapsed, err)
+}
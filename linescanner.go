@@ -0,0 +1,128 @@
+package nango
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+//LineEvent is a line of output from a device attached to the board
+type LineEvent struct {
+	//Time is when the end of the line was read
+	Time time.Time
+	//Line is the line without its terminator
+	Line string
+	//Fields holds the named subexpressions of the scanner's pattern which
+	//matched the line. It is nil if the line didn't match.
+	Fields map[string]string
+}
+
+//LineScanner reads line-oriented output, such as from a barcode scanner or
+//serial scale, from a UART on the board and delivers each non-empty line on
+//C. Events are dropped if the consumer falls behind.
+type LineScanner struct {
+	C <-chan LineEvent
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+	err    error
+}
+
+//NewLineScanner starts reading lines from serial, which must already have
+//been begun at the device's baud rate. If pattern is not nil, its named
+//subexpressions are extracted into the Fields of the lines it matches.
+func NewLineScanner(conn Conn, serial AttachedSerial, pattern *regexp.Regexp) *LineScanner {
+	c := make(chan LineEvent, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &LineScanner{C: c, cancel: cancel, done: make(chan struct{})}
+	go l.run(ctx, newLineReader(serial), c, clockOf(conn), pattern)
+	return l
+}
+
+//lineReader reads lines from a UART whose reads time out while the device is
+//quiet
+type lineReader struct {
+	r       *bufio.Reader
+	partial string
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+//readLine returns the next line, including its terminator. If the read times
+//out first it returns the timeout error and the start of the line is kept to
+//be joined to the rest of it by the next call.
+func (l *lineReader) readLine() (string, error) {
+	line, err := l.r.ReadString('\n')
+	line, l.partial = l.partial+line, ""
+	if errors.Is(err, ErrTimeout) {
+		l.partial = line
+		return "", err
+	}
+	return line, err
+}
+
+func (l *LineScanner) run(ctx context.Context, r *lineReader, c chan<- LineEvent, clock Clock, pattern *regexp.Regexp) {
+	defer close(l.done)
+	defer close(c)
+	for ctx.Err() == nil {
+		line, err := r.readLine()
+		if errors.Is(err, ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			l.mu.Lock()
+			l.err = err
+			l.mu.Unlock()
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		e := LineEvent{Time: clock.Now(), Line: line}
+		if pattern != nil {
+			e.Fields = extractFields(pattern, line)
+		}
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
+
+//extractFields returns the named subexpressions of pattern matching line,
+//or nil if it doesn't match
+func extractFields(pattern *regexp.Regexp, line string) map[string]string {
+	m := pattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	for i, name := range pattern.SubexpNames() {
+		if name != "" && m[2*i] >= 0 {
+			fields[name] = line[m[2*i]:m[2*i+1]]
+		}
+	}
+	return fields
+}
+
+//Err returns the error which stopped the scanner, if any
+func (l *LineScanner) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+//Close stops reading, waiting for the read in progress to return
+func (l *LineScanner) Close() {
+	l.cancel()
+	<-l.done
+}
@@ -0,0 +1,58 @@
+package nango
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLineScanner(t *testing.T) {
+	_, conn := openLoopback(t)
+	m := &fakeModem{}
+	m.response.WriteString("ST,GS,+  12.50 kg\r\n\r\nUS,NT,+")
+	l := NewLineScanner(conn, m, regexp.MustCompile(`^(?P<status>ST|US),GS,(?P<weight>[+-]\s*[\d.]+)(?: (?P<unit>\w+))?`))
+	defer l.Close()
+
+	e := <-l.C
+	if e.Line != "ST,GS,+  12.50 kg" || e.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if e.Fields["status"] != "ST" || e.Fields["weight"] != "+  12.50" || e.Fields["unit"] != "kg" {
+		t.Fatalf("unexpected fields %v", e.Fields)
+	}
+
+	//the rest of a line arriving after a timeout is joined to its start
+	for {
+		m.mu.Lock()
+		if m.timeouts > 0 {
+			break
+		}
+		m.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	m.response.WriteString("   0.20 kg\r\nERR\r\n")
+	m.mu.Unlock()
+	if e := <-l.C; e.Line != "US,NT,+   0.20 kg" || e.Fields != nil {
+		t.Fatalf("expected unmatched line, got %+v", e)
+	}
+	if e := <-l.C; e.Line != "ERR" {
+		t.Fatalf("expected empty line skipped, got %+v", e)
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	m := &fakeModem{}
+	m.response.WriteString("ab\r\ncd")
+	r := newLineReader(m)
+	if line, err := r.readLine(); line != "ab\r\n" || err != nil {
+		t.Fatalf("unexpected line %q, %v", line, err)
+	}
+	if line, err := r.readLine(); !errors.Is(err, ErrTimeout) || line != "" {
+		t.Fatalf("expected a timeout, got %q, %v", line, err)
+	}
+	m.response.WriteString("ef\n")
+	if line, err := r.readLine(); line != "cdef\n" || err != nil {
+		t.Fatalf("expected the line joined after the timeout, got %q, %v", line, err)
+	}
+}
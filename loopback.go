@@ -0,0 +1,217 @@
+package nango
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//loopbackReadTimeout is how long a Loopback read waits for a response before
+//reporting that no data has arrived
+const loopbackReadTimeout = 10 * time.Millisecond
+
+//LoopbackCall is a call received by a Loopback
+type LoopbackCall struct {
+	Namespace string
+	Id        int
+	Method    string
+	//Args holds the wire encoding of each argument
+	Args []string
+	//Node is the address of a call made through a Node, empty if the call
+	//is unaddressed. The responses to addressed calls are prefixed with the
+	//address, as a node's would be.
+	Node string
+}
+
+//LoopbackHandler computes the response to a call. Returning false sends no
+//response, as if the firmware never answered.
+type LoopbackHandler func(call LoopbackCall) (response string, ok bool)
+
+//Loopback is a Transport which stands in for the firmware: it parses the
+//requests written to it and answers them from registered handlers, so the full
+//call path from encoding through the dispatcher to response parsing can be
+//exercised without hardware. Calls without a handler are answered with their
+//arguments joined by commas.
+//
+//	lb := nango.NewLoopback()
+//	lb.Respond("A", "r", "1")
+//	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial))
+type Loopback struct {
+	mu       sync.Mutex
+	handlers map[string]LoopbackHandler
+	in       []byte
+	out      bytes.Buffer
+	closed   bool
+	ready    chan struct{}
+}
+
+func NewLoopback() *Loopback {
+	return &Loopback{
+		handlers: make(map[string]LoopbackHandler),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+//Handle answers calls to method in namespace with h
+func (l *Loopback) Handle(namespace string, method string, h LoopbackHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[namespace+"."+method] = h
+}
+
+//Respond answers every call to method in namespace with response
+func (l *Loopback) Respond(namespace string, method string, response string) {
+	l.Handle(namespace, method, func(LoopbackCall) (string, bool) {
+		return response, true
+	})
+}
+
+//Dial opens the loopback. It is a DialFunc for WithTransport.
+func (l *Loopback) Dial() (Transport, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = false
+	l.in = l.in[:0]
+	l.out.Reset()
+	return l, nil
+}
+
+func (l *Loopback) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return 0, ErrPortClosed
+	}
+	l.in = append(l.in, b...)
+	var calls []LoopbackCall
+	for {
+		//like the firmware, ignore the bytes which wake it
+		for len(l.in) > 0 && l.in[0] == wakeByte {
+			l.in = l.in[1:]
+		}
+		call, n, ok := ParseCall(l.in)
+		if !ok {
+			break
+		}
+		l.in = l.in[n:]
+		calls = append(calls, call)
+	}
+	l.mu.Unlock()
+	//handlers run unlocked as they may block to simulate slow calls
+	for _, call := range calls {
+		response, ok := l.answer(call)
+		if !ok {
+			continue
+		}
+		l.mu.Lock()
+		if call.Node != "" {
+			l.out.WriteString(string(nodeMarker) + call.Node + " ")
+		}
+		l.out.WriteString(response)
+		l.out.WriteString("\r\n")
+		l.mu.Unlock()
+		select {
+		case l.ready <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+func (l *Loopback) answer(call LoopbackCall) (string, bool) {
+	l.mu.Lock()
+	h := l.handlers[call.Namespace+"."+call.Method]
+	l.mu.Unlock()
+	if h == nil {
+		return strings.Join(call.Args, ","), true
+	}
+	return h(call)
+}
+
+//Read returns pending responses, or 0 and io.EOF if none arrive within a
+//short timeout, like a serial port opened with a read timeout
+func (l *Loopback) Read(b []byte) (int, error) {
+	timer := time.NewTimer(loopbackReadTimeout)
+	defer timer.Stop()
+	for {
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return 0, ErrPortClosed
+		}
+		if l.out.Len() > 0 {
+			n, err := l.out.Read(b)
+			l.mu.Unlock()
+			return n, err
+		}
+		l.mu.Unlock()
+		select {
+		case <-l.ready:
+		case <-timer.C:
+			return 0, io.EOF
+		}
+	}
+}
+
+//Flush discards responses not yet read
+func (l *Loopback) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Reset()
+	return nil
+}
+
+func (l *Loopback) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+//ParseCall parses the first complete call framed in b, as written by a
+//connection, returning it and its length in bytes. ok is false if b doesn't
+//hold a complete call yet. It lets test doubles standing in for the firmware,
+//such as Loopback, decode requests.
+func ParseCall(b []byte) (call LoopbackCall, n int, ok bool) {
+	field := func() (string, bool) {
+		i := bytes.IndexByte(b[n:], 0)
+		if i < 0 {
+			return "", false
+		}
+		s := string(b[n : n+i])
+		n += i + 1
+		return s, true
+	}
+	var id, nargs string
+	if len(b) > 0 && b[0] == nodeMarker {
+		if call.Node, ok = field(); !ok {
+			return
+		}
+		call.Node = call.Node[1:]
+	}
+	if call.Namespace, ok = field(); !ok {
+		return
+	}
+	if id, ok = field(); !ok {
+		return
+	}
+	if nargs, ok = field(); !ok {
+		return
+	}
+	if call.Method, ok = field(); !ok {
+		return
+	}
+	call.Id, _ = strconv.Atoi(id)
+	count, _ := strconv.Atoi(nargs)
+	for i := 0; i < count; i++ {
+		var arg string
+		if arg, ok = field(); !ok {
+			return
+		}
+		call.Args = append(call.Args, arg)
+	}
+	return call, n, true
+}
@@ -0,0 +1,523 @@
+package nango
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openLoopback(t *testing.T, opts ...Option) (*Loopback, *FirmwareConnection) {
+	t.Helper()
+	lb := NewLoopback()
+	opts = append([]Option{WithTransport(lb.Dial), WithReadTimeout(100 * time.Millisecond)}, opts...)
+	conn := NewFirmwareConnection(nil, opts...)
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return lb, conn
+}
+
+func TestLoopbackEncoding(t *testing.T) {
+	_, conn := openLoopback(t)
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	s, err := f.call("echo", "D13", 42, byte(7), []byte{0xca, 0xfe}, true, nil, []interface{}{"x", nil, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "D13,42,7,cafe,True,x,False"; s != want {
+		t.Fatalf("expected %q, got %q", want, s)
+	}
+}
+
+func TestLoopbackDialect(t *testing.T) {
+	lb := NewLoopback()
+	d := CDialect
+	d.OmitNil = false
+	d.Nil = "-1"
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithReadTimeout(100*time.Millisecond), WithDialect(d))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	s, err := f.call("echo", true, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1,-1,0"; s != want {
+		t.Fatalf("expected %q, got %q", want, s)
+	}
+}
+
+func TestLoopbackException(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond("T", "fail", "!ERR fail\tassert i < 8\t312")
+	lb.Respond("T", "ok", "1")
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	p := conn.Pipeline()
+	failed := p.Call(f, "fail")
+	ok := p.Call(f, "ok")
+	p.Exec()
+	var e *FirmwareException
+	if !errors.As(failed.Err(), &e) || e.Method != "fail" || e.Reason != "assert i < 8" || e.FreeMemory != 312 {
+		t.Fatalf("expected FirmwareException, got %v", failed.Err())
+	}
+	if v, err := ok.Int(); err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+}
+
+func TestLoopbackMiddleware(t *testing.T) {
+	lb := NewLoopback()
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next CallFunc) CallFunc {
+			return func(ctx context.Context, c *CallInfo) (*Buffer, error) {
+				order = append(order, name+":"+c.Method)
+				return next(ctx, c)
+			}
+		}
+	}
+	denied := errors.New("denied")
+	deny := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, c *CallInfo) (*Buffer, error) {
+			if c.Method == "secret" {
+				return nil, denied
+			}
+			return next(ctx, c)
+		}
+	}
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithReadTimeout(100*time.Millisecond),
+		WithMiddleware(trace("outer"), trace("inner")), WithMiddleware(deny))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	if s, err := f.call("echo", 1); err != nil || s != "1" {
+		t.Fatalf("expected 1, got %q (%v)", s, err)
+	}
+	if _, err := f.call("secret"); err != denied {
+		t.Fatalf("expected denied, got %v", err)
+	}
+	want := []string{"outer:echo", "inner:echo", "outer:secret", "inner:secret"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, order)
+		}
+	}
+}
+
+func TestLoopbackPipelineMiddleware(t *testing.T) {
+	lb := NewLoopback()
+	var mu sync.Mutex
+	var seen []string
+	denied := errors.New("denied")
+	mw := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, c *CallInfo) (*Buffer, error) {
+			mu.Lock()
+			seen = append(seen, c.Method)
+			mu.Unlock()
+			if c.Method == "secret" {
+				return nil, denied
+			}
+			if c.Method == "double" {
+				c.Method, c.Args = "echo", append(c.Args, c.Args...)
+			}
+			return next(ctx, c)
+		}
+	}
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithReadTimeout(100*time.Millisecond), WithMiddleware(mw))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	p := conn.Pipeline()
+	echo := p.Call(f, "echo", 1)
+	secret := p.Call(f, "secret")
+	double := p.Call(f, "double", 2)
+	if err := p.Exec(); err != denied {
+		t.Fatalf("expected denied, got %v", err)
+	}
+	if v, err := echo.Value(); err != nil || v != "1" {
+		t.Fatalf("expected 1, got %q (%v)", v, err)
+	}
+	if secret.Err() != denied {
+		t.Fatalf("expected the secret call denied, got %v", secret.Err())
+	}
+	if v, err := double.Value(); err != nil || v != "2,2" {
+		t.Fatalf("expected the rewritten call to be sent, got %q (%v)", v, err)
+	}
+
+	var writes int
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, func(LoopbackCall) (string, bool) {
+		writes++
+		return "", true
+	})
+	if err := conn.Transaction().DigitalWrite("D7", PinHigh).Call(f, "secret").Commit(); err != denied {
+		t.Fatalf("expected the transaction to be denied, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if writes != 1 || strings.Join(seen, ",") != "echo,secret,double,dw,secret" {
+		t.Fatalf("expected every call to pass through the middleware, got %v", seen)
+	}
+}
+
+func TestLoopbackTransaction(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var mu sync.Mutex
+	var calls []string
+	record := func(c LoopbackCall) (string, bool) {
+		mu.Lock()
+		calls = append(calls, c.Method)
+		mu.Unlock()
+		return "0", true
+	}
+	for _, m := range []string{MethodPinMode, MethodDigitalWrite, MethodDelay} {
+		lb.Handle(NamespaceArduino, m, record)
+	}
+	for _, m := range []string{"beginTransmission", "write", "endTransmission"} {
+		lb.Handle(NamespaceWire, m, record)
+	}
+	rolledBack := false
+	err := conn.Transaction().
+		PinMode("D7", 1).
+		DigitalWrite("D7", 1).
+		Delay(10*time.Millisecond).
+		I2CWrite(0x20, []byte{1, 2}).
+		OnRollback(func() error { rolledBack = true; return nil }).
+		Commit()
+	if err != nil || rolledBack {
+		t.Fatalf("expected commit, got %v (rolled back %v)", err, rolledBack)
+	}
+	want := []string{"pm", "dw", "d", "beginTransmission", "write", "write", "endTransmission"}
+	mu.Lock()
+	got := strings.Join(calls, ",")
+	mu.Unlock()
+	if got != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	lb.Respond(NamespaceWire, "endTransmission", "2")
+	err = conn.Transaction().
+		I2CWrite(0x20, []byte{1}).
+		OnRollback(func() error { rolledBack = true; return nil }).
+		Commit()
+	var i2cErr *I2CError
+	if !errors.As(err, &i2cErr) || i2cErr.Code != I2CAddressNack || !rolledBack {
+		t.Fatalf("expected rolled back I2CError, got %v (rolled back %v)", err, rolledBack)
+	}
+}
+
+func TestLoopbackSafeStates(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var mu sync.Mutex
+	var writes []string
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, func(c LoopbackCall) (string, bool) {
+		mu.Lock()
+		writes = append(writes, strings.Join(c.Args, "="))
+		mu.Unlock()
+		return "", true
+	})
+	applied := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		s := strings.Join(writes, ",")
+		writes = nil
+		return s
+	}
+	api := NewArduinoApi(conn)
+	api.SkipRedundant = true
+	api.SafePin("D7", 0)
+	api.SafePin("D8", 1)
+	api.SafePin("D7", 1)
+	if err := api.SafePin("", 0); err == nil {
+		t.Fatal("expected a safe state without a key to be rejected")
+	}
+
+	func() {
+		defer func() { recover() }()
+		defer conn.RecoverSafeState()
+		panic("boom")
+	}()
+	if got := applied(); got != "D8=1,D7=1" {
+		t.Fatalf("expected safe states applied on panic, got %q", got)
+	}
+
+	//the safe states leave the SkipRedundant cache behind
+	if err := api.DigitalWrite("D7", 0); err != nil {
+		t.Fatal(err)
+	}
+	conn.ApplySafeStates(context.Background())
+	if err := api.DigitalWrite("D7", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := applied(); got != "D7=0,D8=1,D7=1,D7=0" {
+		t.Fatalf("expected the write after the safe states sent, got %q", got)
+	}
+
+	w := conn.WatchHost(20 * time.Millisecond)
+	w.Kick()
+	time.Sleep(60 * time.Millisecond)
+	w.Stop()
+	if got := applied(); got != "D8=1,D7=1" {
+		t.Fatalf("expected safe states applied by watchdog, got %q", got)
+	}
+
+	//OnClose hooks are safe states too
+	conn.OnClose(func(ctx context.Context) error {
+		return api.DigitalWrite("D9", 0)
+	})
+	conn.ClearSafeState("D8")
+	conn.Close()
+	if got := applied(); got != "D9=0,D7=1" {
+		t.Fatalf("expected safe states applied on close, got %q", got)
+	}
+}
+
+func TestLoopbackMalformedResponses(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond(NamespaceCounter, "readAndReset", "12")
+	lb.Respond("T", "get", "")
+	var fwErr *FirmwareError
+	_, err := NewCounter(conn).ReadAndReset("D2")
+	if !errors.As(err, &fwErr) || fwErr.Method != "readAndReset" || fwErr.Response != "12" {
+		t.Fatalf("expected FirmwareError, got %v", err)
+	}
+	p := conn.Pipeline()
+	r := p.Call(&FirmwareClass{Conn: conn, Namespace: "T"}, "get")
+	p.Exec()
+	if _, err := r.Int(); !errors.As(err, &fwErr) || fwErr.Method != "get" {
+		t.Fatalf("expected FirmwareError, got %v", err)
+	}
+	err = Recover(func() error {
+		var b []byte
+		_ = b[0]
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected panic to be returned as an error")
+	}
+}
+
+func TestLoopbackResponses(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond("A", "r", "1")
+	lb.Respond("T", "float", "2.5")
+	lb.Respond("T", "bytes", "deadbeef")
+	api := NewArduinoApi(conn)
+	v, err := api.DigitalRead("D2")
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	fl, err := f.CallAndReturnFloat("float")
+	if err != nil || fl != 2.5 {
+		t.Fatalf("expected 2.5, got %v (%v)", fl, err)
+	}
+	b, err := f.CallAndReturnBytes("bytes")
+	if err != nil || !bytes.Equal(b, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("expected deadbeef, got %x (%v)", b, err)
+	}
+	_, err = f.CallAndReturnInt("float")
+	var fwErr *FirmwareError
+	if !errors.As(err, &fwErr) || fwErr.Response != "2.5" {
+		t.Fatalf("expected FirmwareError, got %v", err)
+	}
+}
+
+func TestLoopbackPipeline(t *testing.T) {
+	_, conn := openLoopback(t)
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	p := conn.Pipeline()
+	r1 := p.Call(f, "echo", 1)
+	r2 := p.Call(f, "echo", 2)
+	if err := p.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r1.Int(); v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	if v, _ := r2.Int(); v != 2 {
+		t.Fatalf("expected 2, got %d", v)
+	}
+}
+
+func TestLoopbackTimeout(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Handle("T", "silent", func(LoopbackCall) (string, bool) { return "", false })
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	_, err := f.call("silent")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	var te interface {
+		Timeout() bool
+		Temporary() bool
+	}
+	if !errors.As(err, &te) || !te.Timeout() || !te.Temporary() {
+		t.Fatalf("expected a temporary timeout error, got %v", err)
+	}
+	//the connection recovers for later calls
+	s, err := f.call("echo", "ok")
+	if err != nil || s != "ok" {
+		t.Fatalf("expected ok, got %q (%v)", s, err)
+	}
+}
+
+func TestLoopbackContextCancelled(t *testing.T) {
+	_, conn := openLoopback(t)
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := f.CallContext(ctx, "echo")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLoopbackClosed(t *testing.T) {
+	_, conn := openLoopback(t)
+	conn.Close()
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	_, err := f.call("echo")
+	if !errors.Is(err, ErrPortClosed) {
+		t.Fatalf("expected ErrPortClosed, got %v", err)
+	}
+}
+
+func TestLoopbackRequiredClasses(t *testing.T) {
+	lb := NewLoopback()
+	lb.Respond(NamespaceInfo, "count", "2")
+	lb.Handle(NamespaceInfo, "name", func(c LoopbackCall) (string, bool) {
+		return []string{NamespaceArduino, NamespaceWire}[c.Args[0][0]-'0'], true
+	})
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithRequiredClasses(NamespaceArduino, NamespaceServo))
+	err := conn.Open()
+	var missing *MissingClassesError
+	if !errors.As(err, &missing) || len(missing.Missing) != 1 || missing.Missing[0] != NamespaceServo {
+		t.Fatalf("expected Servo to be missing, got %v", err)
+	}
+	conn = NewFirmwareConnection(nil, WithTransport(lb.Dial), WithRequiredClasses(NamespaceArduino, NamespaceWire))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestLoopbackSkipRedundantAfterAnalogWrite(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var mu sync.Mutex
+	var calls []string
+	record := func(c LoopbackCall) (string, bool) {
+		mu.Lock()
+		calls = append(calls, c.Method+" "+strings.Join(c.Args, " "))
+		mu.Unlock()
+		return "", true
+	}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, record)
+	lb.Handle(NamespaceArduino, MethodAnalogWrite, record)
+	api := NewArduinoApi(conn)
+	api.SkipRedundant = true
+	for _, f := range []func() error{
+		func() error { return api.DigitalWrite("D9", PinHigh) },
+		func() error { return api.AnalogWrite("D9", 128) },
+		//the pin is no longer known to be high, so this must reach the board
+		func() error { return api.DigitalWrite("D9", PinHigh) },
+		func() error { return api.DigitalWrite("D9", PinHigh) },
+	} {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := strings.Join(calls, ","), "dw D9 1,aw D9 128,dw D9 1"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLoopbackCloseDuringCalls(t *testing.T) {
+	_, conn := openLoopback(t)
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := f.call("echo", j); errors.Is(err, ErrPortClosed) {
+					return
+				}
+			}
+		}()
+	}
+	conn.Close()
+	wg.Wait()
+}
+
+func TestLoopbackBorrowBytesErrors(t *testing.T) {
+	lb := NewLoopback()
+	lb.Respond("T", "odd", "abc")
+	lb.Respond("T", "ok", "cafe")
+	//a middleware returning no buffer makes decoding panic
+	mw := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, c *CallInfo) (*Buffer, error) {
+			if c.Method == "nothing" {
+				return nil, nil
+			}
+			return next(ctx, c)
+		}
+	}
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithMiddleware(mw))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &FirmwareClass{Conn: conn, Namespace: "T"}
+	for _, method := range []string{"odd", "nothing"} {
+		b, err := f.CallAndBorrowBytes(method)
+		var fe *FirmwareError
+		if !errors.As(err, &fe) {
+			t.Fatalf("%s: expected a FirmwareError, got %v", method, err)
+		}
+		if b != nil {
+			t.Fatalf("%s: expected no buffer returned with the error", method)
+		}
+	}
+	b, err := f.CallAndBorrowBytes("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Release()
+	if !bytes.Equal(b.Bytes(), []byte{0xca, 0xfe}) {
+		t.Fatalf("unexpected response %x", b.Bytes())
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	frame, err := appendFrame(nil, "T", 3, "echo", []interface{}{"x", 7}, &NanpyDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(frame); i++ {
+		if _, _, ok := ParseCall(frame[:i]); ok {
+			t.Fatalf("parsed an incomplete call from %q", frame[:i])
+		}
+	}
+	call, n, ok := ParseCall(append(frame, 'A'))
+	if !ok || n != len(frame) || call.Namespace != "T" || call.Id != 3 || call.Method != "echo" ||
+		len(call.Args) != 2 || call.Args[0] != "x" || call.Args[1] != "7" || call.Node != "" {
+		t.Fatalf("unexpected call %+v of %d bytes", call, n)
+	}
+}
@@ -0,0 +1,115 @@
+package machine
+
+import (
+	"github.com/justinsantoro/nango"
+)
+
+//I2CConfig holds the configuration of an I2C bus. Its fields are ignored as
+//the bus is configured by the firmware.
+type I2CConfig struct {
+	Frequency uint32
+	SCL       Pin
+	SDA       Pin
+}
+
+//I2C is an I2C bus
+type I2C struct{}
+
+//I2C0 is the board's I2C bus
+var I2C0 = &I2C{}
+
+func (i2c *I2C) Configure(config I2CConfig) error {
+	return nil
+}
+
+//Tx writes w to the device at addr and then reads len(r) bytes into r. The
+//write and read are separate transactions rather than joined by a repeated
+//start.
+func (i2c *I2C) Tx(addr uint16, w, r []byte) error {
+	m := current().I2C()
+	if len(w) > 0 {
+		err := m.Send(nango.I2CAddress(addr), w)
+		if err != nil {
+			return err
+		}
+	}
+	if len(r) > 0 {
+		b, err := m.Request(nango.I2CAddress(addr), len(r))
+		if err != nil {
+			return err
+		}
+		copy(r, b)
+	}
+	return nil
+}
+
+//ReadRegister reads len(data) bytes from register r of the device at address
+func (i2c *I2C) ReadRegister(address uint8, r uint8, data []byte) error {
+	return i2c.Tx(uint16(address), []byte{r}, data)
+}
+
+//WriteRegister writes data to register r of the device at address
+func (i2c *I2C) WriteRegister(address uint8, r uint8, data []byte) error {
+	return i2c.Tx(uint16(address), append([]byte{r}, data...), nil)
+}
+
+//SPIConfig holds the configuration of an SPI bus
+type SPIConfig struct {
+	Frequency uint32
+	LSBFirst  bool
+	Mode      uint8
+	SCK       Pin
+	SDO       Pin
+	SDI       Pin
+}
+
+//SPI is an SPI bus
+type SPI struct {
+	settings nango.SpiSettings
+}
+
+//SPI0 is the board's SPI bus
+var SPI0 = &SPI{settings: nango.DefaultSpiSettings}
+
+//Configure starts the bus with the given settings
+func (spi *SPI) Configure(config SPIConfig) error {
+	spi.settings = nango.DefaultSpiSettings
+	if config.Frequency > 0 {
+		spi.settings.Clock = int(config.Frequency)
+	}
+	if config.LSBFirst {
+		spi.settings.BitOrder = nango.LsbFirst
+	}
+	spi.settings.DataMode = int(config.Mode)
+	return current().SPI().Begin()
+}
+
+//Tx exchanges w with the bus, writing the bytes received into r. Either may
+//be nil; zeros are sent when w is shorter than r.
+func (spi *SPI) Tx(w, r []byte) error {
+	n := len(w)
+	if len(r) > n {
+		n = len(r)
+	}
+	tx := make([]byte, n)
+	copy(tx, w)
+	bus := current().SPI()
+	err := bus.BeginTransaction(spi.settings)
+	if err != nil {
+		return err
+	}
+	rx, err := bus.TransferBytes(tx)
+	errEnd := bus.EndTransaction()
+	if err != nil {
+		return err
+	}
+	copy(r, rx)
+	return errEnd
+}
+
+//Transfer exchanges a single byte
+func (spi *SPI) Transfer(b byte) (byte, error) {
+	r := make([]byte, 1)
+	err := spi.Tx([]byte{b}, r)
+	return r[0], err
+}
@@ -0,0 +1,245 @@
+//Package machine mirrors the API of TinyGo's machine package on top of a
+//nango Board, so code written to run on a microcontroller can run on the host
+//instead, driving the board over its nango connection. Importing it in place
+//of TinyGo's package is usually the only change needed:
+//
+//	import machine "github.com/justinsantoro/nango/machine"
+//
+//	machine.Use(board)
+//	led := machine.D13
+//	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+//	led.High()
+//
+//TinyGo's pin methods don't return errors. Errors from such calls are passed
+//to OnError.
+package machine
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/justinsantoro/nango"
+)
+
+var (
+	mu    sync.Mutex
+	board *nango.Board
+)
+
+//OnError is called with the errors of calls which can't return them. It logs
+//them by default.
+var OnError = func(err error) {
+	log.Printf("machine: %s", err)
+}
+
+//Use makes b the board the package's pins and buses drive
+func Use(b *nango.Board) {
+	mu.Lock()
+	defer mu.Unlock()
+	board = b
+}
+
+func current() *nango.Board {
+	mu.Lock()
+	defer mu.Unlock()
+	if board == nil {
+		panic("machine: no board: call Use first")
+	}
+	return board
+}
+
+func report(err error) {
+	if err != nil {
+		OnError(err)
+	}
+}
+
+//Pin is a digital or analog pin
+type Pin uint8
+
+//Digital pins D0 to D53 are numbered from 0 and analog pins A0 to A15 from 54
+const (
+	D0 Pin = iota
+	D1
+	D2
+	D3
+	D4
+	D5
+	D6
+	D7
+	D8
+	D9
+	D10
+	D11
+	D12
+	D13
+	D14
+	D15
+	D16
+	D17
+	D18
+	D19
+	D20
+	D21
+	D22
+	D23
+	D24
+	D25
+	D26
+	D27
+	D28
+	D29
+	D30
+	D31
+	D32
+	D33
+	D34
+	D35
+	D36
+	D37
+	D38
+	D39
+	D40
+	D41
+	D42
+	D43
+	D44
+	D45
+	D46
+	D47
+	D48
+	D49
+	D50
+	D51
+	D52
+	D53
+)
+
+const (
+	A0 Pin = analogBase + iota
+	A1
+	A2
+	A3
+	A4
+	A5
+	A6
+	A7
+	A8
+	A9
+	A10
+	A11
+	A12
+	A13
+	A14
+	A15
+)
+
+const (
+	analogBase = 54
+	//NoPin is an unconnected pin. Calls on it are ignored.
+	NoPin Pin = 0xff
+	//LED is the builtin LED of most boards
+	LED = D13
+)
+
+//Name returns the nango name of the pin, e.g. D13 or A0, or NoPin
+func (p Pin) Name() string {
+	if p == NoPin {
+		return "NoPin"
+	}
+	if p >= analogBase {
+		return fmt.Sprintf("A%d", p-analogBase)
+	}
+	return fmt.Sprintf("D%d", p)
+}
+
+type PinMode uint8
+
+const (
+	PinInput PinMode = iota
+	PinOutput
+	PinInputPullup
+)
+
+//PinConfig holds the configuration of a pin
+type PinConfig struct {
+	Mode PinMode
+}
+
+var pinModes = map[PinMode]int{
+	PinInput:       nango.PinInput,
+	PinOutput:      nango.PinOutput,
+	PinInputPullup: nango.PinInputPullup,
+}
+
+//Configure sets the mode of the pin
+func (p Pin) Configure(config PinConfig) {
+	if p == NoPin {
+		return
+	}
+	report(current().Pins().PinMode(p.Name(), pinModes[config.Mode]))
+}
+
+//Set drives an output pin high or low
+func (p Pin) Set(high bool) {
+	if p == NoPin {
+		return
+	}
+	v := nango.PinLow
+	if high {
+		v = nango.PinHigh
+	}
+	report(current().Pins().DigitalWrite(p.Name(), v))
+}
+
+func (p Pin) High() {
+	p.Set(true)
+}
+
+func (p Pin) Low() {
+	p.Set(false)
+}
+
+//Get reads the level of the pin
+func (p Pin) Get() bool {
+	if p == NoPin {
+		return false
+	}
+	v, err := current().Pins().DigitalRead(p.Name())
+	if err != nil {
+		report(err)
+		return false
+	}
+	return v == nango.PinHigh
+}
+
+//ADCConfig holds the configuration of an ADC. Its fields are ignored as the
+//resolution and reference are fixed by the firmware.
+type ADCConfig struct {
+	Reference  uint32
+	Resolution uint32
+	Samples    uint32
+}
+
+//ADC reads an analog pin
+type ADC struct {
+	Pin Pin
+}
+
+func (a ADC) Configure(config ADCConfig) {}
+
+//Get returns the voltage of the pin scaled to 16 bits, as on TinyGo, or 0 if
+//it can't be read
+func (a ADC) Get() uint16 {
+	if a.Pin == NoPin {
+		return 0
+	}
+	v, err := current().Pins().AnalogRead(a.Pin.Name())
+	if err != nil {
+		report(err)
+		return 0
+	}
+	//scale the 10 bit reading of AVR boards to the full 16 bit range
+	return uint16(v) << 6
+}
@@ -0,0 +1,92 @@
+package machine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//useLoopback makes a board on a loopback connection the current board and
+//records the calls made on it
+func useLoopback(t *testing.T) (*nango.Loopback, func() []nango.LoopbackCall) {
+	t.Helper()
+	lb := nango.NewLoopback()
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	Use(nango.NewBoard(conn))
+	var mu sync.Mutex
+	var calls []nango.LoopbackCall
+	record := func(response string) nango.LoopbackHandler {
+		return func(c nango.LoopbackCall) (string, bool) {
+			mu.Lock()
+			calls = append(calls, c)
+			mu.Unlock()
+			return response, true
+		}
+	}
+	lb.Handle(nango.NamespaceArduino, nango.MethodPinMode, record("0"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodDigitalWrite, record("0"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodDigitalRead, record("1"))
+	lb.Handle(nango.NamespaceArduino, nango.MethodAnalogRead, record("1023"))
+	return lb, func() []nango.LoopbackCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]nango.LoopbackCall(nil), calls...)
+	}
+}
+
+func TestPinName(t *testing.T) {
+	for p, name := range map[Pin]string{D0: "D0", D13: "D13", D53: "D53", A0: "A0", A15: "A15", NoPin: "NoPin"} {
+		if p.Name() != name {
+			t.Errorf("expected %s, got %s", name, p.Name())
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	_, calls := useLoopback(t)
+	LED.Configure(PinConfig{Mode: PinOutput})
+	LED.High()
+	if !D2.Get() {
+		t.Fatal("expected D2 high")
+	}
+	got := calls()
+	if len(got) != 3 || got[0].Method != nango.MethodPinMode || got[0].Args[0] != "D13" ||
+		got[1].Method != nango.MethodDigitalWrite || got[1].Args[1] != "1" || got[2].Args[0] != "D2" {
+		t.Fatalf("unexpected calls %+v", got)
+	}
+
+	//calls on NoPin are ignored
+	NoPin.Configure(PinConfig{Mode: PinOutput})
+	NoPin.High()
+	if NoPin.Get() || (ADC{Pin: NoPin}).Get() != 0 {
+		t.Fatal("expected NoPin to read low")
+	}
+	if n := len(calls()); n != 3 {
+		t.Fatalf("expected no calls made on NoPin, got %d", n-3)
+	}
+}
+
+func TestADC(t *testing.T) {
+	lb, _ := useLoopback(t)
+	if v := (ADC{Pin: A0}).Get(); v != 0xffc0 {
+		t.Fatalf("expected the reading scaled to 16 bits, got %#x", v)
+	}
+
+	var errs []error
+	onError := OnError
+	OnError = func(err error) { errs = append(errs, err) }
+	defer func() { OnError = onError }()
+	lb.Respond(nango.NamespaceArduino, nango.MethodAnalogRead, "!ERR bad pin\tpin < 16\t12")
+	if v := (ADC{Pin: A1}).Get(); v != 0 {
+		t.Fatalf("expected 0 on error, got %#x", v)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected the error reported, got %v", errs)
+	}
+}
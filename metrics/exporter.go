@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//Exporter publishes the latest readings of pins and devices as gauges,
+//turning a board into a sensor node Prometheus can scrape. Unlike Collector,
+//which measures the client, it exports what the board measures.
+//
+//	e := metrics.NewExporter()
+//	e.Attach(poller)
+//	http.Handle("/metrics", e.Handler())
+type Exporter struct {
+	value  *prometheus.Desc
+	time   *prometheus.Desc
+	errors *prometheus.Desc
+
+	mu       sync.Mutex
+	readings map[string]*exportedReading
+}
+
+type exportedReading struct {
+	value  float64
+	time   time.Time
+	errors int
+}
+
+//NewExporter returns an empty Exporter
+func NewExporter() *Exporter {
+	labels := []string{"source"}
+	return &Exporter{
+		value: prometheus.NewDesc("nango_reading",
+			"Latest value read from a pin or device.", labels, nil),
+		time: prometheus.NewDesc("nango_reading_timestamp_seconds",
+			"Unix time of the latest successful reading.", labels, nil),
+		errors: prometheus.NewDesc("nango_reading_errors_total",
+			"Number of failed readings.", labels, nil),
+		readings: make(map[string]*exportedReading),
+	}
+}
+
+//Attach exports the readings published by p
+func (e *Exporter) Attach(p *nango.Poller) (detach func()) {
+	return p.Subscribe(e.Observe)
+}
+
+//Observe records r as the latest reading of its source
+func (e *Exporter) Observe(r nango.Reading) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	er, ok := e.readings[r.Source]
+	if !ok {
+		er = &exportedReading{}
+		e.readings[r.Source] = er
+	}
+	if r.Err != nil {
+		er.errors++
+		return
+	}
+	er.value = r.Value
+	er.time = r.Time
+}
+
+//Handler returns a handler serving the exported readings in the Prometheus
+//exposition format, independently of the default registry
+func (e *Exporter) Handler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(e)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+//Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.value
+	ch <- e.time
+	ch <- e.errors
+}
+
+//Collect implements prometheus.Collector
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for source, r := range e.readings {
+		if !r.time.IsZero() {
+			ch <- prometheus.MustNewConstMetric(e.value, prometheus.GaugeValue, r.value, source)
+			ch <- prometheus.MustNewConstMetric(e.time, prometheus.GaugeValue, float64(r.time.UnixNano())/1e9, source)
+		}
+		ch <- prometheus.MustNewConstMetric(e.errors, prometheus.CounterValue, float64(r.errors), source)
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+func TestExporter(t *testing.T) {
+	e := NewExporter()
+	at := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	e.Observe(nango.Reading{Source: "A0", Value: 512, Time: at})
+	e.Observe(nango.Reading{Source: "A0", Err: errors.New("timeout"), Time: at.Add(time.Second)})
+	e.Observe(nango.Reading{Source: "A0", Value: 0.5, Time: at.Add(2 * time.Second)})
+	//a source which never read successfully only exports its errors
+	e.Observe(nango.Reading{Source: "bme280", Err: errors.New("nack"), Time: at})
+
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	b, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(b)
+	for _, line := range []string{
+		`nango_reading{source="A0"} 0.5`,
+		`nango_reading_timestamp_seconds{source="A0"} 1.622505602e+09`,
+		`nango_reading_errors_total{source="A0"} 1`,
+		`nango_reading_errors_total{source="bme280"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `nango_reading{source="bme280"}`) {
+		t.Errorf("expected no value exported for a source without readings\n%s", body)
+	}
+}
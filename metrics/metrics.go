@@ -0,0 +1,85 @@
+//Package metrics exports nango client metrics as Prometheus collectors.
+//
+//	c := metrics.NewCollector()
+//	prometheus.MustRegister(c)
+//	conn.Observer = c
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//Collector records the calls made on one or more connections. It implements
+//both nango.CallObserver and prometheus.Collector.
+type Collector struct {
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	timeouts *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+//NewCollector returns a Collector using the default latency buckets
+func NewCollector() *Collector {
+	return NewCollectorWithBuckets([]float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5})
+}
+
+//NewCollectorWithBuckets returns a Collector using the given latency histogram
+//buckets in seconds
+func NewCollectorWithBuckets(buckets []float64) *Collector {
+	labels := []string{"namespace", "method"}
+	return &Collector{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nango",
+			Name:      "calls_total",
+			Help:      "Number of firmware method calls made.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nango",
+			Name:      "call_errors_total",
+			Help:      "Number of firmware method calls which returned an error, including timeouts.",
+		}, labels),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nango",
+			Name:      "call_timeouts_total",
+			Help:      "Number of firmware method calls which timed out waiting for a response.",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "nango",
+			Name:      "call_duration_seconds",
+			Help:      "Round trip time of firmware method calls.",
+			Buckets:   buckets,
+		}, labels),
+	}
+}
+
+//ObserveCall implements nango.CallObserver
+func (c *Collector) ObserveCall(namespace string, method string, elapsed time.Duration, err error) {
+	c.calls.WithLabelValues(namespace, method).Inc()
+	c.latency.WithLabelValues(namespace, method).Observe(elapsed.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(namespace, method).Inc()
+		if errors.Is(err, nango.ErrTimeout) {
+			c.timeouts.WithLabelValues(namespace, method).Inc()
+		}
+	}
+}
+
+//Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.calls.Describe(ch)
+	c.errors.Describe(ch)
+	c.timeouts.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+//Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.calls.Collect(ch)
+	c.errors.Collect(ch)
+	c.timeouts.Collect(ch)
+	c.latency.Collect(ch)
+}
@@ -0,0 +1,109 @@
+package nango
+
+import (
+	"context"
+	"sync"
+)
+
+//CallInfo describes a call made on a FirmwareConnection
+type CallInfo struct {
+	Namespace string
+	Id        int
+	Method    string
+	Args      []interface{}
+	Priority  int
+	//Key, if not empty, replaces calls with the same key still waiting to
+	//be sent
+	Key string
+	//Node is the node of a multi-drop bus the call is addressed to, nil for
+	//a connection to a single board
+	Node *Node
+	//sleep, if set, is the sleep window the call starts once acknowledged
+	sleep *sleepWindow
+}
+
+//CallFunc makes a call, returning the response in a pooled buffer the caller
+//must release
+type CallFunc func(ctx context.Context, c *CallInfo) (*Buffer, error)
+
+//Middleware wraps every call made on a connection, e.g. to log, meter, retry,
+//rate limit or authorize it. It may inspect or modify the call before passing
+//it to next, and the response or error next returns, or not call next at all.
+type Middleware func(next CallFunc) CallFunc
+
+//WithMiddleware wraps every call made on the connection in mw. The first
+//middleware is the outermost, and all of them run outside the connection's
+//tracing and retries. Each call of a Pipeline, Batch or Transaction passes
+//through the middleware on its own; those reaching the end of the chain are
+//then sent together.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(s *FirmwareConnection) {
+		s.middleware = append(s.middleware, mw...)
+	}
+}
+
+//chain returns the connection's middleware wrapped around endOfChain, or nil
+//if it has none
+func (s *FirmwareConnection) chain() CallFunc {
+	if len(s.middleware) == 0 {
+		return nil
+	}
+	next := CallFunc(s.endOfChain)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		next = s.middleware[i](next)
+	}
+	return next
+}
+
+//handle makes a call through the connection's middleware
+func (s *FirmwareConnection) handle(ctx context.Context, c *CallInfo) (*Buffer, error) {
+	if s.handler == nil {
+		return s.invoke(ctx, c)
+	}
+	return s.handler(ctx, c)
+}
+
+//endOfChain makes a call which has passed through every middleware. The
+//first call to reach it for a slot of a pipeline joins the pipeline's
+//transfer, any others are made on their own.
+func (s *FirmwareConnection) endOfChain(ctx context.Context, c *CallInfo) (*Buffer, error) {
+	if slot, ok := ctx.Value(pipelineSlotKey{}).(*pipelineSlot); ok && slot.claim(c) {
+		r := <-slot.result
+		return r.value, r.err
+	}
+	return s.invoke(ctx, c)
+}
+
+//pipelineSlotKey is the context key of the pipelineSlot of a call
+type pipelineSlotKey struct{}
+
+//pipelineSlot connects a call of a pipeline running through the middleware to
+//the transfer which sends the pipeline. Each slot is reported on reached
+//exactly once: when its call reaches the end of the chain, or when the chain
+//returns without it having done so.
+type pipelineSlot struct {
+	once    sync.Once
+	call    *CallInfo //the call as it reached the end of the chain, if it did
+	reached chan<- *pipelineSlot
+	result  chan callResult
+}
+
+//claim reports c as having reached the end of the chain, unless the slot has
+//already been reported
+func (sl *pipelineSlot) claim(c *CallInfo) bool {
+	claimed := false
+	sl.once.Do(func() {
+		sl.call = c
+		claimed = true
+		sl.reached <- sl
+	})
+	return claimed
+}
+
+//release reports the slot if the chain returned without its call reaching
+//the end
+func (sl *pipelineSlot) release() {
+	sl.once.Do(func() {
+		sl.reached <- sl
+	})
+}
@@ -0,0 +1,217 @@
+package nango
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	modbusReadCoils              = 0x01
+	modbusReadHoldingRegisters   = 0x03
+	modbusWriteSingleCoil        = 0x05
+	modbusWriteSingleRegister    = 0x06
+	modbusWriteMultipleCoils     = 0x0F
+	modbusWriteMultipleRegisters = 0x10
+)
+
+//the most coils or registers a single request may address, so that the
+//request and response fit in a 256 byte frame
+const (
+	modbusMaxReadCoils      = 2000
+	modbusMaxReadRegisters  = 125
+	modbusMaxWriteCoils     = 1968
+	modbusMaxWriteRegisters = 123
+)
+
+//ModbusException is returned when a slave answers a request with an exception response
+type ModbusException struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ModbusException) Error() string {
+	return fmt.Sprintf("modbus exception %d on function 0x%02x", e.Code, e.Function)
+}
+
+//ModbusCRC16 computes the CRC used to terminate Modbus RTU frames
+func ModbusCRC16(b []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+//ModbusRTU is a Modbus RTU master speaking through a UART attached to the
+//arduino, typically via an RS-485 transceiver
+type ModbusRTU struct {
+	port io.ReadWriter
+	mu   sync.Mutex
+}
+
+func NewModbusRTU(port AttachedSerial) *ModbusRTU {
+	return &ModbusRTU{port: port}
+}
+
+//ReadCoils reads count coils starting at addr
+func (m *ModbusRTU) ReadCoils(slave byte, addr uint16, count uint16) ([]bool, error) {
+	if err := modbusCheckCount(modbusReadCoils, int(count), modbusMaxReadCoils); err != nil {
+		return nil, err
+	}
+	data, err := m.transact(slave, modbusReadCoils, u16s(addr, count))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < int(count+7)/8 {
+		return nil, modbusShortResponse(modbusReadCoils)
+	}
+	coils := make([]bool, count)
+	for i := range coils {
+		coils[i] = data[i/8]&(1<<uint(i%8)) != 0
+	}
+	return coils, nil
+}
+
+//ReadHoldingRegisters reads count holding registers starting at addr
+func (m *ModbusRTU) ReadHoldingRegisters(slave byte, addr uint16, count uint16) ([]uint16, error) {
+	if err := modbusCheckCount(modbusReadHoldingRegisters, int(count), modbusMaxReadRegisters); err != nil {
+		return nil, err
+	}
+	data, err := m.transact(slave, modbusReadHoldingRegisters, u16s(addr, count))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < int(count)*2 {
+		return nil, modbusShortResponse(modbusReadHoldingRegisters)
+	}
+	regs := make([]uint16, count)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	return regs, nil
+}
+
+//WriteSingleCoil turns the coil at addr on or off
+func (m *ModbusRTU) WriteSingleCoil(slave byte, addr uint16, on bool) error {
+	var v uint16
+	if on {
+		v = 0xFF00
+	}
+	_, err := m.transact(slave, modbusWriteSingleCoil, u16s(addr, v))
+	return err
+}
+
+//WriteMultipleCoils sets consecutive coils starting at addr
+func (m *ModbusRTU) WriteMultipleCoils(slave byte, addr uint16, values []bool) error {
+	if err := modbusCheckCount(modbusWriteMultipleCoils, len(values), modbusMaxWriteCoils); err != nil {
+		return err
+	}
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+	req := append(u16s(addr, uint16(len(values))), byte(len(packed)))
+	_, err := m.transact(slave, modbusWriteMultipleCoils, append(req, packed...))
+	return err
+}
+
+//WriteSingleRegister writes value to the holding register at addr
+func (m *ModbusRTU) WriteSingleRegister(slave byte, addr uint16, value uint16) error {
+	_, err := m.transact(slave, modbusWriteSingleRegister, u16s(addr, value))
+	return err
+}
+
+//WriteMultipleRegisters writes consecutive holding registers starting at addr
+func (m *ModbusRTU) WriteMultipleRegisters(slave byte, addr uint16, values []uint16) error {
+	if err := modbusCheckCount(modbusWriteMultipleRegisters, len(values), modbusMaxWriteRegisters); err != nil {
+		return err
+	}
+	req := append(u16s(addr, uint16(len(values))), byte(len(values)*2))
+	_, err := m.transact(slave, modbusWriteMultipleRegisters, append(req, u16s(values...)...))
+	return err
+}
+
+//transact sends a request frame and returns the data portion of the response
+//(after the byte count for read functions)
+func (m *ModbusRTU) transact(slave byte, function byte, data []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	frame := append([]byte{slave, function}, data...)
+	crc := ModbusCRC16(frame)
+	frame = append(frame, byte(crc), byte(crc>>8))
+	_, err := m.port.Write(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 3)
+	_, err = io.ReadFull(m.port, header)
+	if err != nil {
+		return nil, err
+	}
+	var rest int
+	switch {
+	case header[1] == function|0x80:
+		//exception code already read, only the crc remains
+		rest = 2
+	case header[1] != function:
+		return nil, fmt.Errorf("modbus: expected response to function 0x%02x but received 0x%02x", function, header[1])
+	case function == modbusReadCoils || function == modbusReadHoldingRegisters:
+		rest = int(header[2]) + 2
+	default:
+		//remainder of the echoed address and value or quantity, then the crc
+		rest = 3 + 2
+	}
+	resp := make([]byte, len(header)+rest)
+	copy(resp, header)
+	_, err = io.ReadFull(m.port, resp[len(header):])
+	if err != nil {
+		return nil, err
+	}
+	body := resp[:len(resp)-2]
+	if crc := ModbusCRC16(body); uint16(resp[len(resp)-2])|uint16(resp[len(resp)-1])<<8 != crc {
+		return nil, fmt.Errorf("modbus: bad crc in response to function 0x%02x", function)
+	}
+	if body[0] != slave {
+		return nil, fmt.Errorf("modbus: expected response from slave %d but received %d", slave, body[0])
+	}
+	if header[1] == function|0x80 {
+		return nil, &ModbusException{Function: function, Code: header[2]}
+	}
+	if function == modbusReadCoils || function == modbusReadHoldingRegisters {
+		return body[3:], nil
+	}
+	return body[2:], nil
+}
+
+func u16s(vs ...uint16) []byte {
+	b := make([]byte, len(vs)*2)
+	for i, v := range vs {
+		binary.BigEndian.PutUint16(b[i*2:], v)
+	}
+	return b
+}
+
+//modbusCheckCount checks a request for function addresses between 1 and max
+//coils or registers
+func modbusCheckCount(function byte, count int, max int) error {
+	if count < 1 || count > max {
+		return fmt.Errorf("modbus: function 0x%02x can't address %d items, the limit is 1 to %d", function, count, max)
+	}
+	return nil
+}
+
+func modbusShortResponse(function byte) error {
+	return fmt.Errorf("modbus: short response to function 0x%02x", function)
+}
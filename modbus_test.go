@@ -0,0 +1,80 @@
+package nango
+
+import (
+	"bytes"
+	"testing"
+)
+
+//fakeSerial records writes and replays a canned response
+type fakeSerial struct {
+	written  bytes.Buffer
+	response *bytes.Reader
+}
+
+func (f *fakeSerial) Begin(baud int) error        { return nil }
+func (f *fakeSerial) Available() (int, error)     { return f.response.Len(), nil }
+func (f *fakeSerial) Read(b []byte) (int, error)  { return f.response.Read(b) }
+func (f *fakeSerial) Write(b []byte) (int, error) { return f.written.Write(b) }
+func withCRC(b ...byte) []byte {
+	crc := ModbusCRC16(b)
+	return append(b, byte(crc), byte(crc>>8))
+}
+
+func TestModbusCRC16(t *testing.T) {
+	crc := ModbusCRC16([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A})
+	if crc != 0xCDC5 {
+		t.Fatalf("expected crc 0xCDC5, got 0x%04X", crc)
+	}
+}
+
+func TestModbusReadHoldingRegisters(t *testing.T) {
+	port := &fakeSerial{response: bytes.NewReader(withCRC(0x11, 0x03, 0x04, 0x02, 0x2B, 0x00, 0x64))}
+	regs, err := NewModbusRTU(port).ReadHoldingRegisters(0x11, 0x006B, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regs) != 2 || regs[0] != 0x022B || regs[1] != 0x0064 {
+		t.Fatalf("unexpected registers %v", regs)
+	}
+	if want := withCRC(0x11, 0x03, 0x00, 0x6B, 0x00, 0x02); !bytes.Equal(port.written.Bytes(), want) {
+		t.Fatalf("expected request % x, got % x", want, port.written.Bytes())
+	}
+}
+
+func TestModbusWriteSingleCoil(t *testing.T) {
+	port := &fakeSerial{response: bytes.NewReader(withCRC(0x11, 0x05, 0x00, 0xAC, 0xFF, 0x00))}
+	err := NewModbusRTU(port).WriteSingleCoil(0x11, 0x00AC, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModbusException(t *testing.T) {
+	port := &fakeSerial{response: bytes.NewReader(withCRC(0x11, 0x83, 0x02))}
+	_, err := NewModbusRTU(port).ReadHoldingRegisters(0x11, 0x006B, 2)
+	e, ok := err.(*ModbusException)
+	if !ok || e.Code != 2 || e.Function != 0x03 {
+		t.Fatalf("expected modbus exception 2, got %v", err)
+	}
+}
+
+func TestModbusCountLimits(t *testing.T) {
+	port := &fakeSerial{response: bytes.NewReader(nil)}
+	m := NewModbusRTU(port)
+	for name, err := range map[string]error{
+		"read no coils":       func() error { _, err := m.ReadCoils(1, 0, 0); return err }(),
+		"read 2001 coils":     func() error { _, err := m.ReadCoils(1, 0, 2001); return err }(),
+		"read 126 registers":  func() error { _, err := m.ReadHoldingRegisters(1, 0, 126); return err }(),
+		"write no coils":      m.WriteMultipleCoils(1, 0, nil),
+		"write 1969 coils":    m.WriteMultipleCoils(1, 0, make([]bool, 1969)),
+		"write no registers":  m.WriteMultipleRegisters(1, 0, nil),
+		"write 124 registers": m.WriteMultipleRegisters(1, 0, make([]uint16, 124)),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if port.written.Len() != 0 {
+		t.Fatalf("expected no request sent, got % x", port.written.Bytes())
+	}
+}
@@ -0,0 +1,184 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+//SoftLimitError is returned for moves beyond a StepperMotion's travel limits
+type SoftLimitError struct {
+	Target int
+	Min    int
+	Max    int
+}
+
+func (e *SoftLimitError) Error() string {
+	return fmt.Sprintf("stepper move to %d outside soft limits %d to %d", e.Target, e.Min, e.Max)
+}
+
+//ErrHomingFailed is returned by Home if the limit switch isn't reached
+var ErrHomingFailed = errors.New("stepper homing: limit switch not reached")
+
+//StepperMotion positions a Stepper, for camera sliders and small CNC
+//gadgets. Moves are made in chunks of steps, each at a speed following a
+//trapezoidal profile, so they accelerate and decelerate and can be stopped
+//between chunks by cancelling their context.
+type StepperMotion struct {
+	Stepper     *Stepper
+	StepsPerRev int
+	//MinRPM is the speed moves start and end at, and homing runs at
+	MinRPM float64
+	MaxRPM float64
+	//Accel is the acceleration in revolutions per second squared. Zero moves
+	//at MaxRPM throughout.
+	Accel float64
+	//Chunk is the number of steps sent per call, trading the smoothness of
+	//acceleration and responsiveness to cancellation against call overhead
+	Chunk int
+	//Min and Max are the soft limits of travel in steps. They are disabled
+	//if Min is not less than Max.
+	Min int
+	Max int
+	//Api reads LimitPin, which Home drives towards until it reads
+	//LimitActive
+	Api         *ArduinoApi
+	LimitPin    string
+	LimitActive int
+
+	mu       sync.Mutex
+	position int
+	rpm      int
+}
+
+//NewStepperMotion returns a StepperMotion at position 0, with no limits,
+//moving between 10 and 60 RPM in chunks of 20 steps
+func NewStepperMotion(s *Stepper, stepsPerRev int) *StepperMotion {
+	return &StepperMotion{
+		Stepper:     s,
+		StepsPerRev: stepsPerRev,
+		MinRPM:      10,
+		MaxRPM:      60,
+		Chunk:       20,
+	}
+}
+
+//Position returns the position in steps
+func (m *StepperMotion) Position() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.position
+}
+
+//SetPosition redefines the current position, e.g. after homing by hand
+func (m *StepperMotion) SetPosition(pos int) {
+	m.mu.Lock()
+	m.position = pos
+	m.mu.Unlock()
+}
+
+//checkLimits returns a SoftLimitError if target is outside the limits
+func (m *StepperMotion) checkLimits(target int) error {
+	if m.Min < m.Max && (target < m.Min || target > m.Max) {
+		return &SoftLimitError{Target: target, Min: m.Min, Max: m.Max}
+	}
+	return nil
+}
+
+//setSpeed sets the stepper's speed if it changed
+func (m *StepperMotion) setSpeed(rpm float64) error {
+	r := int(math.Max(1, math.Round(rpm)))
+	if r == m.rpm {
+		return nil
+	}
+	if err := m.Stepper.SetSpeed(r); err != nil {
+		return err
+	}
+	m.rpm = r
+	return nil
+}
+
+//rpmAt returns the speed of a move at the point where moved steps have been
+//made and remaining steps are left
+func (m *StepperMotion) rpmAt(moved int, remaining int) float64 {
+	if m.Accel <= 0 {
+		return m.MaxRPM
+	}
+	//v² = v0² + 2as, in revolutions and seconds
+	v0 := m.MinRPM / 60
+	a := m.Accel
+	steps := float64(m.StepsPerRev)
+	up := math.Sqrt(v0*v0 + 2*a*float64(moved)/steps)
+	down := math.Sqrt(v0*v0 + 2*a*float64(remaining)/steps)
+	return math.Min(m.MaxRPM, math.Min(up, down)*60)
+}
+
+//MoveTo moves to the absolute position target in steps. If ctx is cancelled
+//the move stops after the chunk in progress and ctx's error is returned.
+func (m *StepperMotion) MoveTo(ctx context.Context, target int) error {
+	if err := m.checkLimits(target); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir := 1
+	total := target - m.position
+	if total < 0 {
+		dir, total = -1, -total
+	}
+	for moved := 0; moved < total; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := total - moved
+		if n > m.Chunk {
+			n = m.Chunk
+		}
+		if err := m.setSpeed(m.rpmAt(moved, total-moved)); err != nil {
+			return err
+		}
+		if err := m.Stepper.Step(dir * n); err != nil {
+			return err
+		}
+		moved += n
+		m.position += dir * n
+	}
+	return nil
+}
+
+//Jog moves by steps relative to the current position, within the limits
+func (m *StepperMotion) Jog(ctx context.Context, steps int) error {
+	return m.MoveTo(ctx, m.Position()+steps)
+}
+
+//Home moves backwards at MinRPM until the limit switch is reached, at most
+//maxSteps, and sets the position there to 0. Soft limits don't apply while
+//homing.
+func (m *StepperMotion) Home(ctx context.Context, maxSteps int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.setSpeed(m.MinRPM); err != nil {
+		return err
+	}
+	for moved := 0; ; moved += m.Chunk {
+		v, err := m.Api.DigitalRead(m.LimitPin)
+		if err != nil {
+			return err
+		}
+		if v == m.LimitActive {
+			m.position = 0
+			return nil
+		}
+		if moved >= maxSteps {
+			return ErrHomingFailed
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := m.Stepper.Step(-m.Chunk); err != nil {
+			return err
+		}
+	}
+}
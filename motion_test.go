@@ -0,0 +1,79 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestStepperMotion(t *testing.T) {
+	lb, conn := openLoopback(t)
+	lb.Respond(NamespaceStepper, MethodNew, "1")
+	var steps []int
+	var speeds []int
+	lb.Handle(NamespaceStepper, "step", func(c LoopbackCall) (string, bool) {
+		n, _ := strconv.Atoi(c.Args[0])
+		steps = append(steps, n)
+		return "", true
+	})
+	lb.Handle(NamespaceStepper, "setSpeed", func(c LoopbackCall) (string, bool) {
+		n, _ := strconv.Atoi(c.Args[0])
+		speeds = append(speeds, n)
+		return "", true
+	})
+	reads := 0
+	lb.Handle(NamespaceArduino, MethodDigitalRead, func(c LoopbackCall) (string, bool) {
+		reads++
+		if reads > 3 {
+			return "0", true
+		}
+		return "1", true
+	})
+	s, err := NewStepper(conn, 200, "D8", "D9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewStepperMotion(s, 200)
+	m.Accel = 1
+	m.Min, m.Max = 0, 1000
+	m.Api, m.LimitPin, m.LimitActive = NewArduinoApi(conn), "D2", 0
+
+	if err := m.Home(context.Background(), 1000); err != nil || m.Position() != 0 {
+		t.Fatalf("homing failed at %d (%v)", m.Position(), err)
+	}
+	if len(steps) != 3 || steps[0] != -20 {
+		t.Fatalf("expected 3 homing chunks, got %v", steps)
+	}
+
+	steps, speeds = nil, nil
+	if err := m.MoveTo(context.Background(), 500); err != nil || m.Position() != 500 {
+		t.Fatalf("move ended at %d (%v)", m.Position(), err)
+	}
+	sum := 0
+	for _, n := range steps {
+		sum += n
+	}
+	if sum != 500 {
+		t.Fatalf("expected 500 steps, got %d", sum)
+	}
+	peak := 0
+	for _, v := range speeds {
+		if v > peak {
+			peak = v
+		}
+	}
+	if speeds[0] >= peak || speeds[len(speeds)-1] >= peak {
+		t.Fatalf("expected acceleration and deceleration, got speeds %v", speeds)
+	}
+
+	var limit *SoftLimitError
+	if err := m.Jog(context.Background(), 600); !errors.As(err, &limit) || m.Position() != 500 {
+		t.Fatalf("expected soft limit error, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.MoveTo(ctx, 0); err != context.Canceled || m.Position() != 500 {
+		t.Fatalf("expected cancelled move, got %v", err)
+	}
+}
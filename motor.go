@@ -0,0 +1,138 @@
+package nango
+
+import (
+	"math"
+)
+
+//DCMotor drives a DC motor through an H-bridge such as an L298N or TB6612,
+//controlled by two direction pins and a PWM enable pin
+type DCMotor struct {
+	api    *ArduinoApi
+	in1    string
+	in2    string
+	enable string
+}
+
+//NewDCMotor returns a coasting motor on the driver's direction pins in1 and
+//in2 and its PWM enable pin
+func NewDCMotor(api *ArduinoApi, in1 string, in2 string, enable string) (*DCMotor, error) {
+	m := &DCMotor{api: api, in1: in1, in2: in2, enable: enable}
+	for _, pin := range []string{in1, in2, enable} {
+		if err := api.PinMode(pin, PinOutput); err != nil {
+			return nil, err
+		}
+	}
+	return m, m.Coast()
+}
+
+func (m *DCMotor) set(in1 int, in2 int, duty int) error {
+	//disable the bridge while changing direction to avoid shoot-through
+	if err := m.api.AnalogWrite(m.enable, 0); err != nil {
+		return err
+	}
+	if err := m.api.DigitalWrite(m.in1, in1); err != nil {
+		return err
+	}
+	if err := m.api.DigitalWrite(m.in2, in2); err != nil {
+		return err
+	}
+	return m.api.AnalogWrite(m.enable, duty)
+}
+
+//duty converts a speed from 0 to 1 to a PWM duty cycle
+func duty(speed float64) int {
+	return int(clamp(math.Abs(speed), 0, 1)*255 + 0.5)
+}
+
+//Forward turns the motor forwards at speed, from 0 to 1
+func (m *DCMotor) Forward(speed float64) error {
+	return m.set(PinHigh, PinLow, duty(speed))
+}
+
+//Reverse turns the motor backwards at speed, from 0 to 1
+func (m *DCMotor) Reverse(speed float64) error {
+	return m.set(PinLow, PinHigh, duty(speed))
+}
+
+//SetSpeed turns the motor at speed from -1, full reverse, to 1, full
+//forward. Zero coasts.
+func (m *DCMotor) SetSpeed(speed float64) error {
+	switch {
+	case speed > 0:
+		return m.Forward(speed)
+	case speed < 0:
+		return m.Reverse(-speed)
+	}
+	return m.Coast()
+}
+
+//Brake shorts the motor's terminals, stopping it quickly
+func (m *DCMotor) Brake() error {
+	return m.set(PinHigh, PinHigh, 255)
+}
+
+//Coast disconnects the motor, letting it spin down freely
+func (m *DCMotor) Coast() error {
+	return m.set(PinLow, PinLow, 0)
+}
+
+//DifferentialDrive steers a vehicle with a motor on each side
+type DifferentialDrive struct {
+	Left  *DCMotor
+	Right *DCMotor
+	//TrackWidth is the distance between the wheels
+	TrackWidth float64
+	//MaxSpeed is the ground speed of a wheel at full duty cycle, in units of
+	//TrackWidth per second
+	MaxSpeed float64
+}
+
+//Drive moves at speed while turning at rate radians per second, positive
+//counter-clockwise. Speeds beyond what the motors can reach are scaled down
+//preserving the radius of the turn.
+func (d *DifferentialDrive) Drive(speed float64, rate float64) error {
+	l, r := d.wheelSpeeds(speed, rate)
+	return d.Tank(l, r)
+}
+
+//wheelSpeeds returns the duty cycles, from -1 to 1, of the wheels for Drive
+func (d *DifferentialDrive) wheelSpeeds(speed float64, rate float64) (left float64, right float64) {
+	left = (speed - rate*d.TrackWidth/2) / d.MaxSpeed
+	right = (speed + rate*d.TrackWidth/2) / d.MaxSpeed
+	if max := math.Max(math.Abs(left), math.Abs(right)); max > 1 {
+		left /= max
+		right /= max
+	}
+	return
+}
+
+//Arc drives at speed along a circle of radius, positive turning
+//counter-clockwise
+func (d *DifferentialDrive) Arc(speed float64, radius float64) error {
+	if radius == 0 {
+		return d.Turn(speed / (d.TrackWidth / 2))
+	}
+	return d.Drive(speed, speed/radius)
+}
+
+//Turn spins in place at rate radians per second, positive counter-clockwise
+func (d *DifferentialDrive) Turn(rate float64) error {
+	return d.Drive(0, rate)
+}
+
+//Tank sets the speed of each side directly, from -1 to 1
+func (d *DifferentialDrive) Tank(left float64, right float64) error {
+	if err := d.Left.SetSpeed(left); err != nil {
+		return err
+	}
+	return d.Right.SetSpeed(right)
+}
+
+//Stop brakes both motors
+func (d *DifferentialDrive) Stop() error {
+	err := d.Left.Brake()
+	if errRight := d.Right.Brake(); err == nil {
+		err = errRight
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package nango
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDifferentialDriveWheelSpeeds(t *testing.T) {
+	d := &DifferentialDrive{TrackWidth: 0.2, MaxSpeed: 1}
+	for _, c := range []struct {
+		speed, rate, left, right float64
+	}{
+		{0.5, 0, 0.5, 0.5},
+		{0, 5, -0.5, 0.5},
+		{0.5, 2, 0.3, 0.7},
+		//scaled down keeping the ratio of the wheel speeds
+		{1, 5, 0.5 / 1.5, 1},
+	} {
+		l, r := d.wheelSpeeds(c.speed, c.rate)
+		if math.Abs(l-c.left) > 1e-9 || math.Abs(r-c.right) > 1e-9 {
+			t.Errorf("Drive(%v, %v): expected %v,%v got %v,%v", c.speed, c.rate, c.left, c.right, l, r)
+		}
+	}
+}
+
+func TestDCMotor(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var writes []string
+	record := func(c LoopbackCall) (string, bool) {
+		writes = append(writes, c.Method+":"+c.Args[0]+"="+c.Args[1])
+		return "", true
+	}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, record)
+	lb.Handle(NamespaceArduino, MethodAnalogWrite, record)
+	m, err := NewDCMotor(NewArduinoApi(conn), "D7", "D8", "D9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writes = nil
+	if err := m.SetSpeed(-0.5); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aw:D9=0", "dw:D7=0", "dw:D8=1", "aw:D9=128"}
+	if len(writes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, writes)
+	}
+	for i := range want {
+		if writes[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, writes)
+		}
+	}
+}
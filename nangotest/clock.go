@@ -0,0 +1,78 @@
+package nangotest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+var _ nango.Clock = (*Clock)(nil)
+
+//Clock is a fake nango.Clock whose time only moves when advanced, so
+//timeouts, retries and coalescing can be tested without real delays
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+type clockWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+//NewClock returns a Clock set to now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+//After returns a channel which receives the time once the clock has been
+//advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, clockWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+//Sleep blocks until the clock has been advanced by d
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+//Advance moves the clock forward by d, waking every sleeper whose time has
+//come
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiting
+}
+
+//Waiters returns the number of sleepers waiting for the clock to advance,
+//which lets a test wait until the code under test is blocked on the clock
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,30 @@
+package nangotest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Second)
+		close(done)
+	}()
+	for c.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("sleep returned before the clock reached its deadline")
+	default:
+	}
+	c.Advance(500 * time.Millisecond)
+	<-done
+	if got := c.Now().Sub(start); got != time.Second {
+		t.Fatalf("expected clock to have advanced 1s, got %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+package nangotest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Exchange is a request written to the firmware and the response read back,
+//without its line terminator
+type Exchange struct {
+	Request  []byte
+	Response []byte
+}
+
+//Fixture is a recorded conversation with the firmware. Fixtures are stored as
+//text with one Go quoted string per line: requests are prefixed with "> " and
+//responses with "< ". Blank lines and lines starting with # are ignored.
+//
+//	# Wire.endTransmission(true)
+//	> "Wire\x000\x001\x00endTransmission\x00True\x00"
+//	< "0"
+type Fixture []Exchange
+
+//ParseFixture reads a fixture in the text format described by Fixture
+func ParseFixture(r io.Reader) (Fixture, error) {
+	var f Fixture
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(line) < 2 || line[1] != ' ' || line[0] != '>' && line[0] != '<' {
+			return nil, fmt.Errorf("fixture line %d: expected \"> \" or \"< \" prefix", n)
+		}
+		v, err := strconv.Unquote(line[2:])
+		if err != nil {
+			return nil, fmt.Errorf("fixture line %d: %w", n, err)
+		}
+		if line[0] == '>' {
+			f = append(f, Exchange{Request: []byte(v)})
+			continue
+		}
+		if len(f) == 0 || f[len(f)-1].Response != nil {
+			return nil, fmt.Errorf("fixture line %d: response without a request", n)
+		}
+		f[len(f)-1].Response = []byte(v)
+	}
+	return f, s.Err()
+}
+
+//LoadFixture reads the fixture stored at path
+func LoadFixture(path string) (Fixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseFixture(file)
+}
+
+//WriteTo writes the fixture in the text format described by Fixture
+func (f Fixture) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	for _, e := range f {
+		fmt.Fprintf(&b, "> %s\n", strconv.Quote(string(e.Request)))
+		if e.Response != nil {
+			fmt.Fprintf(&b, "< %s\n", strconv.Quote(string(e.Response)))
+		}
+	}
+	return b.WriteTo(w)
+}
+
+//FixtureConn is a nango.Conn which replays a fixture, failing the test as
+//soon as a request differs from the recorded one
+type FixtureConn struct {
+	t       testing.TB
+	mu      sync.Mutex
+	fixture Fixture
+	replies [][]byte
+}
+
+//Replay returns a FixtureConn replaying f
+func Replay(t testing.TB, f Fixture) *FixtureConn {
+	return &FixtureConn{t: t, fixture: f}
+}
+
+//ReplayFile returns a FixtureConn replaying the fixture stored at path
+func ReplayFile(t testing.TB, path string) *FixtureConn {
+	t.Helper()
+	f, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Replay(t, f)
+}
+
+func (c *FixtureConn) Write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.fixture) == 0 {
+		c.t.Errorf("nangotest: unexpected request %q after end of fixture", b)
+		return fmt.Errorf("nangotest: unexpected request %q", b)
+	}
+	e := c.fixture[0]
+	if !bytes.Equal(b, e.Request) {
+		c.t.Errorf("nangotest: request diverged from fixture\nexpected %q\nreceived %q", e.Request, b)
+		return fmt.Errorf("nangotest: unexpected request %q", b)
+	}
+	c.fixture = c.fixture[1:]
+	if e.Response != nil {
+		c.replies = append(c.replies, e.Response)
+	}
+	return nil
+}
+
+func (c *FixtureConn) Flush() error {
+	return nil
+}
+
+func (c *FixtureConn) ReadLine() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.replies) == 0 {
+		return nil, fmt.Errorf("no response recorded: %w", nango.SerialTimeoutError{Port: "nangotest", Op: "ReadLine"})
+	}
+	r := c.replies[0]
+	c.replies = c.replies[1:]
+	return r, nil
+}
+
+//AssertDone fails the test if any recorded requests have not been made
+func (c *FixtureConn) AssertDone() {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.fixture) > 0 {
+		c.t.Errorf("nangotest: %d recorded requests were not made, starting with %q", len(c.fixture), c.fixture[0].Request)
+	}
+}
+
+//Recorder is a nango.Conn which passes calls through to Conn, recording them
+//as a Fixture. Calls made through a Recorder bypass the dispatcher of a
+//FirmwareConnection, so it must not be used concurrently.
+type Recorder struct {
+	Conn    nango.Conn
+	mu      sync.Mutex
+	fixture Fixture
+	//answered is the number of exchanges whose response has been read
+	answered int
+}
+
+//Record returns a Recorder passing calls through to conn
+func Record(conn nango.Conn) *Recorder {
+	return &Recorder{Conn: conn}
+}
+
+func (r *Recorder) Write(b []byte) error {
+	r.mu.Lock()
+	r.fixture = append(r.fixture, Exchange{Request: append([]byte(nil), b...)})
+	r.mu.Unlock()
+	return r.Conn.Write(b)
+}
+
+func (r *Recorder) Flush() error {
+	return r.Conn.Flush()
+}
+
+func (r *Recorder) ReadLine() ([]byte, error) {
+	line, err := r.Conn.ReadLine()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.answered < len(r.fixture) {
+		//a request which failed is recorded without a response
+		if err == nil {
+			r.fixture[r.answered].Response = append([]byte{}, line...)
+		}
+		r.answered++
+	}
+	return line, err
+}
+
+//Fixture returns the conversation recorded so far
+func (r *Recorder) Fixture() Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(Fixture(nil), r.fixture...)
+}
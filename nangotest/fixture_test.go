@@ -0,0 +1,51 @@
+package nangotest
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/justinsantoro/nango"
+)
+
+func TestReplayFile(t *testing.T) {
+	conn := ReplayFile(t, "testdata/i2c_probe.txt")
+	ok, err := nango.NewI2cMaster(nango.NewWire(conn)).Probe(0x68)
+	if err != nil || !ok {
+		t.Fatalf("expected device at 0x68, got %v (%v)", ok, err)
+	}
+	conn.AssertDone()
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	mock := NewConn(t)
+	mock.Expect("A", "r").Return("1")
+	mock.Expect("A", "a").Return("512")
+	rec := Record(mock)
+	api := nango.NewArduinoApi(rec)
+	api.DigitalRead("D2")
+	api.AnalogRead("A0")
+	mock.AssertDone()
+
+	var b bytes.Buffer
+	if _, err := rec.Fixture().WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFixture(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, rec.Fixture()) {
+		t.Fatalf("fixture changed by round trip:\n%q\n%q", f, rec.Fixture())
+	}
+	replay := Replay(t, f)
+	v, err := nango.NewArduinoApi(replay).DigitalRead("D2")
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+	v, err = nango.NewArduinoApi(replay).AnalogRead("A0")
+	if err != nil || v != 512 {
+		t.Fatalf("expected 512, got %d (%v)", v, err)
+	}
+	replay.AssertDone()
+}
@@ -0,0 +1,225 @@
+//Package nangotest provides a scriptable mock firmware connection for unit
+//testing code built on nango without hardware.
+//
+//	conn := nangotest.NewConn(t)
+//	conn.Expect("A", "r").WithArgs("D2").Return("1")
+//	api := nango.NewArduinoApi(conn)
+//	...
+//	conn.AssertDone()
+package nangotest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Call is a method call received by a Conn
+type Call struct {
+	Namespace string
+	Id        int
+	Method    string
+	//Args holds the wire encoding of each argument
+	Args []string
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s[%d].%s(%s)", c.Namespace, c.Id, c.Method, strings.Join(c.Args, ", "))
+}
+
+//Expectation is a call a Conn expects to receive, along with the response it
+//gives
+type Expectation struct {
+	namespace string
+	method    string
+	args      []string
+	checkArgs bool
+	response  string
+	err       error
+}
+
+//WithArgs restricts the expectation to calls with the given arguments, which
+//are compared by their wire encoding. Without it any arguments match.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = make([]string, len(args))
+	for i, a := range args {
+		e.args[i] = Encode(nil, a)
+	}
+	e.checkArgs = true
+	return e
+}
+
+//Return sets the response to the call
+func (e *Expectation) Return(response string) *Expectation {
+	e.response = response
+	return e
+}
+
+//ReturnBytes sets the response to the call to b, hex encoded as the firmware
+//returns byte buffers
+func (e *Expectation) ReturnBytes(b []byte) *Expectation {
+	return e.Return(hex.EncodeToString(b))
+}
+
+//ReturnError makes reading the response to the call fail with err
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+//Timeout makes the call time out
+func (e *Expectation) Timeout() *Expectation {
+	return e.ReturnError(nango.SerialTimeoutError{Port: "nangotest", Op: "ReadLine"})
+}
+
+func (e *Expectation) matches(c Call) bool {
+	if c.Namespace != e.namespace || c.Method != e.method {
+		return false
+	}
+	if !e.checkArgs {
+		return true
+	}
+	if len(c.Args) != len(e.args) {
+		return false
+	}
+	for i := range e.args {
+		if c.Args[i] != e.args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	if !e.checkArgs {
+		return fmt.Sprintf("%s.%s(...)", e.namespace, e.method)
+	}
+	return fmt.Sprintf("%s.%s(%s)", e.namespace, e.method, strings.Join(e.args, ", "))
+}
+
+//Conn is a mock nango.Conn which answers calls from a script of
+//expectations, which must be met in order. Unexpected calls fail the test and
+//return an error.
+type Conn struct {
+	t        testing.TB
+	mu       sync.Mutex
+	expected []*Expectation
+	calls    []Call
+	pending  []byte
+	replies  []*Expectation
+}
+
+//NewConn returns a Conn reporting failures to t
+func NewConn(t testing.TB) *Conn {
+	return &Conn{t: t}
+}
+
+//Expect appends a call to the script and returns its expectation, which by
+//default matches any arguments and returns an empty response
+func (c *Conn) Expect(namespace string, method string) *Expectation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &Expectation{namespace: namespace, method: method}
+	c.expected = append(c.expected, e)
+	return e
+}
+
+//Calls returns every call received so far
+func (c *Conn) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+//AssertDone fails the test if any expected calls have not been received
+func (c *Conn) AssertDone() {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.expected {
+		c.t.Errorf("nangotest: expected call %s was not made", e)
+	}
+}
+
+//Write receives one or more framed calls
+func (c *Conn) Write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, b...)
+	for {
+		call, n, ok := nango.ParseCall(c.pending)
+		if !ok {
+			return nil
+		}
+		c.pending = c.pending[n:]
+		c.receive(Call{Namespace: call.Namespace, Id: call.Id, Method: call.Method, Args: call.Args})
+	}
+}
+
+func (c *Conn) receive(call Call) {
+	c.calls = append(c.calls, call)
+	if len(c.expected) == 0 {
+		c.t.Errorf("nangotest: unexpected call %s", call)
+		c.replies = append(c.replies, &Expectation{err: fmt.Errorf("nangotest: unexpected call %s", call)})
+		return
+	}
+	e := c.expected[0]
+	if !e.matches(call) {
+		c.t.Errorf("nangotest: expected call %s but received %s", e, call)
+		c.replies = append(c.replies, &Expectation{err: fmt.Errorf("nangotest: unexpected call %s", call)})
+		return
+	}
+	c.expected = c.expected[1:]
+	c.replies = append(c.replies, e)
+}
+
+//Flush does nothing
+func (c *Conn) Flush() error {
+	return nil
+}
+
+//ReadLine returns the response to the oldest call not yet answered
+func (c *Conn) ReadLine() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.replies) == 0 {
+		return nil, fmt.Errorf("no call awaiting a response: %w", nango.SerialTimeoutError{Port: "nangotest", Op: "ReadLine"})
+	}
+	e := c.replies[0]
+	c.replies = c.replies[1:]
+	if e.err != nil {
+		return nil, e.err
+	}
+	return []byte(e.response), nil
+}
+
+//Encode returns the wire encoding nango uses for an argument in dialect d.
+//A nil d means NanpyDialect, the dialect of calls made on a Conn.
+func Encode(d *nango.Dialect, arg interface{}) string {
+	if d == nil {
+		d = &nango.NanpyDialect
+	}
+	switch v := arg.(type) {
+	case nil:
+		return d.Nil
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case byte:
+		return strconv.Itoa(int(v))
+	case []byte:
+		return hex.EncodeToString(v)
+	case bool:
+		if v {
+			return d.True
+		}
+		return d.False
+	}
+	return fmt.Sprint(arg)
+}
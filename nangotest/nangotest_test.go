@@ -0,0 +1,50 @@
+package nangotest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/justinsantoro/nango"
+)
+
+func TestConn(t *testing.T) {
+	conn := NewConn(t)
+	conn.Expect("A", "pm").WithArgs("D13", nango.PinOutput)
+	conn.Expect("A", "r").WithArgs("D2").Return("1")
+	conn.Expect("A", "a").Timeout()
+	api := nango.NewArduinoApi(conn)
+	if err := api.PinMode("D13", nango.PinOutput); err != nil {
+		t.Fatal(err)
+	}
+	v, err := api.DigitalRead("D2")
+	if err != nil || v != 1 {
+		t.Fatalf("expected 1, got %d (%v)", v, err)
+	}
+	_, err = api.AnalogRead("A0")
+	if !errors.Is(err, nango.ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	conn.AssertDone()
+	if calls := conn.Calls(); len(calls) != 3 || calls[1].String() != "A[0].r(D2)" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, c := range []struct {
+		d    *nango.Dialect
+		arg  interface{}
+		want string
+	}{
+		{nil, true, "True"},
+		{&nango.CDialect, true, "1"},
+		{&nango.CDialect, false, "0"},
+		{&nango.Dialect{Nil: "None"}, nil, "None"},
+		{nil, []byte{0xca, 0xfe}, "cafe"},
+		{nil, byte(7), "7"},
+	} {
+		if got := Encode(c.d, c.arg); got != c.want {
+			t.Errorf("Encode(%v) = %q, expected %q", c.arg, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package nangotest
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+//Behavior computes the value of a simulated input pin from the time elapsed
+//since the simulator started
+type Behavior func(elapsed time.Duration) int
+
+//Constant holds a pin at v
+func Constant(v int) Behavior {
+	return func(time.Duration) int { return v }
+}
+
+//Sine oscillates a pin around mid by amplitude with the given period,
+//clamped to the range of the ADC
+func Sine(mid float64, amplitude float64, period time.Duration) Behavior {
+	return func(t time.Duration) int {
+		v := mid + amplitude*math.Sin(2*math.Pi*t.Seconds()/period.Seconds())
+		return int(math.Round(math.Max(0, math.Min(1023, v))))
+	}
+}
+
+//Ramp moves a pin linearly from from to to over the given duration, then
+//holds it at to
+func Ramp(from int, to int, over time.Duration) Behavior {
+	return func(t time.Duration) int {
+		if t >= over {
+			return to
+		}
+		return from + int(float64(to-from)*t.Seconds()/over.Seconds())
+	}
+}
+
+//Step holds a pin at before until at, then at after
+func Step(before int, after int, at time.Duration) Behavior {
+	return func(t time.Duration) int {
+		if t < at {
+			return before
+		}
+		return after
+	}
+}
+
+//Toggle starts a digital pin low and toggles it every period
+func Toggle(period time.Duration) Behavior {
+	return func(t time.Duration) int {
+		return int(t/period) % 2
+	}
+}
+
+//I2CDevice is a simulated I2C slave exposing a map of byte registers. A
+//write sets the register pointer from its first byte and writes the rest to
+//consecutive registers; a read returns consecutive registers from the
+//pointer.
+type I2CDevice struct {
+	mu        sync.Mutex
+	registers [256]byte
+	pointer   byte
+}
+
+//Register returns the value of register r
+func (d *I2CDevice) Register(r byte) byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.registers[r]
+}
+
+//SetRegister sets register r to v
+func (d *I2CDevice) SetRegister(r byte, v byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.registers[r] = v
+}
+
+func (d *I2CDevice) write(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(b) == 0 {
+		return
+	}
+	d.pointer = b[0]
+	for _, v := range b[1:] {
+		d.registers[d.pointer] = v
+		d.pointer++
+	}
+}
+
+func (d *I2CDevice) read(n int) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = d.registers[d.pointer]
+		d.pointer++
+	}
+	return b
+}
+
+//Simulator is a simulated board: a nango.Loopback answering the Arduino and
+//Wire classes from simulated pins and I2C devices, whose inputs follow
+//scripted behaviors over time, so application logic and drivers can be
+//exercised without hardware.
+//
+//	sim := nangotest.NewSimulator(nil)
+//	sim.Script("A0", nangotest.Sine(512, 200, time.Minute))
+//	sim.AddI2CDevice(0x68, map[byte]byte{0x75: 0x68})
+//	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(sim.Dial))
+//
+//Other classes can be simulated with the Loopback's Handle and Respond.
+type Simulator struct {
+	*nango.Loopback
+	clock nango.Clock
+	start time.Time
+
+	mu        sync.Mutex
+	modes     map[string]int
+	values    map[string]int
+	behaviors map[string]Behavior
+	devices   map[int]*I2CDevice
+	//the Wire transaction in progress
+	txAddress int
+	tx        []byte
+	rx        []byte
+}
+
+//NewSimulator returns a simulator whose behaviors follow clock, or the
+//system clock if nil
+func NewSimulator(clock nango.Clock) *Simulator {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	s := &Simulator{
+		Loopback:  nango.NewLoopback(),
+		clock:     clock,
+		start:     clock.Now(),
+		modes:     make(map[string]int),
+		values:    make(map[string]int),
+		behaviors: make(map[string]Behavior),
+		devices:   make(map[int]*I2CDevice),
+	}
+	a, w := nango.NamespaceArduino, nango.NamespaceWire
+	s.handle(a, nango.MethodPinMode, func(args []string) string {
+		s.modes[args[0]] = atoi(args[1])
+		if s.modes[args[0]] == nango.PinInputPullup {
+			s.values[args[0]] = nango.PinHigh
+		}
+		return ""
+	})
+	s.handle(a, nango.MethodDigitalWrite, s.write)
+	s.handle(a, nango.MethodAnalogWrite, s.write)
+	s.handle(a, nango.MethodDigitalRead, func(args []string) string {
+		v := s.value(args[0])
+		if v > 1 {
+			//analog behaviors read as digital with the threshold of a 5V AVR
+			v = btoi(v >= 614)
+		}
+		return strconv.Itoa(v)
+	})
+	s.handle(a, nango.MethodAnalogRead, func(args []string) string {
+		return strconv.Itoa(s.value(args[0]))
+	})
+	s.handle(a, nango.MethodMillis, func([]string) string {
+		return strconv.FormatInt(int64(s.clock.Now().Sub(s.start)/time.Millisecond), 10)
+	})
+	s.handle(w, nango.MethodWireBegin, func([]string) string { return "" })
+	s.handle(w, nango.MethodWireBeginTransmission, func(args []string) string {
+		s.txAddress, s.tx = atoi(args[0]), s.tx[:0]
+		return ""
+	})
+	s.handle(w, nango.MethodWireWrite, func(args []string) string {
+		s.tx = append(s.tx, byte(atoi(args[0])))
+		return "1"
+	})
+	s.handle(w, nango.MethodWireEndTransmission, func([]string) string {
+		d, ok := s.devices[s.txAddress]
+		if !ok {
+			//NACK on transmit of address
+			return "2"
+		}
+		d.write(s.tx)
+		return "0"
+	})
+	s.handle(w, nango.MethodWireRequestFrom, func(args []string) string {
+		d, ok := s.devices[atoi(args[0])]
+		if !ok {
+			s.rx = nil
+			return "0"
+		}
+		s.rx = d.read(atoi(args[1]))
+		return strconv.Itoa(len(s.rx))
+	})
+	s.handle(w, nango.MethodWireAvailable, func([]string) string {
+		return strconv.Itoa(len(s.rx))
+	})
+	s.handle(w, nango.MethodWireRead, func([]string) string {
+		if len(s.rx) == 0 {
+			return ""
+		}
+		//like the firmware, the byte is returned raw
+		v := s.rx[0]
+		s.rx = s.rx[1:]
+		return string([]byte{v})
+	})
+	return s
+}
+
+//handle answers calls to method in namespace with h, called with the
+//simulator locked
+func (s *Simulator) handle(namespace string, method string, h func(args []string) string) {
+	s.Handle(namespace, method, func(c nango.LoopbackCall) (string, bool) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return h(c.Args), true
+	})
+}
+
+func (s *Simulator) write(args []string) string {
+	s.values[args[0]] = atoi(args[1])
+	return ""
+}
+
+//value returns the value of a pin, following its behavior if it has one
+func (s *Simulator) value(pin string) int {
+	if b, ok := s.behaviors[pin]; ok {
+		return b(s.clock.Now().Sub(s.start))
+	}
+	return s.values[pin]
+}
+
+//Script makes pin follow b when read
+func (s *Simulator) Script(pin string, b Behavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviors[pin] = b
+}
+
+//Set holds pin at v when read, replacing any behavior
+func (s *Simulator) Set(pin string, v int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.behaviors, pin)
+	s.values[pin] = v
+}
+
+//Value returns the value last written to pin, or its current value if it is
+//scripted
+func (s *Simulator) Value(pin string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value(pin)
+}
+
+//Mode returns the mode pin was last set to
+func (s *Simulator) Mode(pin string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.modes[pin]
+}
+
+//AddI2CDevice adds a device at address with the given initial registers
+func (s *Simulator) AddI2CDevice(address int, registers map[byte]byte) *I2CDevice {
+	d := &I2CDevice{}
+	for r, v := range registers {
+		d.registers[r] = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices[address] = d
+	return d
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//systemClock is the real time nango.Clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
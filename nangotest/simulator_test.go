@@ -0,0 +1,82 @@
+package nangotest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+)
+
+func TestSimulator(t *testing.T) {
+	clock := NewClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim := NewSimulator(clock)
+	sim.Script("A0", Ramp(0, 1000, 10*time.Second))
+	sim.Script("A1", Sine(512, 100, 4*time.Second))
+	sim.Script("D2", Toggle(time.Second))
+	rtc := sim.AddI2CDevice(0x68, map[byte]byte{0x75: 0x68})
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(sim.Dial))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	api := nango.NewArduinoApi(conn)
+
+	read := func(pin string, analog bool) int {
+		t.Helper()
+		var v int
+		var err error
+		if analog {
+			v, err = api.AnalogRead(pin)
+		} else {
+			v, err = api.DigitalRead(pin)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	clock.Advance(time.Second)
+	if v := read("A0", true); v != 100 {
+		t.Fatalf("expected ramp at 100, got %d", v)
+	}
+	if v := read("A1", true); v != 612 {
+		t.Fatalf("expected sine at its peak, got %d", v)
+	}
+	if v := read("D2", false); v != 1 {
+		t.Fatalf("expected D2 toggled high, got %d", v)
+	}
+	clock.Advance(time.Second)
+	if v := read("D2", false); v != 0 {
+		t.Fatalf("expected D2 toggled low, got %d", v)
+	}
+	if ms, err := api.Millis(); err != nil || ms != 2000 {
+		t.Fatalf("expected 2000ms since start, got %d (%v)", ms, err)
+	}
+
+	if err := api.PinMode("D13", nango.PinOutput); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DigitalWrite("D13", nango.PinHigh); err != nil {
+		t.Fatal(err)
+	}
+	if sim.Mode("D13") != nango.PinOutput || sim.Value("D13") != 1 {
+		t.Fatalf("expected D13 driven high, got mode %d value %d", sim.Mode("D13"), sim.Value("D13"))
+	}
+
+	i2c := nango.NewI2cMaster(nango.NewWire(conn))
+	if err := i2c.Send(0x68, []byte{0x75}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := i2c.Request(0x68, 1); err != nil || len(b) != 1 || b[0] != 0x68 {
+		t.Fatalf("expected WHO_AM_I 0x68, got %x (%v)", b, err)
+	}
+	if err := i2c.Send(0x68, []byte{0x6b, 0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if rtc.Register(0x6b) != 1 || rtc.Register(0x6c) != 2 {
+		t.Fatal("expected consecutive registers written")
+	}
+	if ok, err := i2c.Probe(0x50); err != nil || ok {
+		t.Fatalf("expected no device at 0x50, got %v (%v)", ok, err)
+	}
+}
@@ -0,0 +1,135 @@
+package nango
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+)
+
+//nodeMarker starts the address prefixed to frames for, and responses from,
+//a node on a multi-drop bus: "@3\0" before a frame and "@3 " before a
+//response
+const nodeMarker = '@'
+
+//defaultBusTurnaround is long enough for a slow node to give up on a late
+//response at 115200 baud
+const defaultBusTurnaround = 50 * time.Millisecond
+
+//WithBusTurnaround sets how long the bus is left quiet after a node fails to
+//respond, so a late response can't collide with the next call
+func WithBusTurnaround(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.busTurnaround = d
+	}
+}
+
+//Node is a handle on one of several boards sharing a multi-drop bus such as
+//RS-485, each running firmware built with a node address. Calls made through
+//a Node are prefixed with its address, so only that board executes and
+//answers them, and are sent by the bus connection's dispatcher, so nodes may
+//be called concurrently and keep the connection's priorities, retries and
+//middleware. The bus is a single master: nodes only ever speak when
+//answering the host, so they never contend for it.
+//
+//	bus := nango.NewFirmwareConnection(conf)
+//	pump := nango.NewArduinoApi(bus.Node(1))
+//	valve := nango.NewArduinoApi(bus.Node(2))
+//
+//Calls made on the bus connection itself are unaddressed, and so are ignored
+//by nodes; don't use WithRequiredClasses on a bus.
+type Node struct {
+	bus     *FirmwareConnection
+	Address int
+	//pending and response hold a call made with Write, Flush and ReadLine
+	pending  []byte
+	response []byte
+	err      error
+}
+
+//Node returns a handle on the board with the given address on the bus
+func (s *FirmwareConnection) Node(address int) *Node {
+	return &Node{bus: s, Address: address}
+}
+
+//Write buffers a frame to be sent on Flush. Calls made directly with Write,
+//Flush and ReadLine rather than through a FirmwareClass must not be made
+//concurrently on the same Node, and Flush expects exactly one frame.
+func (n *Node) Write(b []byte) error {
+	n.pending = append(n.pending, b...)
+	return nil
+}
+
+//Flush sends the buffered frame to the node and waits for its response
+func (n *Node) Flush() error {
+	r := getRequest(context.Background(), PriorityNormal)
+	defer putRequest(r)
+	r.frame = appendNodeAddress(r.frame, n.Address)
+	r.frame = append(r.frame, n.pending...)
+	n.pending = n.pending[:0]
+	r.n = 1
+	r.node = n
+	n.bus.do(r)
+	res := r.results[0]
+	n.response, n.err = n.response[:0], res.err
+	if res.value != nil {
+		n.response = append(n.response, res.value.b...)
+		res.value.Release()
+	}
+	return nil
+}
+
+//ReadLine returns the response to the frame last flushed
+func (n *Node) ReadLine() ([]byte, error) {
+	if n.err != nil {
+		err := n.err
+		n.err = nil
+		return nil, err
+	}
+	if n.response == nil {
+		return nil, SerialTimeoutError{Port: n.bus.Name(), Op: "ReadLine"}
+	}
+	line := n.response
+	n.response = nil
+	return line, nil
+}
+
+//appendNodeAddress appends the prefix addressing a frame to a node
+func appendNodeAddress(b []byte, address int) []byte {
+	b = append(b, nodeMarker)
+	b = strconv.AppendInt(b, int64(address), 10)
+	return append(b, 0)
+}
+
+//readNodeBuffer reads the response of node, discarding responses from other
+//nodes, such as a late response to a call which timed out
+func (s *FirmwareConnection) readNodeBuffer(node *Node) (*Buffer, error) {
+	prefix := append(strconv.AppendInt([]byte{nodeMarker}, int64(node.Address), 10), ' ')
+	deadline := clockOf(s).Now().Add(s.readTimeout())
+	for {
+		line, err := s.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(line, prefix) {
+			line = line[len(prefix):]
+			if e := parseException(line); e != nil {
+				return nil, e
+			}
+			b := getBuffer()
+			b.b = append(b.b, line...)
+			return b, nil
+		}
+		s.logf("discarding response %q on %s waiting for node %d", line, s.Name(), node.Address)
+		if !clockOf(s).Now().Before(deadline) {
+			return nil, SerialTimeoutError{Port: s.Name(), Op: "ReadLine"}
+		}
+	}
+}
+
+//awaitBusQuiet waits out the turnaround after a node failed to respond
+func (s *FirmwareConnection) awaitBusQuiet() {
+	if d := s.busQuietUntil.Sub(clockOf(s).Now()); d > 0 {
+		clockOf(s).Sleep(d)
+	}
+}
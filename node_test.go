@@ -0,0 +1,50 @@
+package nango
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNode(t *testing.T) {
+	lb, bus := openLoopback(t, WithBusTurnaround(30*time.Millisecond))
+	lb.Handle(NamespaceArduino, MethodDigitalRead, func(c LoopbackCall) (string, bool) {
+		switch c.Node {
+		case "1":
+			return "1", true
+		case "2":
+			return "0", true
+		case "3":
+			return "!ERR r\tno such pin\t512", true
+		}
+		//node 4 is missing from the bus
+		return "", false
+	})
+	read := func(node int) (int, error) {
+		return NewArduinoApi(bus.Node(node)).DigitalRead("D2")
+	}
+	if v, err := read(1); err != nil || v != 1 {
+		t.Fatalf("expected 1 from node 1, got %v (%v)", v, err)
+	}
+	if v, err := read(2); err != nil || v != 0 {
+		t.Fatalf("expected 0 from node 2, got %v (%v)", v, err)
+	}
+	var exc *FirmwareException
+	if _, err := read(3); !errors.As(err, &exc) || exc.Reason != "no such pin" {
+		t.Fatalf("expected exception from node 3, got %v", err)
+	}
+	if _, err := read(4); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout from missing node, got %v", err)
+	}
+	//the bus is left quiet before the next call
+	start := time.Now()
+	if v, err := read(1); err != nil || v != 1 || time.Since(start) < 30*time.Millisecond {
+		t.Fatalf("expected node 1 answering after the turnaround, got %v after %s (%v)", v, time.Since(start), err)
+	}
+
+	//calls made directly on a node are addressed too
+	n := bus.Node(2)
+	if v, err := directCall(n, NamespaceArduino, StaticId, MethodDigitalRead, []interface{}{"D2"}); err != nil || v.String() != "0" {
+		t.Fatalf("expected 0 from a direct call to node 2, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package nango
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+//OneWireAddress is the 64 bit ROM code of a OneWire device: family code, 48
+//bit serial number and CRC
+type OneWireAddress [8]byte
+
+//FamilyCode returns the device family, e.g. 0x28 for a DS18B20
+func (a OneWireAddress) FamilyCode() byte {
+	return a[0]
+}
+
+//Valid reports whether the address CRC matches its contents
+func (a OneWireAddress) Valid() bool {
+	return OneWireCRC8(a[:7]) == a[7]
+}
+
+func (a OneWireAddress) String() string {
+	return hex.EncodeToString(a[:])
+}
+
+//OneWireCRC8 computes the Dallas/Maxim CRC8 used for OneWire ROM codes and
+//scratchpads
+func OneWireCRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 0x01
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8C
+			}
+			b >>= 1
+		}
+	}
+	return crc
+}
+
+//OneWire gives access to an instance of the arduino OneWire library
+//https://www.pjrc.com/teensy/td_libs_OneWire.html
+type OneWire struct {
+	*FirmwareClass
+}
+
+//NewOneWire creates a OneWire bus instance on the firmware for the given pin
+func NewOneWire(conn Conn, pin string) (*OneWire, error) {
+	f, err := NewFirmwareObject(conn, NamespaceOneWire, pin)
+	if err != nil {
+		return nil, err
+	}
+	return &OneWire{f}, nil
+}
+
+//Reset resets the bus and reports whether any device answered with a presence pulse
+func (w *OneWire) Reset() (bool, error) {
+	v, err := w.CallAndReturnInt(MethodOneWireReset)
+	return v == 1, err
+}
+
+//Select addresses the device with the given ROM code. Must follow a Reset.
+func (w *OneWire) Select(addr OneWireAddress) error {
+	return w.CallAndReturnNothing(MethodOneWireSelect, addr[:])
+}
+
+//Skip addresses all devices on the bus. Must follow a Reset.
+func (w *OneWire) Skip() error {
+	return w.CallAndReturnNothing(MethodOneWireSkip)
+}
+
+//Write writes a byte to the bus. If power is true the bus is held high
+//afterwards to power parasitic devices.
+func (w *OneWire) Write(b byte, power bool) error {
+	return w.CallAndReturnNothing(MethodOneWireWrite, int(b), power)
+}
+
+//WriteBytes writes a buffer to the bus in a single call
+func (w *OneWire) WriteBytes(b []byte, power bool) error {
+	return w.CallAndReturnNothing(MethodOneWireWriteBytes, b, power)
+}
+
+//Read reads a byte from the bus
+func (w *OneWire) Read() (byte, error) {
+	v, err := w.CallAndReturnInt(MethodOneWireRead)
+	return byte(v), err
+}
+
+//ReadBytes reads n bytes from the bus in a single call
+func (w *OneWire) ReadBytes(n int) ([]byte, error) {
+	b, err := w.CallAndReturnBytes(MethodOneWireReadBytes, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != n {
+		return nil, fmt.Errorf("onewire readBytes: requested %d bytes but received %d", n, len(b))
+	}
+	return b, nil
+}
+
+//Depower stops forcing power onto the bus after a Write with power
+func (w *OneWire) Depower() error {
+	return w.CallAndReturnNothing(MethodOneWireDepower)
+}
+
+//ResetSearch restarts the ROM search from the first device
+func (w *OneWire) ResetSearch() error {
+	return w.CallAndReturnNothing(MethodOneWireResetSearch)
+}
+
+//Search returns the next device address on the bus. ok is false when no more
+//devices are found.
+func (w *OneWire) Search() (addr OneWireAddress, ok bool, err error) {
+	b, err := w.CallAndReturnBytes(MethodOneWireSearch)
+	if err != nil || len(b) == 0 {
+		return
+	}
+	if len(b) != len(addr) {
+		err = fmt.Errorf("onewire search: expected %d byte address but received %d", len(addr), len(b))
+		return
+	}
+	copy(addr[:], b)
+	ok = true
+	return
+}
+
+//SearchAll returns the addresses of every device on the bus, skipping any
+//address received with a bad CRC
+func (w *OneWire) SearchAll() ([]OneWireAddress, error) {
+	err := w.ResetSearch()
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]OneWireAddress, 0)
+	for {
+		addr, ok, err := w.Search()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return addrs, nil
+		}
+		if addr.Valid() {
+			addrs = append(addrs, addr)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package nango
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestOneWireCRC8(t *testing.T) {
+	//the ROM code worked through in Maxim application note 27
+	rom := OneWireAddress{0x02, 0x1C, 0xB8, 0x01, 0x00, 0x00, 0x00, 0xA2}
+	if crc := OneWireCRC8(rom[:7]); crc != 0xA2 {
+		t.Fatalf("expected crc 0xA2, got 0x%02X", crc)
+	}
+	if !rom.Valid() {
+		t.Fatal("expected the ROM code valid")
+	}
+	rom[3] ^= 0x10
+	if rom.Valid() {
+		t.Fatal("expected a corrupted ROM code invalid")
+	}
+	if OneWireCRC8(nil) != 0 {
+		t.Fatal("expected the crc of nothing to be 0")
+	}
+}
+
+//oneWireBus simulates the ROM search the firmware's OneWire library runs
+//over a set of devices
+type oneWireBus struct {
+	devices         []OneWireAddress
+	searches        int
+	last            OneWireAddress
+	lastDiscrepancy int
+	done            bool
+}
+
+func (b *oneWireBus) attach(lb *Loopback) {
+	lb.Respond(NamespaceOneWire, MethodNew, "1")
+	lb.Handle(NamespaceOneWire, MethodOneWireResetSearch, func(LoopbackCall) (string, bool) {
+		b.lastDiscrepancy, b.done = 0, false
+		return "", true
+	})
+	lb.Handle(NamespaceOneWire, MethodOneWireSearch, func(LoopbackCall) (string, bool) {
+		b.searches++
+		addr, ok := b.search()
+		if !ok {
+			return "", true
+		}
+		return hex.EncodeToString(addr[:]), true
+	})
+}
+
+func bit(a OneWireAddress, i int) byte {
+	return a[i/8] >> uint(i%8) & 1
+}
+
+//search finds the next device: at each bit where the devices still taking
+//part differ, it takes the 0 branch first and the 1 branch on the pass
+//after, as the devices' wired-AND replies drive the real search
+func (b *oneWireBus) search() (OneWireAddress, bool) {
+	var addr OneWireAddress
+	if b.done || len(b.devices) == 0 {
+		return addr, false
+	}
+	taking := b.devices
+	lastZero := 0
+	for i := 0; i < 64; i++ {
+		var zeros, ones []OneWireAddress
+		for _, d := range taking {
+			if bit(d, i) == 0 {
+				zeros = append(zeros, d)
+			} else {
+				ones = append(ones, d)
+			}
+		}
+		var dir byte
+		switch {
+		case len(zeros) > 0 && len(ones) > 0:
+			//a discrepancy
+			if i+1 < b.lastDiscrepancy {
+				dir = bit(b.last, i)
+			} else if i+1 == b.lastDiscrepancy {
+				dir = 1
+			}
+			if dir == 0 {
+				lastZero = i + 1
+			}
+		case len(ones) > 0:
+			dir = 1
+		}
+		if dir == 0 {
+			taking = zeros
+		} else {
+			taking = ones
+		}
+		addr[i/8] |= dir << uint(i%8)
+	}
+	b.lastDiscrepancy = lastZero
+	b.done = lastZero == 0
+	b.last = addr
+	return addr, true
+}
+
+//romCode returns a DS18B20 ROM code with the given serial number and its CRC
+func romCode(serial ...byte) OneWireAddress {
+	a := OneWireAddress{0x28}
+	copy(a[1:7], serial)
+	a[7] = OneWireCRC8(a[:7])
+	return a
+}
+
+func TestOneWireSearchAll(t *testing.T) {
+	lb, conn := openLoopback(t)
+	bad := romCode(0x01, 0, 0, 0, 0, 0x80)
+	bad[7]++
+	//in search order: the devices differ at the low bits of the first serial
+	//byte and the high bit of the last, and share long prefixes
+	found := []OneWireAddress{
+		romCode(0x00, 0, 0, 0, 0, 0x00),
+		romCode(0x00, 0, 0, 0, 0, 0x80),
+		romCode(0x02, 0, 0, 0, 0, 0x00),
+		romCode(0x01, 0x10, 0, 0, 0, 0x00),
+		romCode(0x03, 0, 0, 0, 0, 0x00),
+	}
+	bus := &oneWireBus{devices: append([]OneWireAddress{bad}, found...)}
+	bus.attach(lb)
+	w, err := NewOneWire(conn, "D2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pass := 0; pass < 2; pass++ {
+		bus.searches = 0
+		addrs, err := w.SearchAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != len(found) {
+			t.Fatalf("expected %d devices, got %v", len(found), addrs)
+		}
+		for i := range found {
+			if addrs[i] != found[i] {
+				t.Fatalf("expected %v in search order, got %v", found, addrs)
+			}
+		}
+		//every device including the one with a bad crc, then the end
+		if bus.searches != len(bus.devices)+1 {
+			t.Fatalf("expected %d searches, got %d", len(bus.devices)+1, bus.searches)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+//Option configures a FirmwareConnection when it is constructed, see
+//NewFirmwareConnection
+type Option func(*FirmwareConnection)
+
+//WithReadTimeout sets how long to wait for each response
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.ReadTimeout = d
+	}
+}
+
+//WithAdaptiveTimeout derives the response timeout from observed round trip
+//times, see AdaptiveTimeout
+func WithAdaptiveTimeout(a AdaptiveTimeout) Option {
+	return func(s *FirmwareConnection) {
+		s.AdaptiveTimeout = &a
+	}
+}
+
+//WithSleepAfterConnect sets how long Open waits for the board to reset
+func WithSleepAfterConnect(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.SleepAfterConnect = d
+	}
+}
+
+//WithLogger sets the logger the connection reports discarded data and slow
+//calls to, instead of the standard logger
+func WithLogger(l *log.Logger) Option {
+	return func(s *FirmwareConnection) {
+		s.logger = l
+	}
+}
+
+//WithRetry makes each call up to attempts times while it times out, waiting
+//backoff before the first retry and doubling the wait for each further one.
+//Only timeouts are retried, as other errors are not transient, but a call
+//which timed out may still have taken effect, so retrying suits idempotent
+//calls such as reads and absolute writes rather than toggles or increments.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.retry = retryPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+//WithTracer traces every call made on the connection
+func WithTracer(t Tracer) Option {
+	return func(s *FirmwareConnection) {
+		s.tracer = t
+	}
+}
+
+//WithTransport talks to the firmware over the transports returned by dial
+//instead of the serial port described by the connection's SerialConfig
+func WithTransport(dial DialFunc) Option {
+	return func(s *FirmwareConnection) {
+		s.dial = dial
+	}
+}
+
+//WithObserver notifies o of every call made on the connection
+func WithObserver(o CallObserver) Option {
+	return func(s *FirmwareConnection) {
+		s.Observer = o
+	}
+}
+
+//WithCoalesceWindow sets the connection's CoalesceWindow
+func WithCoalesceWindow(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.CoalesceWindow = d
+	}
+}
+
+//WithSlowCallThreshold logs calls taking longer than d
+func WithSlowCallThreshold(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.SlowCallThreshold = d
+	}
+}
+
+//WithLatencyRecording enables the per-method histograms returned by Latencies
+func WithLatencyRecording() Option {
+	return func(s *FirmwareConnection) {
+		s.RecordLatency = true
+	}
+}
+
+//retryPolicy is the retry setting of a connection, see WithRetry
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+//do calls f until it succeeds, fails with an error other than a timeout, the
+//attempts are used up or ctx is done
+func (p retryPolicy) do(ctx context.Context, clock Clock, f func() (*Buffer, error)) (v *Buffer, err error) {
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		v, err = f()
+		if !errors.Is(err, ErrTimeout) || attempt >= p.attempts {
+			return v, err
+		}
+		select {
+		case <-clock.After(backoff):
+		case <-ctx.Done():
+			return v, err
+		}
+		backoff *= 2
+	}
+}
+
+//Tracer is notified of the start of every call made on a connection and
+//returns a function which is called with the outcome of the call. The context
+//it returns is used for the call.
+type Tracer interface {
+	StartCall(ctx context.Context, namespace string, method string) (context.Context, func(err error))
+}
+
+func (s *FirmwareConnection) logf(format string, v ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
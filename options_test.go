@@ -0,0 +1,81 @@
+package nango_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func TestRetry(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := nangotest.NewClock(start)
+	lb := nango.NewLoopback()
+	var mu sync.Mutex
+	var calls []time.Duration
+	answerAfter := 0
+	lb.Handle("T", "get", func(c nango.LoopbackCall) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, clock.Now().Sub(start))
+		return "7", len(calls) > answerAfter
+	})
+	lb.Respond("T", "fail", "!ERR fail\tassert i < 8\t312")
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithClock(clock),
+		nango.WithReadTimeout(20*time.Millisecond), nango.WithRetry(3, 10*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &nango.FirmwareClass{Conn: conn, Namespace: "T"}
+	//call runs method, moving the clock on until it returns
+	call := func(method string) (int, error) {
+		type result struct {
+			v   int
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			v, err := f.CallAndReturnInt(method)
+			done <- result{v, err}
+		}()
+		for {
+			select {
+			case r := <-done:
+				return r.v, r.err
+			case <-time.After(time.Millisecond):
+				clock.Advance(5 * time.Millisecond)
+			}
+		}
+	}
+
+	//two timeouts are retried, waiting 10ms then 20ms after each
+	answerAfter = 2
+	if v, err := call("get"); err != nil || v != 7 {
+		t.Fatalf("expected 7 after retries, got %d, %v", v, err)
+	}
+	mu.Lock()
+	if len(calls) != 3 || calls[1]-calls[0] < 30*time.Millisecond || calls[2]-calls[1] < 40*time.Millisecond {
+		t.Fatalf("expected 3 calls with a doubling backoff, got them at %v", calls)
+	}
+	calls, answerAfter = nil, 10
+	mu.Unlock()
+
+	if _, err := call("get"); !errors.Is(err, nango.ErrTimeout) {
+		t.Fatalf("expected a timeout once the attempts are used up, got %v", err)
+	}
+	mu.Lock()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(calls))
+	}
+	mu.Unlock()
+
+	//errors other than timeouts are returned at once
+	var exc *nango.FirmwareException
+	if _, err := call("fail"); !errors.As(err, &exc) {
+		t.Fatalf("expected a FirmwareException, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package nango
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//OutputFunc drives an output, e.g. the duty cycle of a PWM pin
+type OutputFunc func(ctx context.Context, v float64) error
+
+//PWMOutput writes v, rounded and clamped to 0-255, as the duty cycle of pin
+func PWMOutput(api *ArduinoApi, pin string) OutputFunc {
+	return func(ctx context.Context, v float64) error {
+		return api.AnalogWrite(pin, int(clamp(v+0.5, 0, 255)))
+	}
+}
+
+func clamp(v float64, min float64, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+//PID is a proportional-integral-derivative controller driving Output to
+//bring Input to the setpoint, e.g. a heater's PWM pin from a temperature
+//reading. The derivative is taken of the input rather than the error, so
+//setpoint changes don't kick the output, and the integral is clamped to the
+//output range to prevent windup.
+type PID struct {
+	Input  ReadFunc
+	Output OutputFunc
+	//OutMin and OutMax bound the output
+	OutMin float64
+	OutMax float64
+	//SampleTime is how often the controller runs once scheduled
+	SampleTime time.Duration
+
+	mu         sync.Mutex
+	kp, ki, kd float64
+	setpoint   float64
+	integral   float64
+	lastInput  float64
+	lastTime   time.Time
+}
+
+//NewPID returns a PID controller with the given gains, an output range of
+//0-255 and a sample time of 100ms
+func NewPID(input ReadFunc, output OutputFunc, kp float64, ki float64, kd float64) *PID {
+	return &PID{
+		Input:      input,
+		Output:     output,
+		OutMin:     0,
+		OutMax:     255,
+		SampleTime: 100 * time.Millisecond,
+		kp:         kp,
+		ki:         ki,
+		kd:         kd,
+	}
+}
+
+//SetTunings changes the gains. Ki and Kd are per second.
+func (p *PID) SetTunings(kp float64, ki float64, kd float64) {
+	p.mu.Lock()
+	p.kp, p.ki, p.kd = kp, ki, kd
+	p.mu.Unlock()
+}
+
+//SetSetpoint changes the value the controller drives the input to
+func (p *PID) SetSetpoint(v float64) {
+	p.mu.Lock()
+	p.setpoint = v
+	p.mu.Unlock()
+}
+
+//Reset clears the controller's integral and derivative state, e.g. after
+//the process has been under manual control
+func (p *PID) Reset() {
+	p.mu.Lock()
+	p.integral = 0
+	p.lastTime = time.Time{}
+	p.mu.Unlock()
+}
+
+//Update computes the output for input measured at now. The integral and
+//derivative terms are scaled by the time since the previous update, so late
+//samples don't distort the control.
+func (p *PID) Update(now time.Time, input float64) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.setpoint - input
+	var derivative float64
+	if !p.lastTime.IsZero() {
+		dt := now.Sub(p.lastTime).Seconds()
+		if dt > 0 {
+			p.integral = clamp(p.integral+p.ki*err*dt, p.OutMin, p.OutMax)
+			derivative = -(input - p.lastInput) / dt
+		}
+	}
+	p.lastInput = input
+	p.lastTime = now
+	return clamp(p.kp*err+p.integral+p.kd*derivative, p.OutMin, p.OutMax)
+}
+
+//Step reads the input, updates the controller and writes the output
+func (p *PID) Step(ctx context.Context, now time.Time) error {
+	v, err := p.Input(ctx)
+	if err != nil {
+		return err
+	}
+	return p.Output(ctx, p.Update(now, v))
+}
+
+//Schedule runs the controller every SampleTime on s as the task called name
+func (p *PID) Schedule(s *Scheduler, name string) {
+	s.Every(name, p.SampleTime, func(ctx context.Context) error {
+		return p.Step(ctx, s.clock.Now())
+	})
+}
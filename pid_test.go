@@ -0,0 +1,52 @@
+package nango
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPIDConverges(t *testing.T) {
+	p := NewPID(nil, nil, 2, 1, 0.1)
+	p.SetSetpoint(50)
+	//first order plant whose value moves towards the output
+	v := 20.0
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 600; i++ {
+		out := p.Update(now, v)
+		if out < p.OutMin || out > p.OutMax {
+			t.Fatalf("output %v outside range", out)
+		}
+		v += (out - v) * 0.05
+		now = now.Add(100 * time.Millisecond)
+	}
+	if v < 49 || v > 51 {
+		t.Fatalf("expected input near setpoint 50, got %v", v)
+	}
+}
+
+func TestPIDAntiWindup(t *testing.T) {
+	p := NewPID(nil, nil, 1, 10, 0)
+	p.SetSetpoint(1000)
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	//saturate for a long time, then overshoot the setpoint
+	for i := 0; i < 100; i++ {
+		p.Update(now, 0)
+		now = now.Add(time.Second)
+	}
+	p.SetSetpoint(0)
+	//without anti windup the integral accumulated while saturated would
+	//hold the output at its maximum for thousands of steps
+	for i := 0; ; i++ {
+		now = now.Add(time.Second)
+		out := p.Update(now, 10)
+		if i == 0 && out >= p.OutMax {
+			t.Fatalf("output %v still saturated after the setpoint dropped", out)
+		}
+		if out == p.OutMin {
+			break
+		}
+		if i == 3 {
+			t.Fatalf("output %v did not reach the minimum within %d steps", out, i+1)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package nango
+
+import (
+	"fmt"
+	"strings"
+)
+
+//PinCapability is a set of functions a pin supports
+type PinCapability int
+
+const (
+	CapDigital PinCapability = 1 << iota
+	CapAnalogIn
+	CapPWM
+	CapInterrupt
+)
+
+func (c PinCapability) String() string {
+	var names []string
+	for _, n := range []struct {
+		c    PinCapability
+		name string
+	}{{CapDigital, "digital"}, {CapAnalogIn, "analog input"}, {CapPWM, "PWM"}, {CapInterrupt, "interrupt"}} {
+		if c&n.c != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+//BoardModel describes the pins of a board model
+type BoardModel struct {
+	Name string
+	Pins map[string]PinCapability
+}
+
+//PinCapabilityError is returned when a pin is used for a function it doesn't
+//support, which the firmware would otherwise silently ignore
+type PinCapabilityError struct {
+	Model    string
+	Pin      string
+	Required PinCapability
+	Has      PinCapability
+}
+
+func (e *PinCapabilityError) Error() string {
+	if e.Has == 0 {
+		return fmt.Sprintf("%s has no pin %s", e.Model, e.Pin)
+	}
+	return fmt.Sprintf("pin %s of %s does not support %s (supports %s)", e.Pin, e.Model, e.Required, e.Has)
+}
+
+//Check returns a PinCapabilityError unless pin supports every capability in
+//required. A nil model allows everything.
+func (m *BoardModel) Check(pin string, required PinCapability) error {
+	if m == nil {
+		return nil
+	}
+	has := m.Pins[strings.ToUpper(pin)]
+	if has&required != required {
+		return &PinCapabilityError{Model: m.Name, Pin: pin, Required: required, Has: has}
+	}
+	return nil
+}
+
+//newBoardModel builds a model with digital pins D0 to D(digital-1), analog
+//pins A0 to A(analog-1), the first digitalAnalog of which can also be used as
+//digital pins, and the given PWM and interrupt capable digital pins
+func newBoardModel(name string, digital int, analog int, digitalAnalog int, pwm []int, interrupts []int) *BoardModel {
+	m := &BoardModel{Name: name, Pins: make(map[string]PinCapability)}
+	for i := 0; i < digital; i++ {
+		m.Pins[fmt.Sprintf("D%d", i)] = CapDigital
+	}
+	for i := 0; i < analog; i++ {
+		c := CapAnalogIn
+		if i < digitalAnalog {
+			c |= CapDigital
+		}
+		m.Pins[fmt.Sprintf("A%d", i)] = c
+	}
+	for _, p := range pwm {
+		m.Pins[fmt.Sprintf("D%d", p)] |= CapPWM
+	}
+	for _, p := range interrupts {
+		m.Pins[fmt.Sprintf("D%d", p)] |= CapInterrupt
+	}
+	return m
+}
+
+//BoardModels holds the pin capabilities of common boards
+var BoardModels = map[string]*BoardModel{
+	"uno":      newBoardModel("uno", 14, 6, 6, []int{3, 5, 6, 9, 10, 11}, []int{2, 3}),
+	"nano":     newBoardModel("nano", 14, 8, 6, []int{3, 5, 6, 9, 10, 11}, []int{2, 3}),
+	"mega2560": newBoardModel("mega2560", 54, 16, 16, []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 44, 45, 46}, []int{2, 3, 18, 19, 20, 21}),
+	"leonardo": newBoardModel("leonardo", 14, 6, 6, []int{3, 5, 6, 9, 10, 11, 13}, []int{0, 1, 2, 3, 7}),
+	"micro":    newBoardModel("micro", 14, 6, 6, []int{3, 5, 6, 9, 10, 11, 13}, []int{0, 1, 2, 3, 7}),
+}
@@ -0,0 +1,49 @@
+package nango
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBoardModelCheck(t *testing.T) {
+	uno := BoardModels["uno"]
+	for _, c := range []struct {
+		pin      string
+		required PinCapability
+		ok       bool
+	}{
+		{"D9", CapPWM, true},
+		{"d9", CapPWM | CapDigital, true},
+		{"D7", CapPWM, false},
+		{"A0", CapDigital, true},
+		{"A0", CapPWM, false},
+		{"D2", CapInterrupt, true},
+		{"D4", CapInterrupt, false},
+		{"D20", CapDigital, false},
+	} {
+		err := uno.Check(c.pin, c.required)
+		if (err == nil) != c.ok {
+			t.Errorf("Check(%s, %s): unexpected result %v", c.pin, c.required, err)
+		}
+	}
+	if err := BoardModels["nano"].Check("A7", CapDigital); err == nil {
+		t.Error("nano A7 is analog input only")
+	}
+	var nilModel *BoardModel
+	if err := nilModel.Check("X99", CapPWM); err != nil {
+		t.Errorf("nil model should allow everything, got %v", err)
+	}
+}
+
+func TestAnalogWriteRejectsNonPWMPin(t *testing.T) {
+	api := NewArduinoApi(nil)
+	api.Model = BoardModels["uno"]
+	err := api.AnalogWrite("D7", 128)
+	var capErr *PinCapabilityError
+	if !errors.As(err, &capErr) || capErr.Required != CapPWM {
+		t.Fatalf("expected PinCapabilityError, got %v", err)
+	}
+	if want := "pin D7 of uno does not support PWM (supports digital)"; err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+}
@@ -0,0 +1,233 @@
+package nango
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+//PipelineResult holds the response to a call queued on a Pipeline. It is
+//populated by Pipeline.Exec.
+type PipelineResult struct {
+	namespace string
+	method    string
+	value     string
+	err       error
+	call      *CallInfo //the call as queued, for the connection's middleware
+}
+
+func (r *PipelineResult) responseError(err error) error {
+	return &FirmwareError{Namespace: r.namespace, Method: r.method, Response: r.value, Err: err}
+}
+
+//Value returns the raw response to the call
+func (r *PipelineResult) Value() (string, error) {
+	return r.value, r.err
+}
+
+//Int returns the response to the call parsed as an int
+func (r *PipelineResult) Int() (int, error) {
+	if r.err != nil {
+		return -1, r.err
+	}
+	v, err := strconv.Atoi(r.value)
+	if err != nil {
+		return -1, r.responseError(err)
+	}
+	return v, nil
+}
+
+//Float returns the response to the call parsed as a float
+func (r *PipelineResult) Float() (float64, error) {
+	if r.err != nil {
+		return -1, r.err
+	}
+	v, err := strconv.ParseFloat(r.value, 64)
+	if err != nil {
+		return -1, r.responseError(err)
+	}
+	return v, nil
+}
+
+//Err returns the error encountered by the call, if any
+func (r *PipelineResult) Err() error {
+	return r.err
+}
+
+//Pipeline collects independent calls which are written to the firmware
+//back-to-back by Exec, after which the responses are read and matched to the
+//calls in order. A burst of calls then costs a single round trip instead of
+//one per call.
+//
+//The firmware processes requests as it reads them, but boards without USB
+//flow control can overrun their serial receive buffer (64 bytes on most AVR
+//boards) if a pipeline is very large.
+type Pipeline struct {
+	conn    *FirmwareConnection
+	buf     []byte
+	results []*PipelineResult
+	err     error
+	//Priority is the priority the pipeline waits for the connection with.
+	//It is raised to that of the highest priority class queued.
+	Priority int
+}
+
+//Pipeline returns an empty Pipeline on the connection
+func (s *FirmwareConnection) Pipeline() *Pipeline {
+	return &Pipeline{conn: s}
+}
+
+//Call queues a call to methodName on f. The returned result is populated once
+//Exec returns.
+func (p *Pipeline) Call(f *FirmwareClass, methodName string, args ...interface{}) *PipelineResult {
+	r := &PipelineResult{namespace: f.Namespace, method: methodName}
+	r.call = &CallInfo{Namespace: f.Namespace, Id: f.Id, Method: methodName, Args: args, Priority: f.Priority}
+	n := len(p.buf)
+	var err error
+	p.buf, err = appendFrame(p.buf, f.Namespace, f.Id, methodName, args, dialectOf(p.conn))
+	if err != nil {
+		p.buf = p.buf[:n]
+		r.err = err
+		if p.err == nil {
+			p.err = err
+		}
+		return r
+	}
+	p.results = append(p.results, r)
+	if f.Priority > p.Priority {
+		p.Priority = f.Priority
+	}
+	return r
+}
+
+//Len returns the number of calls queued
+func (p *Pipeline) Len() int {
+	return len(p.results)
+}
+
+//Exec sends every queued call and reads their responses, returning the first
+//error encountered. Calls which could not be completed carry the error in
+//their result. The pipeline is empty afterwards and may be reused.
+func (p *Pipeline) Exec() error {
+	return p.ExecContext(context.Background())
+}
+
+//ExecContext is like Exec but the pipeline is abandoned if ctx is done before
+//it has been sent to the firmware
+func (p *Pipeline) ExecContext(ctx context.Context) error {
+	err := p.err
+	results := p.results
+	defer func() {
+		p.buf = p.buf[:0]
+		p.results = nil
+		p.err = nil
+		p.Priority = PriorityNormal
+	}()
+	if err != nil {
+		for _, r := range results {
+			if r.err == nil {
+				r.err = err
+			}
+		}
+		return err
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	statInFlightCalls.Add(int64(len(results)))
+	defer statInFlightCalls.Add(-int64(len(results)))
+	if p.conn.handler != nil {
+		return p.execMiddleware(ctx, results)
+	}
+	start := clockOf(p.conn).Now()
+	req := getRequest(ctx, p.Priority)
+	defer putRequest(req)
+	req.frame = append(req.frame, p.buf...)
+	req.n = len(results)
+	p.conn.do(req)
+	for i, r := range results {
+		r.err = req.results[i].err
+		if b := req.results[i].value; b != nil {
+			r.value = b.String()
+			b.Release()
+		}
+		if err == nil {
+			err = r.err
+		}
+		p.conn.observe(r.namespace, r.method, nil, start, r.err)
+	}
+	return err
+}
+
+//execMiddleware runs each call through the connection's middleware. Once
+//every call has either reached the end of the chain or been answered by a
+//middleware, those which reached the end are sent as one transfer and their
+//responses returned back up the chain.
+func (p *Pipeline) execMiddleware(ctx context.Context, results []*PipelineResult) error {
+	s := p.conn
+	start := clockOf(s).Now()
+	reached := make(chan *pipelineSlot, len(results))
+	slots := make([]*pipelineSlot, len(results))
+	outcomes := make([]callResult, len(results))
+	var wg sync.WaitGroup
+	for i, r := range results {
+		slot := &pipelineSlot{reached: reached, result: make(chan callResult, 1)}
+		slots[i] = slot
+		wg.Add(1)
+		go func(i int, c *CallInfo) {
+			defer wg.Done()
+			v, err := s.handler(context.WithValue(ctx, pipelineSlotKey{}, slot), c)
+			outcomes[i] = callResult{value: v, err: err}
+			slot.release()
+		}(i, r.call)
+		//the calls enter the chain one at a time, so middleware sees them in
+		//the order they were queued
+		<-reached
+	}
+
+	//middleware may have changed the calls, so they are encoded again
+	req := getRequest(ctx, p.Priority)
+	defer putRequest(req)
+	var sent []*pipelineSlot
+	for _, slot := range slots {
+		c := slot.call
+		if c == nil {
+			continue
+		}
+		n := len(req.frame)
+		var err error
+		req.frame, err = appendFrame(req.frame, c.Namespace, c.Id, c.Method, c.Args, dialectOf(s))
+		if err != nil {
+			req.frame = req.frame[:n]
+			slot.result <- callResult{err: err}
+			continue
+		}
+		if c.Priority > req.priority {
+			req.priority = c.Priority
+		}
+		sent = append(sent, slot)
+	}
+	if len(sent) > 0 {
+		req.n = len(sent)
+		s.do(req)
+		for i, slot := range sent {
+			slot.result <- req.results[i]
+		}
+	}
+	wg.Wait()
+
+	var err error
+	for i, r := range results {
+		r.err = outcomes[i].err
+		if b := outcomes[i].value; b != nil {
+			r.value = b.String()
+			b.Release()
+		}
+		if err == nil {
+			err = r.err
+		}
+		s.observe(r.namespace, r.method, nil, start, r.err)
+	}
+	return err
+}
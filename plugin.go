@@ -0,0 +1,186 @@
+package nango
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Kinds of capability a plugin can provide. A capability is named by its kind
+//and name joined with a colon, e.g. "driver:bme680".
+const (
+	CapabilityNamespace = "namespace"
+	CapabilityDriver    = "driver"
+	CapabilityCommand   = "command"
+)
+
+//DriverFactory creates a driver on b. args holds the driver's settings, e.g.
+//from a configuration file.
+type DriverFactory func(b *Board, args map[string]string) (interface{}, error)
+
+//PluginCommand is a subcommand added to the nango command line tool
+type PluginCommand struct {
+	//Usage is shown in the tool's help, e.g. "lora send <msg>"
+	Usage string
+	//Run executes the command. open connects to the board selected by the
+	//tool's flags and is only called by commands which need one.
+	Run func(open func() (*Board, error), args []string) error
+}
+
+//Plugin extends nango with firmware classes, drivers and commands from
+//another module, so they can be used without changes to this package.
+//Plugins register themselves from an init function:
+//
+//	func init() {
+//		nango.Register(&nango.Plugin{
+//			Name:       "example.com/lora",
+//			Namespaces: []string{"LoRa"},
+//			Drivers:    map[string]nango.DriverFactory{"rfm95": newRFM95},
+//		})
+//	}
+type Plugin struct {
+	//Name identifies the plugin, usually by its module path
+	Name string
+	//Namespaces are the firmware classes the plugin wraps
+	Namespaces []string
+	//Drivers create the plugin's drivers by name
+	Drivers map[string]DriverFactory
+	//Commands are added to the nango tool by name
+	Commands map[string]*PluginCommand
+}
+
+//capabilities returns the capability strings p provides
+func (p *Plugin) capabilities() []string {
+	var caps []string
+	for _, ns := range p.Namespaces {
+		caps = append(caps, CapabilityNamespace+":"+ns)
+	}
+	for name := range p.Drivers {
+		caps = append(caps, CapabilityDriver+":"+name)
+	}
+	for name := range p.Commands {
+		caps = append(caps, CapabilityCommand+":"+name)
+	}
+	return caps
+}
+
+var plugins = struct {
+	sync.RWMutex
+	byName       map[string]*Plugin
+	byCapability map[string]*Plugin
+}{
+	byName:       make(map[string]*Plugin),
+	byCapability: make(map[string]*Plugin),
+}
+
+//Register makes the capabilities of p available. It panics if p has no name,
+//has a nil driver or command, or if its name or any of its capabilities are
+//already registered, as that is a mistake in the program's imports rather
+//than a runtime condition.
+func Register(p *Plugin) {
+	if p == nil || p.Name == "" {
+		panic("nango: Register of plugin without a name")
+	}
+	for name, f := range p.Drivers {
+		if f == nil {
+			panic(fmt.Sprintf("nango: plugin %s registers nil driver %s", p.Name, name))
+		}
+	}
+	for name, c := range p.Commands {
+		if c == nil || c.Run == nil {
+			panic(fmt.Sprintf("nango: plugin %s registers command %s without Run", p.Name, name))
+		}
+	}
+	plugins.Lock()
+	defer plugins.Unlock()
+	if _, dup := plugins.byName[p.Name]; dup {
+		panic(fmt.Sprintf("nango: Register called twice for plugin %s", p.Name))
+	}
+	caps := p.capabilities()
+	for _, c := range caps {
+		if other, dup := plugins.byCapability[c]; dup {
+			panic(fmt.Sprintf("nango: plugin %s registers %s, already provided by %s", p.Name, c, other.Name))
+		}
+	}
+	for _, c := range caps {
+		plugins.byCapability[c] = p
+	}
+	plugins.byName[p.Name] = p
+}
+
+//unregister removes the plugin called name and its capabilities, so tests
+//can register plugins repeatedly
+func unregister(name string) {
+	plugins.Lock()
+	defer plugins.Unlock()
+	p, ok := plugins.byName[name]
+	if !ok {
+		return
+	}
+	for _, c := range p.capabilities() {
+		delete(plugins.byCapability, c)
+	}
+	delete(plugins.byName, name)
+}
+
+//Lookup returns the plugin providing capability, e.g. "namespace:LoRa"
+func Lookup(capability string) (*Plugin, bool) {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	p, ok := plugins.byCapability[capability]
+	return p, ok
+}
+
+//Plugins returns the registered plugins sorted by name
+func Plugins() []*Plugin {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	ps := make([]*Plugin, 0, len(plugins.byName))
+	for _, p := range plugins.byName {
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	return ps
+}
+
+//Capabilities returns the registered capabilities of the given kind, or of
+//every kind if kind is empty, sorted
+func Capabilities(kind string) []string {
+	plugins.RLock()
+	defer plugins.RUnlock()
+	var caps []string
+	for c := range plugins.byCapability {
+		if kind == "" || strings.HasPrefix(c, kind+":") {
+			caps = append(caps, c)
+		}
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+//NewDriver creates the driver registered as name on b, which lists it in its
+//Snapshot
+func NewDriver(b *Board, name string, args map[string]string) (interface{}, error) {
+	p, ok := Lookup(CapabilityDriver + ":" + name)
+	if !ok {
+		return nil, fmt.Errorf("no driver registered as %q", name)
+	}
+	d, err := p.Drivers[name](b, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: driver %s: %w", p.Name, name, err)
+	}
+	if b != nil {
+		b.attach(name, args, d)
+	}
+	return d, nil
+}
+
+//PluginCommandFor returns the command registered as name
+func PluginCommandFor(name string) (*PluginCommand, bool) {
+	p, ok := Lookup(CapabilityCommand + ":" + name)
+	if !ok {
+		return nil, false
+	}
+	return p.Commands[name], true
+}
@@ -0,0 +1,82 @@
+package nango
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//registerPlugin registers p until the end of the test
+func registerPlugin(t *testing.T, p *Plugin) {
+	t.Helper()
+	Register(p)
+	t.Cleanup(func() { unregister(p.Name) })
+}
+
+func TestPluginRegistry(t *testing.T) {
+	errBadPin := errors.New("bad pin")
+	registerPlugin(t, &Plugin{
+		Name:       "example.com/test",
+		Namespaces: []string{"TestClass"},
+		Drivers: map[string]DriverFactory{
+			"testdriver": func(b *Board, args map[string]string) (interface{}, error) {
+				if args["pin"] == "" {
+					return nil, errBadPin
+				}
+				return args["pin"], nil
+			},
+		},
+		Commands: map[string]*PluginCommand{
+			"testcmd": {Usage: "testcmd <arg>", Run: func(func() (*Board, error), []string) error { return nil }},
+		},
+	})
+	if p, ok := Lookup("namespace:TestClass"); !ok || p.Name != "example.com/test" {
+		t.Fatalf("expected namespace to be provided by the plugin, got %v", p)
+	}
+	var caps []string
+	for _, c := range Capabilities("") {
+		if strings.HasSuffix(c, ":TestClass") || strings.HasSuffix(c, ":testdriver") || strings.HasSuffix(c, ":testcmd") {
+			caps = append(caps, c)
+		}
+	}
+	expected := []string{"command:testcmd", "driver:testdriver", "namespace:TestClass"}
+	if !reflect.DeepEqual(caps, expected) {
+		t.Fatalf("expected capabilities %v, got %v", expected, caps)
+	}
+	if pc, ok := PluginCommandFor("testcmd"); !ok || pc.Usage != "testcmd <arg>" {
+		t.Fatalf("expected command, got %v", pc)
+	}
+	d, err := NewDriver(nil, "testdriver", map[string]string{"pin": "D3"})
+	if err != nil || d != "D3" {
+		t.Fatalf("expected driver on D3, got %v (%v)", d, err)
+	}
+	if _, err := NewDriver(nil, "testdriver", nil); !errors.Is(err, errBadPin) {
+		t.Fatalf("expected driver error, got %v", err)
+	}
+	if _, err := NewDriver(nil, "missing", nil); err == nil {
+		t.Fatal("expected error for unregistered driver")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic registering a capability twice")
+		}
+		if _, ok := Lookup("namespace:OtherClass"); ok {
+			t.Fatal("expected a rejected plugin to register nothing")
+		}
+	}()
+	Register(&Plugin{Name: "example.com/other", Namespaces: []string{"OtherClass", "TestClass"}})
+}
+
+func TestPluginRegisterNilCommand(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic registering a nil command")
+		}
+		if _, ok := PluginCommandFor("nilcmd"); ok {
+			t.Fatal("expected a rejected plugin to register nothing")
+		}
+	}()
+	Register(&Plugin{Name: "example.com/nilcmd", Commands: map[string]*PluginCommand{"nilcmd": nil}})
+}
@@ -0,0 +1,91 @@
+package nango
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//Reading is a value read from a pin or device by a Poller
+type Reading struct {
+	//Source names what was read, e.g. "A0" or "bme280.temperature"
+	Source string
+	Time   time.Time
+	Value  float64
+	//Err is set if the read failed, in which case Value is meaningless
+	Err error
+}
+
+//ReadFunc reads a value for a Poller
+type ReadFunc func(ctx context.Context) (float64, error)
+
+//Poller reads sources periodically on a Scheduler and publishes the
+//readings to its subscribers, e.g. a data logger or rule engine
+type Poller struct {
+	sched  *Scheduler
+	mu     sync.Mutex
+	subs   map[int]func(Reading)
+	nextId int
+}
+
+//NewPoller returns a Poller scheduling its reads on sched
+func NewPoller(sched *Scheduler) *Poller {
+	return &Poller{sched: sched, subs: make(map[int]func(Reading))}
+}
+
+//Add reads source with read every interval
+func (p *Poller) Add(source string, interval time.Duration, read ReadFunc) {
+	p.sched.Every(source, interval, func(ctx context.Context) error {
+		v, err := read(ctx)
+		p.publish(Reading{Source: source, Time: p.sched.clock.Now(), Value: v, Err: err})
+		return err
+	})
+}
+
+//Remove stops reading source
+func (p *Poller) Remove(source string) {
+	p.sched.Remove(source)
+}
+
+//Subscribe calls fn with every reading until the returned function is called.
+//fn is called on the scheduler's goroutine, so it should return quickly.
+func (p *Poller) Subscribe(fn func(Reading)) (unsubscribe func()) {
+	p.mu.Lock()
+	id := p.nextId
+	p.nextId++
+	p.subs[id] = fn
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		delete(p.subs, id)
+		p.mu.Unlock()
+	}
+}
+
+func (p *Poller) publish(r Reading) {
+	p.mu.Lock()
+	subs := make([]func(Reading), 0, len(p.subs))
+	for _, fn := range p.subs {
+		subs = append(subs, fn)
+	}
+	p.mu.Unlock()
+	for _, fn := range subs {
+		fn(r)
+	}
+}
+
+//AnalogInput reads the analog pin
+func AnalogInput(api *ArduinoApi, pin string) ReadFunc {
+	return func(ctx context.Context) (float64, error) {
+		v, err := api.AnalogRead(pin)
+		return float64(v), err
+	}
+}
+
+//DigitalInput reads the digital pin as 0 or 1
+func DigitalInput(api *ArduinoApi, pin string) ReadFunc {
+	return func(ctx context.Context) (float64, error) {
+		v, err := api.DigitalRead(pin)
+		return float64(v), err
+	}
+}
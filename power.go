@@ -0,0 +1,125 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//SleepMode is an AVR sleep mode
+type SleepMode int
+
+const (
+	//SleepIdle stops the CPU, leaving timers and the UART running, so any
+	//call wakes the board
+	SleepIdle SleepMode = iota
+	//SleepPowerDown stops everything but the watchdog, which wakes the board
+	//after the sleep period, and the wake on serial pin change interrupt if
+	//enabled
+	SleepPowerDown
+)
+
+//wakeByte is sent to wake a board in power down with wake on serial enabled.
+//It is lost in waking the board, or discarded by the firmware if it isn't.
+const wakeByte = 0xff
+
+//defaultWakeDelay is long enough for a crystal oscillator to restart
+const defaultWakeDelay = 20 * time.Millisecond
+
+//WithWakeDelay sets how long to wait after waking a board from power down
+//before sending calls to it
+func WithWakeDelay(d time.Duration) Option {
+	return func(s *FirmwareConnection) {
+		s.wakeDelay = d
+	}
+}
+
+//sleepWindow is a period the firmware sleeps for
+type sleepWindow struct {
+	mode         SleepMode
+	period       time.Duration
+	wakeOnSerial bool
+	//until is when the board wakes itself, zero if only serial wakes it
+	until time.Time
+}
+
+//sleepState tracks whether the board is asleep
+type sleepState struct {
+	mu     sync.Mutex
+	window *sleepWindow
+}
+
+//Sleep puts the board into the given sleep mode for d, or until serial
+//activity if wakeOnSerial is set, in which case d may be zero to sleep until
+//the next call. The connection tracks the sleep window: calls made while the
+//board sleeps wake it if it can be woken, and are otherwise held until it
+//wakes itself, rather than timing out.
+func (s *FirmwareConnection) Sleep(mode SleepMode, d time.Duration, wakeOnSerial bool) error {
+	if d <= 0 && !wakeOnSerial && mode != SleepIdle {
+		return errors.New("sleep: a board in power down without wake on serial needs a period to wake from")
+	}
+	c := &CallInfo{
+		Namespace: NamespacePower,
+		Id:        StaticId,
+		Method:    MethodSleep,
+		Args:      []interface{}{int(mode), int(d / time.Millisecond), wakeOnSerial},
+		sleep:     &sleepWindow{mode: mode, period: d, wakeOnSerial: wakeOnSerial},
+	}
+	b, err := s.handle(context.Background(), c)
+	b.Release()
+	return err
+}
+
+//Asleep reports whether the board is in a sleep window
+func (s *FirmwareConnection) Asleep() bool {
+	s.sleep.mu.Lock()
+	defer s.sleep.mu.Unlock()
+	w := s.sleep.window
+	return w != nil && (w.until.IsZero() || clockOf(s).Now().Before(w.until))
+}
+
+//fallAsleep starts the sleep window of w once the firmware has acknowledged
+//it, before the dispatcher sends another call
+func (s *FirmwareConnection) fallAsleep(w sleepWindow) {
+	if w.period > 0 {
+		w.until = clockOf(s).Now().Add(w.period)
+	}
+	s.sleep.mu.Lock()
+	s.sleep.window = &w
+	s.sleep.mu.Unlock()
+}
+
+//wake ends the sleep window, if any, before the dispatcher sends a call:
+//waking a board in power down with wake on serial, or waiting for one without
+//to wake itself
+func (s *FirmwareConnection) wake() error {
+	s.sleep.mu.Lock()
+	w := s.sleep.window
+	s.sleep.window = nil
+	s.sleep.mu.Unlock()
+	if w == nil || w.mode == SleepIdle {
+		return nil
+	}
+	clock := clockOf(s)
+	if !w.until.IsZero() && !clock.Now().Before(w.until) {
+		return nil
+	}
+	if !w.wakeOnSerial {
+		//the UART is off until the watchdog wakes the board
+		select {
+		case <-clock.After(w.until.Sub(clock.Now()) + s.wakeDelay):
+			return nil
+		case <-s.quit:
+			return portClosed()
+		}
+	}
+	if err := s.Write([]byte{wakeByte}); err != nil {
+		return err
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	clock.Sleep(s.wakeDelay)
+	return nil
+}
@@ -0,0 +1,89 @@
+package nango_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func TestSleep(t *testing.T) {
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb := nango.NewLoopback()
+	var sleeps []string
+	lb.Handle(nango.NamespacePower, nango.MethodSleep, func(c nango.LoopbackCall) (string, bool) {
+		sleeps = append(sleeps, c.Args[0]+","+c.Args[1]+","+c.Args[2])
+		return "0", true
+	})
+	lb.Respond(nango.NamespaceArduino, nango.MethodDigitalRead, "1")
+	var seen []string
+	mw := func(next nango.CallFunc) nango.CallFunc {
+		return func(ctx context.Context, c *nango.CallInfo) (*nango.Buffer, error) {
+			seen = append(seen, c.Method)
+			return next(ctx, c)
+		}
+	}
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithClock(clock),
+		nango.WithMiddleware(mw), nango.WithWakeDelay(20*time.Millisecond))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	api := nango.NewArduinoApi(conn)
+
+	if err := conn.Sleep(nango.SleepPowerDown, 300*time.Millisecond, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || seen[0] != nango.MethodSleep || len(sleeps) != 1 || sleeps[0] != "1,300,False" {
+		t.Fatalf("expected the sleep call made through the middleware, got %v and %v", seen, sleeps)
+	}
+	if !conn.Asleep() {
+		t.Fatal("expected the board asleep")
+	}
+
+	//a board in power down without wake on serial holds calls until it
+	//wakes itself
+	done := make(chan error, 1)
+	go func() {
+		_, err := api.DigitalRead("D2")
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(300 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("expected the call held until the wake delay has passed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(20 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if conn.Asleep() {
+		t.Fatal("expected the board awake")
+	}
+
+	//wake on serial sends a wake byte and only waits out the wake delay
+	if err := conn.Sleep(nango.SleepPowerDown, time.Hour, true); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_, err := api.DigitalRead("D2")
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(20 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.Sleep(nango.SleepPowerDown, 0, false); err == nil {
+		t.Fatal("expected an error sleeping forever without wake on serial")
+	}
+}
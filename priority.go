@@ -0,0 +1,101 @@
+package nango
+
+import "sync"
+
+const (
+	PriorityLow = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+//starvationLimit is the number of times in a row the longest waiting request
+//may be overtaken by higher priority requests before it is served regardless
+const starvationLimit = 8
+
+//callQueue holds the requests waiting for a connection's dispatcher. Requests
+//are served highest priority first and in arrival order within a priority.
+type callQueue struct {
+	mu       sync.Mutex
+	waiting  []*request
+	bypassed int
+	closed   bool
+	//notify wakes the dispatcher when a request is pushed
+	notify chan struct{}
+}
+
+func newCallQueue() *callQueue {
+	return &callQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *callQueue) push(r *request) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return portClosed()
+	}
+	if r.key != "" {
+		for i, w := range q.waiting {
+			if w.key == r.key {
+				//r supersedes the request still waiting with the same key,
+				//taking its place in the queue
+				q.waiting[i] = r
+				w.fail(nil)
+				return nil
+			}
+		}
+	}
+	q.waiting = append(q.waiting, r)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+//pop removes and returns the next request to serve, or nil if none are waiting
+func (q *callQueue) pop() *request {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		return nil
+	}
+	next := 0
+	for i, r := range q.waiting {
+		if r.priority > q.waiting[next].priority {
+			next = i
+		}
+	}
+	if next == 0 || q.bypassed >= starvationLimit {
+		//serve the longest waiting request
+		next = 0
+		q.bypassed = 0
+	} else {
+		q.bypassed++
+	}
+	r := q.waiting[next]
+	q.waiting = append(q.waiting[:next], q.waiting[next+1:]...)
+	return r
+}
+
+//remove takes r out of the queue, reporting whether it was still waiting
+func (q *callQueue) remove(r *request) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, w := range q.waiting {
+		if w == r {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//close rejects further requests and returns those still waiting
+func (q *callQueue) close() []*request {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	waiting := q.waiting
+	q.waiting = nil
+	return waiting
+}
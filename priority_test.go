@@ -0,0 +1,112 @@
+package nango
+
+import (
+	"context"
+	"testing"
+)
+
+//queued pushes a request for each priority onto q, named by its index, and
+//returns them
+func queued(t *testing.T, q *callQueue, names map[*request]int, priorities ...int) []*request {
+	t.Helper()
+	var rs []*request
+	for _, p := range priorities {
+		r := getRequest(context.Background(), p)
+		r.n = 1
+		names[r] = len(names)
+		if err := q.push(r); err != nil {
+			t.Fatal(err)
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+//drain pops every request from q, returning their names in the order served
+func drain(q *callQueue, names map[*request]int) []int {
+	var order []int
+	for r := q.pop(); r != nil; r = q.pop() {
+		order = append(order, names[r])
+	}
+	return order
+}
+
+func equalInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCallQueueOrder(t *testing.T) {
+	q := newCallQueue()
+	names := map[*request]int{}
+	queued(t, q, names, PriorityNormal, PriorityHigh, PriorityLow, PriorityNormal, PriorityHigh)
+	if order := drain(q, names); !equalInts(order, []int{1, 4, 0, 3, 2}) {
+		t.Fatalf("expected highest priority first in arrival order, got %v", order)
+	}
+}
+
+func TestCallQueueStarvation(t *testing.T) {
+	q := newCallQueue()
+	names := map[*request]int{}
+	//the low priority request is overtaken starvationLimit times, then served
+	//ahead of the high priority requests still waiting
+	queued(t, q, names, PriorityLow)
+	for i := 0; i < starvationLimit+2; i++ {
+		queued(t, q, names, PriorityHigh)
+	}
+	expected := []int{1, 2, 3, 4, 5, 6, 7, 8, 0, 9, 10}
+	if order := drain(q, names); !equalInts(order, expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+
+	//serving the longest waiting request resets the count, so a request that
+	//reaches the front later is overtaken starvationLimit times again
+	names = map[*request]int{}
+	queued(t, q, names, PriorityHigh, PriorityLow)
+	for i := 0; i < starvationLimit+1; i++ {
+		queued(t, q, names, PriorityHigh)
+	}
+	expected = []int{0, 2, 3, 4, 5, 6, 7, 8, 9, 1, 10}
+	if order := drain(q, names); !equalInts(order, expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestCallQueueReplace(t *testing.T) {
+	q := newCallQueue()
+	names := map[*request]int{}
+	superseded := queued(t, q, names, PriorityNormal, PriorityNormal)[0]
+	superseded.key = "servo"
+	r := getRequest(context.Background(), PriorityNormal)
+	r.n = 1
+	r.key = "servo"
+	names[r] = 2
+	if err := q.push(r); err != nil {
+		t.Fatal(err)
+	}
+	//the superseded request completes without error and without being sent
+	select {
+	case <-superseded.done:
+	default:
+		t.Fatal("expected the superseded request to be completed")
+	}
+	if len(superseded.results) != 1 || superseded.results[0].err != nil {
+		t.Fatalf("expected a nil result, got %v", superseded.results)
+	}
+	//its replacement takes its place in the queue
+	if order := drain(q, names); !equalInts(order, []int{2, 1}) {
+		t.Fatalf("expected the replacement to be served first, got %v", order)
+	}
+
+	q.close()
+	if err := q.push(r); err == nil {
+		t.Fatal("expected pushing to a closed queue to fail")
+	}
+}
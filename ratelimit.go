@@ -0,0 +1,121 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//ErrRateLimited is returned by calls which exceed the connection's RateLimit
+//when it doesn't Wait
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+//RateLimit caps the rate calls are sent to the firmware at, so a host loop
+//issuing calls as fast as it can doesn't overrun the board's serial receive
+//buffer, only 64 bytes on most AVR boards, and corrupt the stream. Zero
+//rates are unlimited.
+type RateLimit struct {
+	CallsPerSecond float64
+	BytesPerSecond float64
+	//CallBurst and ByteBurst are the number of calls and bytes which may be
+	//sent at once after a quiet period, by default 1 call and 64 bytes. A
+	//call larger than ByteBurst is still sent once the bucket is full.
+	CallBurst int
+	ByteBurst int
+	//Wait makes calls over the limit wait their turn, or until their
+	//context is done. Otherwise they fail immediately with ErrRateLimited.
+	Wait bool
+}
+
+//WithRateLimit limits the rate of calls made on the connection. Calls in a
+//Pipeline count individually but are sent, and so limited, together.
+func WithRateLimit(l RateLimit) Option {
+	return func(s *FirmwareConnection) {
+		if l.CallBurst <= 0 {
+			l.CallBurst = 1
+		}
+		if l.ByteBurst <= 0 {
+			l.ByteBurst = 64
+		}
+		s.limiter = &rateLimiter{
+			wait:  l.Wait,
+			calls: tokenBucket{rate: l.CallsPerSecond, burst: float64(l.CallBurst), tokens: float64(l.CallBurst)},
+			bytes: tokenBucket{rate: l.BytesPerSecond, burst: float64(l.ByteBurst), tokens: float64(l.ByteBurst)},
+		}
+	}
+}
+
+//tokenBucket allows rate tokens a second, up to burst at once. Tokens may be
+//taken beyond those available, leaving a debt which must be repaid before
+//more are taken.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+//take takes n tokens at now, returning how long to wait before using them
+func (b *tokenBucket) take(now time.Time, n float64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	//a take larger than the bucket only waits for it to fill
+	need := n
+	if need > b.burst {
+		need = b.burst
+	}
+	var wait time.Duration
+	if b.tokens < need {
+		wait = time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+	}
+	b.tokens -= n
+	return wait
+}
+
+//rateLimiter applies a RateLimit to a connection
+type rateLimiter struct {
+	mu    sync.Mutex
+	wait  bool
+	calls tokenBucket
+	bytes tokenBucket
+}
+
+//admit waits until n calls of size bytes may be sent, or fails with
+//ErrRateLimited if the limiter doesn't wait
+func (l *rateLimiter) admit(ctx context.Context, clock Clock, n int, size int) error {
+	l.mu.Lock()
+	now := clock.Now()
+	wait := l.calls.take(now, float64(n))
+	if d := l.bytes.take(now, float64(size)); d > wait {
+		wait = d
+	}
+	if wait > 0 && !l.wait {
+		l.calls.tokens += float64(n)
+		l.bytes.tokens += float64(size)
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-clock.After(wait):
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.calls.tokens += float64(n)
+		l.bytes.tokens += float64(size)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := tokenBucket{rate: 10, burst: 2, tokens: 2}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		if d := b.take(now, 1); d != want {
+			t.Fatalf("take %d: expected to wait %s, got %s", i, want, d)
+		}
+	}
+	//the debt is repaid before the bucket refills
+	now = now.Add(300 * time.Millisecond)
+	if d := b.take(now, 1); d != 0 {
+		t.Fatalf("expected no wait after refilling, got %s", d)
+	}
+	//a take larger than the bucket waits for it to fill, then leaves a debt
+	if d := b.take(now, 5); d != 100*time.Millisecond {
+		t.Fatalf("expected to wait for a full bucket, got %s", d)
+	}
+	if d := b.take(now.Add(100*time.Millisecond), 1); d != 400*time.Millisecond {
+		t.Fatalf("expected to wait out the debt, got %s", d)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	open := func(l RateLimit) *FirmwareConnection {
+		lb := NewLoopback()
+		lb.Respond(NamespaceArduino, MethodDigitalRead, "1")
+		conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithRateLimit(l))
+		if err := conn.Open(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	api := NewArduinoApi(open(RateLimit{CallsPerSecond: 10}))
+	if _, err := api.DigitalRead("D2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.DigitalRead("D2"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	api = NewArduinoApi(open(RateLimit{BytesPerSecond: 200, ByteBurst: 10, Wait: true}))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := api.DigitalRead("D2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//each call's 10 byte frame takes 50ms at 200 bytes/s
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected calls held to the byte rate, took %s", elapsed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ArduinoMethodCallContext(ctx, api.FirmwareClass, MethodDigitalRead, "D2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait cut short by the context, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package nango
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RcChannel is the latest pulse measured by the firmware on one receiver channel
+type RcChannel struct {
+	//Width is the pulse width in microseconds, nominally 1000-2000
+	Width int
+	//Age is the time since the last valid pulse was measured
+	Age time.Duration
+	//Failsafe is set when the pulse is stale or out of range
+	Failsafe bool
+}
+
+//Normalized maps Width onto -1 (1000us) to 1 (2000us), clamped
+func (c RcChannel) Normalized() float64 {
+	v := float64(c.Width-1500) / 500
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+//RcReceiver decodes RC receiver outputs in firmware using pin change
+//interrupts, either one PWM signal per channel or a single PPM stream
+type RcReceiver struct {
+	*FirmwareClass
+	//MinPulse and MaxPulse bound the pulse widths in microseconds considered valid
+	MinPulse int
+	MaxPulse int
+	//FailsafeTimeout is how old the last pulse may be before the channel is
+	//considered lost
+	FailsafeTimeout time.Duration
+}
+
+func NewRcReceiver(conn Conn) *RcReceiver {
+	return &RcReceiver{
+		FirmwareClass: &FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceRC,
+		},
+		MinPulse:        800,
+		MaxPulse:        2200,
+		FailsafeTimeout: 100 * time.Millisecond,
+	}
+}
+
+//AttachPWM measures the PWM output of channel on pin
+func (r *RcReceiver) AttachPWM(channel int, pin string) error {
+	return r.CallAndReturnNothing(MethodRCAttach, channel, pin)
+}
+
+//BeginPPM decodes a PPM stream carrying the given number of channels on pin
+func (r *RcReceiver) BeginPPM(pin string, channels int) error {
+	return r.CallAndReturnNothing(MethodRCBeginPPM, pin, channels)
+}
+
+//Detach stops measuring all channels
+func (r *RcReceiver) Detach() error {
+	return r.CallAndReturnNothing(MethodRCDetach)
+}
+
+//Channel returns the latest measurement for channel
+func (r *RcReceiver) Channel(channel int) (RcChannel, error) {
+	s, err := r.call(MethodRCRead, channel)
+	if err != nil {
+		return RcChannel{}, err
+	}
+	c, err := r.parseChannel(s)
+	if err != nil {
+		return RcChannel{}, r.responseError(MethodRCRead, s, err)
+	}
+	return c, nil
+}
+
+//Channels returns the latest measurement for every channel in a single call
+func (r *RcReceiver) Channels() ([]RcChannel, error) {
+	s, err := r.call(MethodRCReadAll)
+	if err != nil {
+		return nil, err
+	}
+	chs := make([]RcChannel, 0)
+	if s == "" {
+		return chs, nil
+	}
+	for _, f := range strings.Split(s, ";") {
+		c, err := r.parseChannel(f)
+		if err != nil {
+			return nil, r.responseError(MethodRCReadAll, s, err)
+		}
+		chs = append(chs, c)
+	}
+	return chs, nil
+}
+
+//Failsafe reports whether any channel has lost signal
+func (r *RcReceiver) Failsafe() (bool, error) {
+	chs, err := r.Channels()
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chs {
+		if c.Failsafe {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//parseChannel decodes a "width,ageMillis" measurement
+func (r *RcReceiver) parseChannel(s string) (c RcChannel, err error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 {
+		err = fmt.Errorf("malformed channel measurement %q", s)
+		return
+	}
+	c.Width, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return
+	}
+	age, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return
+	}
+	c.Age = time.Duration(age) * time.Millisecond
+	c.Failsafe = c.Width < r.MinPulse || c.Width > r.MaxPulse || c.Age > r.FailsafeTimeout
+	return
+}
@@ -0,0 +1,267 @@
+package nango
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Namespaces of the firmware classes wrapped by this package
+const (
+	NamespaceArduino        = "A"
+	NamespaceWire           = "Wire"
+	NamespaceSPI            = "SPI"
+	NamespaceEEPROM         = "EEPROM"
+	NamespaceServo          = "Servo"
+	NamespaceOneWire        = "OneWire"
+	NamespaceSoftwareSerial = "SoftwareSerial"
+	//NamespaceHardwareSerial is followed by the port number, e.g. Serial1
+	NamespaceHardwareSerial = "Serial"
+	NamespaceCAN            = "CAN"
+	NamespaceCounter        = "Counter"
+	NamespaceDmx            = "DmxSimple"
+	NamespaceRC             = "RC"
+	NamespaceSampler        = "Sampler"
+	NamespaceStepper        = "Stepper"
+	NamespacePower          = "Power"
+	//NamespaceOTA updates the firmware of boards which can rewrite their own
+	//flash, such as the ESP32
+	NamespaceOTA = "OTA"
+	//NamespaceAuth authenticates connections made WithSharedSecret
+	NamespaceAuth = "Auth"
+	//NamespaceInfo lists the classes compiled into the firmware
+	NamespaceInfo = "Info"
+)
+
+//StaticId is the object id used to call firmware classes which are not
+//instantiated with NewFirmwareObject
+const StaticId = 0
+
+//Methods of the Arduino class. Their names are abbreviated to keep the
+//frames of the most frequent calls short.
+const (
+	MethodDigitalWrite = "dw"
+	MethodDigitalRead  = "r"
+	MethodAnalogWrite  = "aw"
+	MethodAnalogRead   = "a"
+	MethodPinMode      = "pm"
+	MethodMillis       = "m"
+	MethodPulseIn      = "pi"
+	MethodShiftOut     = "s"
+	//MethodDelay blocks the firmware for a number of milliseconds. It is
+	//not part of Nanpy and is only needed by Transaction.Delay.
+	MethodDelay = "d"
+)
+
+//Methods of the Power class
+const (
+	//MethodSleep puts the board to sleep in a SleepMode for a number of
+	//milliseconds, optionally waking on serial activity
+	MethodSleep = "sleep"
+)
+
+//Methods of the Wire class
+const (
+	MethodWireBegin             = "begin"
+	MethodWireRequestFrom       = "requestFrom"
+	MethodWireBeginTransmission = "beginTransmission"
+	MethodWireEndTransmission   = "endTransmission"
+	MethodWireWrite             = "write"
+	MethodWireAvailable         = "available"
+	MethodWireRead              = "read"
+)
+
+//Methods of the SPI class
+const (
+	MethodSPIBegin            = "begin"
+	MethodSPIEnd              = "end"
+	MethodSPIBeginTransaction = "beginTransaction"
+	MethodSPIEndTransaction   = "endTransaction"
+	MethodSPITransfer         = "transfer"
+	MethodSPITransferBytes    = "transferBytes"
+)
+
+//Methods of the OneWire class
+const (
+	MethodOneWireReset       = "reset"
+	MethodOneWireSelect      = "select"
+	MethodOneWireSkip        = "skip"
+	MethodOneWireWrite       = "write"
+	MethodOneWireWriteBytes  = "writeBytes"
+	MethodOneWireRead        = "read"
+	MethodOneWireReadBytes   = "readBytes"
+	MethodOneWireDepower     = "depower"
+	MethodOneWireResetSearch = "reset_search"
+	MethodOneWireSearch      = "search"
+)
+
+//Methods of the EEPROM class
+const (
+	MethodEEPROMSize       = "size"
+	MethodEEPROMRead       = "read"
+	MethodEEPROMWrite      = "write"
+	MethodEEPROMReadBytes  = "readBytes"
+	MethodEEPROMWriteBytes = "writeBytes"
+)
+
+//Methods of the Servo class
+const (
+	MethodServoWrite             = "write"
+	MethodServoRead              = "read"
+	MethodServoWriteMicroseconds = "writeMicroseconds"
+	MethodServoReadMicroseconds  = "readMicroseconds"
+	MethodServoAttached          = "attached"
+	MethodServoDetach            = "detach"
+)
+
+//Methods of serial ports: the SoftwareSerial and Serial classes
+const (
+	MethodStreamBegin     = "begin"
+	MethodStreamAvailable = "available"
+	MethodStreamRead      = "read"
+	MethodStreamReadBytes = "readBytes"
+	MethodStreamWrite     = "write"
+	MethodStreamEnd       = "end"
+)
+
+//Methods of the SoftwareSerial class besides the Stream methods
+const (
+	MethodSoftwareSerialListen = "listen"
+)
+
+//Methods of the CAN class
+const (
+	MethodCANBegin   = "begin"
+	MethodCANEnd     = "end"
+	MethodCANSend    = "send"
+	MethodCANReceive = "receive"
+	MethodCANState   = "state"
+	MethodCANRecover = "recover"
+)
+
+//Methods of the Counter class
+const (
+	MethodCounterAttach       = "attach"
+	MethodCounterDetach       = "detach"
+	MethodCounterRead         = "read"
+	MethodCounterReadAndReset = "readAndReset"
+)
+
+//Methods of the DmxSimple class
+const (
+	MethodDmxUsePin     = "usePin"
+	MethodDmxMaxChannel = "maxChannel"
+	MethodDmxWrite      = "write"
+	MethodDmxWriteRange = "writeRange"
+)
+
+//Methods of the RC class
+const (
+	MethodRCAttach   = "attach"
+	MethodRCBeginPPM = "beginPPM"
+	MethodRCDetach   = "detach"
+	MethodRCRead     = "read"
+	MethodRCReadAll  = "readAll"
+)
+
+//Methods of the Sampler class
+const (
+	MethodSamplerStart  = "start"
+	MethodSamplerStop   = "stop"
+	MethodSamplerPause  = "pause"
+	MethodSamplerResume = "resume"
+	MethodSamplerFetch  = "fetch"
+)
+
+//Methods of the Stepper class
+const (
+	MethodStepperSetSpeed = "setSpeed"
+	MethodStepperStep     = "step"
+)
+
+//Methods of the OTA class
+const (
+	MethodOTABegin = "begin"
+	MethodOTAWrite = "write"
+	MethodOTAEnd   = "end"
+	MethodOTAAbort = "abort"
+)
+
+//Methods of the Auth class
+const (
+	MethodAuthChallenge = "challenge"
+	MethodAuthRespond   = "respond"
+)
+
+//Methods of the Info class
+const (
+	MethodInfoCount = "count"
+	MethodInfoName  = "name"
+)
+
+//Methods shared by every firmware class
+const (
+	MethodNew    = "new"
+	MethodRemove = "remove"
+)
+
+//FirmwareClasses returns the namespaces of the classes compiled into the
+//firmware
+func (s *FirmwareConnection) FirmwareClasses() ([]string, error) {
+	info := &FirmwareClass{Conn: s, Id: StaticId, Namespace: NamespaceInfo}
+	n, err := info.CallAndReturnInt(MethodInfoCount)
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]string, n)
+	for i := range classes {
+		classes[i], err = info.call(MethodInfoName, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return classes, nil
+}
+
+//MissingClassesError is returned by Open when the firmware lacks classes
+//required with WithRequiredClasses
+type MissingClassesError struct {
+	Missing []string
+}
+
+func (e *MissingClassesError) Error() string {
+	return fmt.Sprintf("firmware is missing required classes: %s", strings.Join(e.Missing, ", "))
+}
+
+//WithRequiredClasses makes Open check that the firmware provides the given
+//classes, so a mismatched firmware fails at connect time rather than with a
+//timeout on first use
+func WithRequiredClasses(namespaces ...string) Option {
+	return func(s *FirmwareConnection) {
+		s.requiredClasses = namespaces
+	}
+}
+
+//checkClasses verifies the firmware provides the required classes
+func (s *FirmwareConnection) checkClasses() error {
+	if len(s.requiredClasses) == 0 {
+		return nil
+	}
+	classes, err := s.FirmwareClasses()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(classes))
+	for _, c := range classes {
+		have[c] = true
+	}
+	var missing []string
+	for _, c := range s.requiredClasses {
+		if !have[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingClassesError{Missing: missing}
+	}
+	return nil
+}
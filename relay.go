@@ -0,0 +1,202 @@
+package nango
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//Relay describes a relay of a RelayBank
+type Relay struct {
+	Name string
+	Pin  string
+	//ActiveLow is set for relay modules energized by driving the pin low
+	ActiveLow bool
+	//MinDwell is the minimum time the relay stays in a state before it may
+	//be switched again, protecting contacts and loads such as compressors
+	MinDwell time.Duration
+}
+
+type relayState struct {
+	Relay
+	on      bool
+	changed time.Time
+}
+
+//RelayInterlockError is returned when energizing a relay would energize it
+//together with another relay it is interlocked with
+type RelayInterlockError struct {
+	Relay    string
+	Conflict string
+}
+
+func (e *RelayInterlockError) Error() string {
+	return fmt.Sprintf("relay %s is interlocked with %s, which is on", e.Relay, e.Conflict)
+}
+
+//RelayDwellError is returned when a relay is switched before its minimum
+//dwell time has passed
+type RelayDwellError struct {
+	Relay     string
+	Remaining time.Duration
+}
+
+func (e *RelayDwellError) Error() string {
+	return fmt.Sprintf("relay %s can't be switched for another %s", e.Relay, e.Remaining)
+}
+
+//RelayBank switches a set of named relays, enforcing interlocks between
+//relays which must never be on together, e.g. the open and close windings
+//of a valve actuator, and minimum dwell times
+type RelayBank struct {
+	api    *ArduinoApi
+	clock  Clock
+	mu     sync.Mutex
+	relays map[string]*relayState
+	groups [][]string
+}
+
+//NewRelayBank switches the relays off and configures their pins as outputs.
+//The off level is written while the pins are still inputs, so an active low
+//relay isn't energized by the pin coming up as a low output.
+func NewRelayBank(api *ArduinoApi, relays ...Relay) (*RelayBank, error) {
+	b := &RelayBank{
+		api:    api,
+		clock:  clockOf(api.Conn),
+		relays: make(map[string]*relayState),
+	}
+	for _, r := range relays {
+		b.relays[r.Name] = &relayState{Relay: r}
+	}
+	for _, r := range relays {
+		if err := b.write(b.relays[r.Name], false); err != nil {
+			return nil, err
+		}
+		if err := api.PinMode(r.Pin, PinOutput); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+//Interlock prevents more than one of the named relays being on at a time
+func (b *RelayBank) Interlock(names ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, name := range names {
+		if _, ok := b.relays[name]; !ok {
+			return fmt.Errorf("unknown relay %s", name)
+		}
+	}
+	b.groups = append(b.groups, names)
+	return nil
+}
+
+func (b *RelayBank) relay(name string) (*relayState, error) {
+	r, ok := b.relays[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown relay %s", name)
+	}
+	return r, nil
+}
+
+//conflict returns the relay interlocked with name which is on, if any
+func (b *RelayBank) conflict(name string) string {
+	for _, g := range b.groups {
+		in := false
+		for _, n := range g {
+			in = in || n == name
+		}
+		if !in {
+			continue
+		}
+		for _, n := range g {
+			if n != name && b.relays[n].on {
+				return n
+			}
+		}
+	}
+	return ""
+}
+
+//write drives the pin of r to on, ignoring its dwell time
+func (b *RelayBank) write(r *relayState, on bool) error {
+	val := PinLow
+	if on != r.ActiveLow {
+		val = PinHigh
+	}
+	if err := b.api.DigitalWrite(r.Pin, val); err != nil {
+		return err
+	}
+	if r.on != on || r.changed.IsZero() {
+		r.changed = b.clock.Now()
+	}
+	r.on = on
+	return nil
+}
+
+//Set switches the relay called name on or off. It fails without switching
+//the relay if it would violate an interlock or the relay's dwell time.
+func (b *RelayBank) Set(name string, on bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.relay(name)
+	if err != nil {
+		return err
+	}
+	if r.on == on {
+		return nil
+	}
+	if on {
+		if c := b.conflict(name); c != "" {
+			return &RelayInterlockError{Relay: name, Conflict: c}
+		}
+	}
+	if elapsed := b.clock.Now().Sub(r.changed); elapsed < r.MinDwell {
+		return &RelayDwellError{Relay: name, Remaining: r.MinDwell - elapsed}
+	}
+	return b.write(r, on)
+}
+
+//On energizes the relay called name
+func (b *RelayBank) On(name string) error {
+	return b.Set(name, true)
+}
+
+//Off de-energizes the relay called name
+func (b *RelayBank) Off(name string) error {
+	return b.Set(name, false)
+}
+
+//State reports whether the relay called name is on
+func (b *RelayBank) State(name string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.relay(name)
+	if err != nil {
+		return false, err
+	}
+	return r.on, nil
+}
+
+//AllOff switches every relay off regardless of dwell times, returning the
+//first error encountered
+func (b *RelayBank) AllOff() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var err error
+	for _, r := range b.relays {
+		if errOff := b.write(r, false); err == nil {
+			err = errOff
+		}
+	}
+	return err
+}
+
+//SafeState switches every relay off. It is a ShutdownHook, e.g.
+//
+//	conn.SafeState("relays", bank.SafeState)
+func (b *RelayBank) SafeState(ctx context.Context) error {
+	return b.AllOff()
+}
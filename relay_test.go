@@ -0,0 +1,69 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelayBank(t *testing.T) {
+	lb, conn := openLoopback(t)
+	pins := map[string]string{}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, func(c LoopbackCall) (string, bool) {
+		pins[c.Args[0]] = c.Args[1]
+		return "", true
+	})
+	b, err := NewRelayBank(NewArduinoApi(conn),
+		Relay{Name: "open", Pin: "D4", ActiveLow: true},
+		Relay{Name: "close", Pin: "D5", ActiveLow: true},
+		Relay{Name: "pump", Pin: "D6", MinDwell: time.Hour},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pins["D4"] != "1" || pins["D5"] != "1" || pins["D6"] != "0" {
+		t.Fatalf("expected all relays off, got %v", pins)
+	}
+	if err := b.Interlock("open", "close"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.On("open"); err != nil || pins["D4"] != "0" {
+		t.Fatalf("expected open energized, got %v (%v)", pins, err)
+	}
+	var interlock *RelayInterlockError
+	if err := b.On("close"); !errors.As(err, &interlock) || interlock.Conflict != "open" {
+		t.Fatalf("expected interlock error, got %v", err)
+	}
+	var dwell *RelayDwellError
+	if err := b.On("pump"); !errors.As(err, &dwell) {
+		t.Fatalf("expected dwell error, got %v", err)
+	}
+	if err := b.SafeState(context.Background()); err != nil || pins["D4"] != "1" {
+		t.Fatalf("expected all relays off, got %v (%v)", pins, err)
+	}
+}
+
+func TestRelayBankPowerUpOrder(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var calls []string
+	record := func(c LoopbackCall) (string, bool) {
+		calls = append(calls, c.Method+" "+strings.Join(c.Args, " "))
+		return "", true
+	}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, record)
+	lb.Handle(NamespaceArduino, MethodPinMode, record)
+	_, err := NewRelayBank(NewArduinoApi(conn),
+		Relay{Name: "open", Pin: "D4", ActiveLow: true},
+		Relay{Name: "close", Pin: "D5", ActiveLow: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the inactive level must be written before the pin becomes an output
+	want := "dw D4 1,pm D4 1,dw D5 1,pm D5 1"
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,175 @@
+package nango
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+//WheelEncoder measures the travel of a wheel with a single channel encoder
+//counted by a Counter. Single channel encoders don't sense direction, so it
+//is taken from the direction the wheel is driven in.
+type WheelEncoder struct {
+	Counter *Counter
+	Pin     string
+	//DistancePerTick is the distance travelled per counted edge, in units of
+	//the drive's TrackWidth
+	DistancePerTick float64
+}
+
+//Pose is a position and heading estimated by dead reckoning. Heading is in
+//radians counter-clockwise from the X axis.
+type Pose struct {
+	X       float64
+	Y       float64
+	Heading float64
+}
+
+//Rover drives a differential drive robot with velocity commands, estimating
+//its pose from wheel encoders, or from the commanded speeds if it has none.
+//Once started, its failsafe stops the motors if no command is received for
+//the Failsafe period, so a control loop which stops sending commands can't
+//leave it driving. The failsafe runs on the host's Scheduler: it can't stop
+//the rover if the host process crashes or the link to the board drops.
+type Rover struct {
+	Drive *DifferentialDrive
+	//Left and Right are optional encoders
+	Left  *WheelEncoder
+	Right *WheelEncoder
+	//Failsafe is how long the rover keeps driving without a command. Zero
+	//disables the failsafe.
+	Failsafe time.Duration
+
+	clock      Clock
+	mu         sync.Mutex
+	pose       Pose
+	left       float64 //commanded duty cycles
+	right      float64
+	commanded  time.Time
+	updated    time.Time
+	failsafing bool
+}
+
+//NewRover returns a stopped Rover timing its failsafe with the clock of conn
+func NewRover(conn Conn, drive *DifferentialDrive) *Rover {
+	return &Rover{Drive: drive, clock: clockOf(conn)}
+}
+
+//Velocity drives at speed while turning at rate radians per second
+func (r *Rover) Velocity(speed float64, rate float64) error {
+	l, rt := r.Drive.wheelSpeeds(speed, rate)
+	return r.tank(l, rt)
+}
+
+//TurnInPlace spins at rate radians per second, positive counter-clockwise
+func (r *Rover) TurnInPlace(rate float64) error {
+	return r.Velocity(0, rate)
+}
+
+func (r *Rover) tank(left float64, right float64) error {
+	r.mu.Lock()
+	r.commanded = r.clock.Now()
+	r.failsafing = false
+	r.mu.Unlock()
+	//update the odometry with the previous speeds before changing them
+	if err := r.Update(context.Background()); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.left, r.right = left, right
+	r.mu.Unlock()
+	return r.Drive.Tank(left, right)
+}
+
+//Stop brakes the motors
+func (r *Rover) Stop() error {
+	if err := r.Update(context.Background()); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.left, r.right = 0, 0
+	r.mu.Unlock()
+	return r.Drive.Stop()
+}
+
+//Pose returns the estimated pose
+func (r *Rover) Pose() Pose {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pose
+}
+
+//ResetPose sets the estimated pose, e.g. to the origin
+func (r *Rover) ResetPose(p Pose) {
+	r.mu.Lock()
+	r.pose = p
+	r.mu.Unlock()
+}
+
+//Failsafing reports whether the failsafe has stopped the rover since the
+//last command
+func (r *Rover) Failsafing() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failsafing
+}
+
+//travel returns the distance a wheel driven at duty has covered since the
+//last update
+func (r *Rover) travel(e *WheelEncoder, duty float64, dt time.Duration) (float64, error) {
+	if e == nil {
+		return duty * r.Drive.MaxSpeed * dt.Seconds(), nil
+	}
+	p, err := e.Counter.ReadAndReset(e.Pin)
+	if err != nil {
+		return 0, err
+	}
+	return math.Copysign(float64(p.Count)*e.DistancePerTick, duty), nil
+}
+
+//Update integrates the pose and stops the rover if the failsafe period has
+//passed since the last command
+func (r *Rover) Update(ctx context.Context) error {
+	r.mu.Lock()
+	now := r.clock.Now()
+	dt := now.Sub(r.updated)
+	if r.updated.IsZero() {
+		dt = 0
+	}
+	r.updated = now
+	left, right := r.left, r.right
+	expired := r.Failsafe > 0 && !r.commanded.IsZero() && !r.failsafing &&
+		now.Sub(r.commanded) > r.Failsafe && (left != 0 || right != 0)
+	r.mu.Unlock()
+
+	dl, err := r.travel(r.Left, left, dt)
+	if err != nil {
+		return err
+	}
+	dr, err := r.travel(r.Right, right, dt)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	d := (dl + dr) / 2
+	dh := (dr - dl) / r.Drive.TrackWidth
+	r.pose.X += d * math.Cos(r.pose.Heading+dh/2)
+	r.pose.Y += d * math.Sin(r.pose.Heading+dh/2)
+	r.pose.Heading = math.Remainder(r.pose.Heading+dh, 2*math.Pi)
+	if expired {
+		r.failsafing = true
+		r.left, r.right = 0, 0
+	}
+	r.mu.Unlock()
+	if expired {
+		return r.Drive.Stop()
+	}
+	return nil
+}
+
+//Start updates the rover every interval on s as the task called name. The
+//interval bounds how late the failsafe may trip.
+func (r *Rover) Start(s *Scheduler, name string, interval time.Duration) {
+	s.Every(name, interval, r.Update)
+}
@@ -0,0 +1,63 @@
+package nango_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func newTestRover(t *testing.T) (*nango.Rover, *nangotest.Clock) {
+	t.Helper()
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	_, conn := openClocked(t, clock)
+	api := nango.NewArduinoApi(conn)
+	left, err := nango.NewDCMotor(api, "D4", "D5", "D6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := nango.NewDCMotor(api, "D7", "D8", "D9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nango.NewRover(conn, &nango.DifferentialDrive{Left: left, Right: right, TrackWidth: 0.2, MaxSpeed: 1}), clock
+}
+
+func TestRoverDeadReckoning(t *testing.T) {
+	r, clock := newTestRover(t)
+	r.Velocity(0.5, 0)
+	clock.Advance(2 * time.Second)
+	r.Stop()
+	p := r.Pose()
+	if p.X != 1 || p.Y != 0 || p.Heading != 0 {
+		t.Fatalf("expected 1m of straight travel along X, got %+v", p)
+	}
+	r.ResetPose(nango.Pose{})
+	r.TurnInPlace(1)
+	clock.Advance(time.Second)
+	r.Stop()
+	p = r.Pose()
+	if p.X != 0 || p.Y != 0 || p.Heading <= 0 {
+		t.Fatalf("expected counter-clockwise turn in place, got %+v", p)
+	}
+}
+
+func TestRoverFailsafe(t *testing.T) {
+	r, clock := newTestRover(t)
+	r.Failsafe = 5 * time.Millisecond
+	r.Velocity(0.5, 0)
+	clock.Advance(5 * time.Millisecond)
+	if err := r.Update(context.Background()); err != nil || r.Failsafing() {
+		t.Fatalf("failsafe tripped early (%v)", err)
+	}
+	clock.Advance(time.Millisecond)
+	if err := r.Update(context.Background()); err != nil || !r.Failsafing() {
+		t.Fatalf("expected failsafe to trip (%v)", err)
+	}
+	r.Velocity(0.5, 0)
+	if r.Failsafing() {
+		t.Fatal("expected a command to clear the failsafe")
+	}
+}
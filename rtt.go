@@ -0,0 +1,105 @@
+package nango
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//rttWindow is the number of recent round trip times kept per connection
+const rttWindow = 64
+
+//rttMinSamples is the number of round trips observed before an adaptive
+//timeout replaces ReadTimeout
+const rttMinSamples = 16
+
+//AdaptiveTimeout derives a connection's response timeout from the round trip
+//times recently observed on it rather than the fixed ReadTimeout, so fast
+//links fail fast and slow links such as radio bridges don't time out
+//spuriously. Calls which time out are counted as taking the whole timeout, so
+//the timeout grows again after the link slows down rather than failing every
+//call. Calls which legitimately take long in firmware, such as PulseIn,
+//should be accounted for with Min.
+type AdaptiveTimeout struct {
+	//Multiplier is applied to the 95th percentile round trip time
+	Multiplier float64
+	//Min and Max bound the derived timeout
+	Min time.Duration
+	Max time.Duration
+}
+
+//DefaultAdaptiveTimeout allows four times the p95 round trip time, between
+//50ms and 10s
+var DefaultAdaptiveTimeout = AdaptiveTimeout{
+	Multiplier: 4,
+	Min:        50 * time.Millisecond,
+	Max:        10 * time.Second,
+}
+
+//rttTracker keeps a window of recent round trip times, along with a sorted
+//copy so the percentile is cheap to read on every call
+type rttTracker struct {
+	mu      sync.Mutex
+	samples [rttWindow]time.Duration
+	sorted  [rttWindow]time.Duration
+	n       int
+	next    int
+}
+
+func (t *rttTracker) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sorted := t.sorted[:t.n]
+	if t.n == rttWindow {
+		//drop the sample leaving the window from the sorted copy
+		i := sort.Search(t.n, func(i int) bool { return sorted[i] >= t.samples[t.next] })
+		copy(sorted[i:], sorted[i+1:])
+		sorted = sorted[:t.n-1]
+	} else {
+		t.n++
+	}
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % rttWindow
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] > d })
+	sorted = t.sorted[:len(sorted)+1]
+	copy(sorted[i+1:], sorted[i:])
+	sorted[i] = d
+}
+
+//percentile returns the q (0-1) percentile of the window and the number of
+//samples it is based on
+func (t *rttTracker) percentile(q float64) (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.n == 0 {
+		return 0, 0
+	}
+	return t.sorted[int(q*float64(t.n-1))], t.n
+}
+
+//RTT returns the 95th percentile of the round trip times recently observed on
+//the connection, or 0 if none have been observed
+func (s *FirmwareConnection) RTT() time.Duration {
+	d, _ := s.rtt.percentile(0.95)
+	return d
+}
+
+//readTimeout returns the timeout to wait for a response with
+func (s *FirmwareConnection) readTimeout() time.Duration {
+	a := s.AdaptiveTimeout
+	if a == nil {
+		return s.ReadTimeout
+	}
+	p95, n := s.rtt.percentile(0.95)
+	if n < rttMinSamples {
+		return s.ReadTimeout
+	}
+	d := time.Duration(float64(p95) * a.Multiplier)
+	if d < a.Min {
+		d = a.Min
+	}
+	if a.Max > 0 && d > a.Max {
+		d = a.Max
+	}
+	return d
+}
@@ -0,0 +1,107 @@
+package nango_test
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+//slowLink delays the responses of the transport it wraps by its latency,
+//advancing the clock while the connection polls for them
+type slowLink struct {
+	nango.Transport
+	clock   *nangotest.Clock
+	latency int64 //time.Duration, set by the test while calls run
+	sent    time.Time
+}
+
+func (l *slowLink) Write(b []byte) (int, error) {
+	l.sent = l.clock.Now()
+	return l.Transport.Write(b)
+}
+
+func (l *slowLink) Read(b []byte) (int, error) {
+	if l.clock.Now().Before(l.sent.Add(time.Duration(atomic.LoadInt64(&l.latency)))) {
+		l.clock.Advance(time.Millisecond)
+		return 0, io.EOF
+	}
+	return l.Transport.Read(b)
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb := nango.NewLoopback()
+	lb.Respond("T", "get", "1")
+	link := &slowLink{clock: clock}
+	dial := func() (nango.Transport, error) {
+		tr, err := lb.Dial()
+		link.Transport = tr
+		return link, err
+	}
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(dial), nango.WithClock(clock),
+		nango.WithReadTimeout(time.Second),
+		nango.WithAdaptiveTimeout(nango.AdaptiveTimeout{Multiplier: 2, Min: 20 * time.Millisecond, Max: 200 * time.Millisecond}))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &nango.FirmwareClass{Conn: conn, Namespace: "T"}
+	//call makes a call with the given latency and returns how long it took
+	call := func(latency time.Duration) (time.Duration, error) {
+		atomic.StoreInt64(&link.latency, int64(latency))
+		start := clock.Now()
+		_, err := f.CallAndReturnInt("get")
+		return clock.Now().Sub(start), err
+	}
+
+	//ReadTimeout is used until enough round trips have been observed
+	if _, err := call(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := call(5 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rtt := conn.RTT(); rtt != 5*time.Millisecond {
+		t.Fatalf("expected a 5ms rtt, got %s", rtt)
+	}
+
+	//twice the 5ms rtt is raised to Min
+	elapsed, err := call(30 * time.Millisecond)
+	if !errors.Is(err, nango.ErrTimeout) || elapsed != 20*time.Millisecond {
+		t.Fatalf("expected a timeout after 20ms, got %v after %s", err, elapsed)
+	}
+
+	//after a latency step the timeouts raise the timeout until calls succeed
+	//again, without it exceeding Max
+	recovered := false
+	for i := 0; i < 100 && !recovered; i++ {
+		elapsed, err = call(150 * time.Millisecond)
+		switch {
+		case err == nil:
+			recovered = true
+		case !errors.Is(err, nango.ErrTimeout):
+			t.Fatal(err)
+		case elapsed > 200*time.Millisecond:
+			t.Fatalf("expected the timeout bounded by Max, waited %s", elapsed)
+		}
+	}
+	if !recovered {
+		t.Fatal("expected calls to succeed again after the latency step")
+	}
+	for i := 0; i < 20; i++ {
+		elapsed, err = call(300 * time.Millisecond)
+		if !errors.Is(err, nango.ErrTimeout) || elapsed > 200*time.Millisecond {
+			t.Fatalf("expected a timeout bounded by Max, got %v after %s", err, elapsed)
+		}
+	}
+	if elapsed != 200*time.Millisecond {
+		t.Fatalf("expected the timeout to reach Max, waited %s", elapsed)
+	}
+}
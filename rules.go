@@ -0,0 +1,180 @@
+package nango
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+//Condition tests a reading's value
+type Condition func(v float64) bool
+
+//Above is true for values greater than threshold
+func Above(threshold float64) Condition {
+	return func(v float64) bool { return v > threshold }
+}
+
+//Below is true for values less than threshold
+func Below(threshold float64) Condition {
+	return func(v float64) bool { return v < threshold }
+}
+
+//Action is run by a rule when it activates or deactivates
+type Action func(ctx context.Context) error
+
+//SetPin writes val to the digital pin
+func SetPin(api *ArduinoApi, pin string, val int) Action {
+	return func(ctx context.Context) error {
+		return api.DigitalWrite(pin, val)
+	}
+}
+
+//Rule automates a response to the readings of a source, e.g. "when A0 > 700
+//for 5s set D7 HIGH, clear it when A0 < 650":
+//
+//	Rule{
+//		Name:       "fan",
+//		Source:     "A0",
+//		On:         Above(700),
+//		For:        5 * time.Second,
+//		Off:        Below(650),
+//		Activate:   SetPin(api, "D7", 1),
+//		Deactivate: SetPin(api, "D7", 0),
+//	}
+//
+//Using an Off condition distinct from On gives hysteresis, and For and
+//OffFor debounce the rule against brief excursions.
+type Rule struct {
+	Name   string
+	Source string
+	On     Condition
+	//For is how long On must hold before the rule activates
+	For time.Duration
+	//Off deactivates the rule. If nil the rule deactivates when On no longer
+	//holds.
+	Off Condition
+	//OffFor is how long Off must hold before the rule deactivates
+	OffFor     time.Duration
+	Activate   Action
+	Deactivate Action
+}
+
+type ruleState struct {
+	Rule
+	active bool
+	since  time.Time //when the pending transition's condition started holding
+}
+
+//off reports whether v deactivates the rule
+func (r *ruleState) off(v float64) bool {
+	if r.Off == nil {
+		return !r.On(v)
+	}
+	return r.Off(v)
+}
+
+//RuleEngine evaluates rules against the readings published by a Poller
+type RuleEngine struct {
+	//OnError, if set, is called with the errors returned by actions instead
+	//of logging them
+	OnError func(rule string, err error)
+
+	mu    sync.Mutex
+	rules []*ruleState
+	unsub func()
+}
+
+//NewRuleEngine returns a RuleEngine evaluating readings published by p
+func NewRuleEngine(p *Poller) *RuleEngine {
+	e := &RuleEngine{}
+	e.unsub = p.Subscribe(e.Evaluate)
+	return e
+}
+
+//Add adds r, which starts inactive. A rule already added with the same name
+//is replaced.
+func (e *RuleEngine) Add(r Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, s := range e.rules {
+		if s.Name == r.Name {
+			e.rules[i] = &ruleState{Rule: r}
+			return
+		}
+	}
+	e.rules = append(e.rules, &ruleState{Rule: r})
+}
+
+//Active reports whether the rule called name is active
+func (e *RuleEngine) Active(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.rules {
+		if s.Name == name {
+			return s.active
+		}
+	}
+	return false
+}
+
+//Evaluate updates the rules of the reading's source. Failed readings are
+//ignored.
+func (e *RuleEngine) Evaluate(r Reading) {
+	if r.Err != nil {
+		return
+	}
+	type transition struct {
+		name   string
+		action Action
+	}
+	var actions []transition
+	e.mu.Lock()
+	for _, s := range e.rules {
+		if s.Source != r.Source {
+			continue
+		}
+		holds, hold := s.On(r.Value), s.For
+		if s.active {
+			holds, hold = s.off(r.Value), s.OffFor
+		}
+		if !holds {
+			s.since = time.Time{}
+			continue
+		}
+		if s.since.IsZero() {
+			s.since = r.Time
+		}
+		if r.Time.Sub(s.since) < hold {
+			continue
+		}
+		s.active = !s.active
+		s.since = time.Time{}
+		action := s.Deactivate
+		if s.active {
+			action = s.Activate
+		}
+		if action != nil {
+			actions = append(actions, transition{s.Name, action})
+		}
+	}
+	e.mu.Unlock()
+	for _, t := range actions {
+		if err := t.action(context.Background()); err != nil {
+			e.report(t.name, err)
+		}
+	}
+}
+
+func (e *RuleEngine) report(rule string, err error) {
+	if e.OnError != nil {
+		e.OnError(rule, err)
+		return
+	}
+	log.Printf("rule %s: %s", rule, err)
+}
+
+//Close stops evaluating readings
+func (e *RuleEngine) Close() {
+	e.unsub()
+}
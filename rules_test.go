@@ -0,0 +1,46 @@
+package nango
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRuleHysteresisAndDebounce(t *testing.T) {
+	e := NewRuleEngine(NewPoller(NewScheduler(nil)))
+	defer e.Close()
+	var out []int
+	set := func(v int) Action {
+		return func(ctx context.Context) error {
+			out = append(out, v)
+			return nil
+		}
+	}
+	e.Add(Rule{
+		Name:       "fan",
+		Source:     "A0",
+		On:         Above(700),
+		For:        5 * time.Second,
+		Off:        Below(650),
+		Activate:   set(1),
+		Deactivate: set(0),
+	})
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []float64{
+		710, 720, 600, //excursion shorter than For
+		710, 720, 730, 740, 750, 760, //held for 5s
+		680, 690, //inside the hysteresis band
+		640, //off immediately
+	} {
+		e.Evaluate(Reading{Source: "A0", Time: start.Add(time.Duration(i) * time.Second), Value: v})
+		if i == 7 && e.Active("fan") {
+			t.Fatal("activated before the condition held for 5s")
+		}
+		if i == 10 && !e.Active("fan") {
+			t.Fatal("deactivated inside the hysteresis band")
+		}
+	}
+	if len(out) != 2 || out[0] != 1 || out[1] != 0 {
+		t.Fatalf("expected activation then deactivation, got %v", out)
+	}
+}
@@ -0,0 +1,150 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//safeState is a state the hardware is returned to when the host stops
+//controlling it. Hooks registered with OnClose are safe states without a key.
+type safeState struct {
+	key   string
+	apply ShutdownHook
+}
+
+//SafeState registers apply as the safe state of the pin or device named key,
+//replacing any safe state already registered for key. It returns an error if
+//key is empty. Safe states are applied, along with the OnClose hooks and in
+//the reverse order of registration, when the connection is closed, by
+//RecoverSafeState when the host panics and by a HostWatchdog which isn't
+//kicked in time. For example
+//
+//	conn.SafeState("servo", func(ctx context.Context) error {
+//		return servo.CallAndReturnNothing(MethodServoDetach)
+//	})
+func (s *FirmwareConnection) SafeState(key string, apply ShutdownHook) error {
+	if key == "" {
+		return errors.New("safe state: key must not be empty")
+	}
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	for i := range s.hooks {
+		if s.hooks[i].key == key {
+			s.hooks[i].apply = apply
+			return nil
+		}
+	}
+	s.hooks = append(s.hooks, safeState{key, apply})
+	return nil
+}
+
+//SafePin registers writing val to the digital pin as its safe state on the
+//connection of api. The pin is dropped from the SkipRedundant cache once the
+//safe state has been written. It does nothing if api doesn't call the board
+//through a FirmwareConnection, which is what keeps the safe states.
+func (api *ArduinoApi) SafePin(pin string, val int) error {
+	conn := connectionOf(api.Conn)
+	if conn == nil {
+		return nil
+	}
+	return conn.SafeState(pin, func(ctx context.Context) error {
+		_, err := methodCall(ctx, api.FirmwareClass, MethodDigitalWrite, []interface{}{pin, val})
+		api.forget(api.values, pin)
+		api.forget(api.duty, pin)
+		return err
+	})
+}
+
+//ClearSafeState removes the safe state registered for key
+func (s *FirmwareConnection) ClearSafeState(key string) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	for i := range s.hooks {
+		if key != "" && s.hooks[i].key == key {
+			s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+			return
+		}
+	}
+}
+
+//ApplySafeStates applies every registered safe state and runs the OnClose
+//hooks, returning the first error encountered
+func (s *FirmwareConnection) ApplySafeStates(ctx context.Context) error {
+	s.hooksMu.Lock()
+	states := append([]safeState(nil), s.hooks...)
+	s.hooksMu.Unlock()
+	var err error
+	for i := len(states) - 1; i >= 0; i-- {
+		if errApply := states[i].apply(ctx); errApply != nil {
+			if states[i].key != "" {
+				s.logf("applying safe state of %s: %s", states[i].key, errApply)
+			}
+			if err == nil {
+				err = errApply
+			}
+		}
+	}
+	return err
+}
+
+//RecoverSafeState applies the safe states if the goroutine is panicking and
+//then continues panicking. It must be deferred directly:
+//
+//	defer conn.RecoverSafeState()
+func (s *FirmwareConnection) RecoverSafeState() {
+	if r := recover(); r != nil {
+		s.ApplySafeStates(context.Background())
+		panic(r)
+	}
+}
+
+//HostWatchdog applies a connection's safe states if it isn't kicked in time,
+//e.g. because the control loop kicking it has hung
+type HostWatchdog struct {
+	kick chan struct{}
+	stop chan struct{}
+	once sync.Once
+}
+
+//WatchHost starts a HostWatchdog which applies the safe states if Kick isn't
+//called for timeout. Once fired it is rearmed by the next Kick.
+func (s *FirmwareConnection) WatchHost(timeout time.Duration) *HostWatchdog {
+	w := &HostWatchdog{
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-w.kick:
+				continue
+			case <-w.stop:
+				return
+			case <-clockOf(s).After(timeout):
+			}
+			s.logf("host watchdog on port %s not kicked for %s, applying safe states", s.Name(), timeout)
+			s.ApplySafeStates(context.Background())
+			select {
+			case <-w.kick:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	return w
+}
+
+//Kick resets the watchdog's timeout
+func (w *HostWatchdog) Kick() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+//Stop stops the watchdog without applying the safe states
+func (w *HostWatchdog) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
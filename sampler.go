@@ -0,0 +1,106 @@
+package nango
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//SampleBatch is a run of consecutive samples fetched from an AnalogSampler
+type SampleBatch struct {
+	Samples []uint16
+	//Dropped is the number of samples the firmware discarded since the
+	//previous fetch because its ring buffer was full
+	Dropped int
+	//Interval is the time between consecutive samples
+	Interval time.Duration
+}
+
+//AnalogSampler samples an analog pin from a timer interrupt in firmware into
+//a ring buffer, at rates up to several kHz, while the host fetches the
+//buffered samples in batches. This enables waveform analysis which per-sample
+//AnalogRead calls are far too slow for.
+type AnalogSampler struct {
+	*FirmwareClass
+	interval time.Duration
+}
+
+func NewAnalogSampler(conn Conn) *AnalogSampler {
+	return &AnalogSampler{
+		FirmwareClass: &FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceSampler,
+		},
+	}
+}
+
+//Start begins sampling pin rate times per second into a ring buffer holding
+//bufferSize samples
+func (a *AnalogSampler) Start(pin string, rate int, bufferSize int) error {
+	if rate <= 0 {
+		return fmt.Errorf("sampler: invalid rate %d", rate)
+	}
+	err := a.CallAndReturnNothing(MethodSamplerStart, pin, rate, bufferSize)
+	if err != nil {
+		return err
+	}
+	a.interval = time.Second / time.Duration(rate)
+	return nil
+}
+
+//Stop stops sampling, discarding any samples not yet fetched
+func (a *AnalogSampler) Stop() error {
+	return a.CallAndReturnNothing(MethodSamplerStop)
+}
+
+//Pause stops sampling without discarding the buffered samples, so that
+//nothing is dropped while the host is unable to keep up
+func (a *AnalogSampler) Pause() error {
+	return a.CallAndReturnNothing(MethodSamplerPause)
+}
+
+//Resume restarts sampling after Pause
+func (a *AnalogSampler) Resume() error {
+	return a.CallAndReturnNothing(MethodSamplerResume)
+}
+
+//Fetch returns the samples buffered since the previous fetch, up to max
+//samples. The firmware sends them as a dropped count followed by little
+//endian 16 bit samples.
+func (a *AnalogSampler) Fetch(max int) (batch SampleBatch, err error) {
+	defer a.recoverResponse(MethodSamplerFetch, &err)
+	batch = SampleBatch{Interval: a.interval}
+	b, err := a.callBuffer(MethodSamplerFetch, max)
+	if err != nil {
+		return batch, err
+	}
+	defer b.Release()
+	//dropped,hexsamples
+	i := bytes.IndexByte(b.Bytes(), ',')
+	if i < 0 {
+		return batch, a.responseError(MethodSamplerFetch, b.String(), errors.New("expected dropped,samples"))
+	}
+	batch.Dropped, err = strconv.Atoi(string(b.Bytes()[:i]))
+	if err != nil {
+		return batch, a.responseError(MethodSamplerFetch, b.String(), err)
+	}
+	raw := b.Bytes()[i+1:]
+	n, err := hex.Decode(raw, raw)
+	if err != nil {
+		//the samples were partially overwritten by decoding in place
+		return batch, a.responseError(MethodSamplerFetch, "", err)
+	}
+	if n%2 != 0 {
+		return batch, a.responseError(MethodSamplerFetch, "", errors.New("odd number of sample bytes"))
+	}
+	batch.Samples = make([]uint16, n/2)
+	for j := range batch.Samples {
+		batch.Samples[j] = binary.LittleEndian.Uint16(raw[j*2:])
+	}
+	return batch, nil
+}
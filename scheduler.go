@@ -0,0 +1,256 @@
+package nango
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//Task is periodic work run by a Scheduler, e.g. reading a sensor
+type Task func(ctx context.Context) error
+
+//ScheduledTask describes a task added to a Scheduler
+type ScheduledTask struct {
+	Name     string
+	Interval time.Duration
+	//Jitter, if non-zero, delays each run by a random duration up to Jitter,
+	//so tasks with the same interval on several connections don't run in
+	//lockstep
+	Jitter time.Duration
+	Run    Task
+}
+
+//TaskStats accounts for the runs of a scheduled task
+type TaskStats struct {
+	Runs   int
+	Errors int
+	//Skipped counts the runs missed because the previous run, or other
+	//tasks, took longer than the interval
+	Skipped int
+	LastRun time.Time
+	LastErr error
+}
+
+type scheduledTask struct {
+	ScheduledTask
+	base   time.Time //next run before jitter
+	next   time.Time
+	paused bool
+	stats  TaskStats
+}
+
+//Scheduler runs periodic tasks on a single goroutine, so the tasks sharing a
+//connection never run concurrently and a task is never started again while
+//its previous run is in progress. Runs which fall due while the goroutine is
+//busy are skipped rather than run back-to-back to catch up.
+type Scheduler struct {
+	//OnError, if set, is called with the errors returned by tasks instead of
+	//logging them
+	OnError func(name string, err error)
+
+	clock  Clock
+	wake   chan struct{}
+	mu     sync.Mutex
+	tasks  []*scheduledTask
+	paused bool
+}
+
+//NewScheduler returns a Scheduler timing its tasks with the clock of conn
+func NewScheduler(conn Conn) *Scheduler {
+	return &Scheduler{
+		clock: clockOf(conn),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+//Add schedules t, first running it after its interval. A task already
+//scheduled with the same name is replaced. Add panics if the interval isn't
+//positive.
+func (s *Scheduler) Add(t ScheduledTask) {
+	if t.Interval <= 0 {
+		panic("nango: non-positive interval for scheduled task " + t.Name)
+	}
+	st := &scheduledTask{ScheduledTask: t}
+	st.base = s.clock.Now().Add(t.Interval)
+	st.next = st.base.Add(st.jitter())
+	s.mu.Lock()
+	s.remove(t.Name)
+	s.tasks = append(s.tasks, st)
+	s.mu.Unlock()
+	s.notify()
+}
+
+//Every schedules task to run every interval
+func (s *Scheduler) Every(name string, interval time.Duration, task Task) {
+	s.Add(ScheduledTask{Name: name, Interval: interval, Run: task})
+}
+
+//Remove unschedules the task called name
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	s.remove(name)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) remove(name string) {
+	for i, t := range s.tasks {
+		if t.Name == name {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+//Pause stops running every task until Resume is called
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.notify()
+}
+
+//Resume restarts the tasks after Pause. Runs missed while paused are skipped.
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+	s.notify()
+}
+
+//PauseTask stops running the task called name until ResumeTask is called
+func (s *Scheduler) PauseTask(name string) {
+	s.setTaskPaused(name, true)
+}
+
+//ResumeTask restarts the task called name after PauseTask
+func (s *Scheduler) ResumeTask(name string) {
+	s.setTaskPaused(name, false)
+}
+
+func (s *Scheduler) setTaskPaused(name string, paused bool) {
+	s.mu.Lock()
+	for _, t := range s.tasks {
+		if t.Name == name {
+			t.paused = paused
+		}
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+//Stats returns the statistics of the task called name
+func (s *Scheduler) Stats(name string) (TaskStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tasks {
+		if t.Name == name {
+			return t.stats, true
+		}
+	}
+	return TaskStats{}, false
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+//Run runs the tasks until ctx is done, returning ctx's error
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		var timer <-chan time.Time
+		if next, ok := s.nextRun(); ok {
+			timer = s.clock.After(next.Sub(s.clock.Now()))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.wake:
+			continue
+		case <-timer:
+		}
+		for {
+			t := s.due()
+			if t == nil {
+				break
+			}
+			err := t.Run(ctx)
+			s.finish(t, err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+//nextRun returns when the next task falls due
+func (s *Scheduler) nextRun() (next time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		return
+	}
+	for _, t := range s.tasks {
+		if !t.paused && (!ok || t.next.Before(next)) {
+			next, ok = t.next, true
+		}
+	}
+	return
+}
+
+//due returns the most overdue task, if any
+func (s *Scheduler) due() *scheduledTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		return nil
+	}
+	now := s.clock.Now()
+	var due *scheduledTask
+	for _, t := range s.tasks {
+		if !t.paused && !t.next.After(now) && (due == nil || t.next.Before(due.next)) {
+			due = t
+		}
+	}
+	return due
+}
+
+//finish records the outcome of a run of t and schedules its next run
+func (s *Scheduler) finish(t *scheduledTask, err error) {
+	s.mu.Lock()
+	now := s.clock.Now()
+	t.stats.Runs++
+	t.stats.LastRun = now
+	t.stats.LastErr = err
+	if err != nil {
+		t.stats.Errors++
+	}
+	t.base = t.base.Add(t.Interval)
+	if !t.base.After(now) {
+		missed := int(now.Sub(t.base)/t.Interval) + 1
+		t.stats.Skipped += missed
+		t.base = t.base.Add(time.Duration(missed) * t.Interval)
+	}
+	t.next = t.base.Add(t.jitter())
+	onError := s.OnError
+	s.mu.Unlock()
+	if err == nil {
+		return
+	}
+	if onError != nil {
+		onError(t.Name, err)
+		return
+	}
+	log.Printf("scheduled task %s: %s", t.Name, err)
+}
+
+func (t *scheduledTask) jitter() time.Duration {
+	if t.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.Jitter)))
+}
@@ -0,0 +1,130 @@
+package nango_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+//openClocked opens a loopback connection timed by clock
+func openClocked(t *testing.T, clock *nangotest.Clock) (*nango.Loopback, *nango.FirmwareConnection) {
+	t.Helper()
+	lb := nango.NewLoopback()
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithClock(clock))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return lb, conn
+}
+
+//waitRuns waits until the task called name has run at least n times
+func waitRuns(t *testing.T, s *nango.Scheduler, name string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, _ := s.Stats(name)
+		if st.Runs >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s ran %d times, expected %d", name, st.Runs, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb, conn := openClocked(t, clock)
+	lb.Respond(nango.NamespaceArduino, nango.MethodDigitalRead, "1")
+	s := nango.NewScheduler(conn)
+	var mu sync.Mutex
+	var errs []string
+	s.OnError = func(name string, err error) {
+		mu.Lock()
+		errs = append(errs, name)
+		mu.Unlock()
+	}
+	api := nango.NewArduinoApi(conn)
+	s.Every("read", 5*time.Millisecond, func(ctx context.Context) error {
+		_, err := api.DigitalRead("D2")
+		return err
+	})
+	//the slow task takes longer than its interval
+	s.Add(nango.ScheduledTask{Name: "slow", Interval: 5 * time.Millisecond, Jitter: time.Millisecond, Run: func(ctx context.Context) error {
+		clock.Advance(12 * time.Millisecond)
+		return errors.New("failed")
+	}})
+	s.PauseTask("slow")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(5 * time.Millisecond)
+		waitRuns(t, s, "read", i)
+	}
+	if st, _ := s.Stats("slow"); st.Runs != 0 {
+		t.Fatalf("expected paused task not to run, ran %d times", st.Runs)
+	}
+	//the overdue slow task runs at once, then read catches up once rather
+	//than for every run it missed meanwhile
+	s.ResumeTask("slow")
+	waitRuns(t, s, "slow", 1)
+	waitRuns(t, s, "read", 4)
+	s.Pause()
+	clock.Advance(20 * time.Millisecond)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	read, _ := s.Stats("read")
+	if read.Runs != 4 || read.Errors != 0 || read.Skipped == 0 {
+		t.Fatalf("expected 4 successful reads with skips and none while paused, got %+v", read)
+	}
+	slow, _ := s.Stats("slow")
+	if slow.Runs != 1 || slow.Skipped == 0 || slow.Errors != slow.Runs {
+		t.Fatalf("expected a failing run with skips, got %+v", slow)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != slow.Runs {
+		t.Fatalf("expected %d errors reported, got %d", slow.Runs, len(errs))
+	}
+}
+
+func TestPoller(t *testing.T) {
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb, conn := openClocked(t, clock)
+	lb.Respond(nango.NamespaceArduino, nango.MethodAnalogRead, "512")
+	s := nango.NewScheduler(conn)
+	p := nango.NewPoller(s)
+	p.Add("A0", 5*time.Millisecond, nango.AnalogInput(nango.NewArduinoApi(conn), "A0"))
+	readings := make(chan nango.Reading, 10)
+	unsubscribe := p.Subscribe(func(r nango.Reading) {
+		select {
+		case readings <- r:
+		default:
+		}
+	})
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	clock.Advance(5 * time.Millisecond)
+	select {
+	case r := <-readings:
+		if r.Source != "A0" || r.Value != 512 || r.Err != nil {
+			t.Fatalf("unexpected reading %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reading published")
+	}
+}
@@ -0,0 +1,51 @@
+package nango
+
+//Servo gives access to an instance of the arduino Servo library
+//https://www.arduino.cc/en/Reference/Servo
+type Servo struct {
+	*FirmwareClass
+}
+
+//NewServo creates a Servo instance on the firmware attached to pin
+func NewServo(conn Conn, pin string) (*Servo, error) {
+	f, err := NewFirmwareObject(conn, NamespaceServo, pin)
+	if err != nil {
+		return nil, err
+	}
+	return &Servo{f}, nil
+}
+
+//Write sets the angle of the shaft in degrees, 0 to 180
+func (s *Servo) Write(angle int) error {
+	return s.CallAndReturnNothing(MethodServoWrite, angle)
+}
+
+//Read returns the angle last written
+func (s *Servo) Read() (int, error) {
+	return s.CallAndReturnInt(MethodServoRead)
+}
+
+//WriteMicroseconds sets the pulse width in microseconds
+func (s *Servo) WriteMicroseconds(us int) error {
+	return s.CallAndReturnNothing(MethodServoWriteMicroseconds, us)
+}
+
+//ReadMicroseconds returns the pulse width last written in microseconds
+func (s *Servo) ReadMicroseconds() (int, error) {
+	return s.CallAndReturnInt(MethodServoReadMicroseconds)
+}
+
+//Attached reports whether the servo is attached to its pin
+func (s *Servo) Attached() (bool, error) {
+	v, err := s.CallAndReturnInt(MethodServoAttached)
+	return v == 1, err
+}
+
+//Close detaches the servo and destroys the firmware instance
+func (s *Servo) Close() error {
+	err := s.CallAndReturnNothing(MethodServoDetach)
+	if err != nil {
+		return err
+	}
+	return s.Remove()
+}
@@ -0,0 +1,94 @@
+package nango
+
+import (
+	"context"
+	"sync"
+)
+
+//ShutdownHook puts the hardware in a safe state before the connection is
+//closed, e.g. switching relays off or detaching servos. It should stop when
+//ctx is done.
+type ShutdownHook func(ctx context.Context) error
+
+//inFlight counts the requests submitted to a connection which have not
+//completed
+type inFlight struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (f *inFlight) add() {
+	f.mu.Lock()
+	f.n++
+	f.mu.Unlock()
+}
+
+func (f *inFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+//wait waits until no requests are in flight or ctx is done
+func (f *inFlight) wait(ctx context.Context) error {
+	f.mu.Lock()
+	if f.n == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//OnClose registers a hook run before the port is closed. It is an anonymous
+//safe state: it also runs whenever the safe states are applied, and hooks and
+//safe states run together in the reverse order of registration.
+func (s *FirmwareConnection) OnClose(hook ShutdownHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.hooks = append(s.hooks, safeState{apply: hook})
+}
+
+//CloseWithContext closes the connection gracefully:
+//
+//  1. it waits for the calls in flight to complete
+//  2. it runs the hooks registered with OnClose and the safe states
+//  3. it drains the calls made meanwhile, e.g. by the hooks or by other
+//     goroutines, so they are sent rather than failed
+//  4. it flushes the port and closes it
+//
+//If ctx is done while waiting the remaining steps still run, with ctx, and
+//the port is closed regardless. The first error encountered is returned.
+func (s *FirmwareConnection) CloseWithContext(ctx context.Context) error {
+	if s.port == nil {
+		return nil
+	}
+	err := s.inFlight.wait(ctx)
+	errSafe := s.ApplySafeStates(ctx)
+	if err == nil {
+		err = errSafe
+	}
+	errDrain := s.inFlight.wait(ctx)
+	if err == nil {
+		err = errDrain
+	}
+	errClose := s.closePort()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
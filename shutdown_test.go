@@ -0,0 +1,120 @@
+package nango_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func TestCloseWithContext(t *testing.T) {
+	clock := nangotest.NewClock(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	lb := nango.NewLoopback()
+	var mu sync.Mutex
+	var order []string
+	record := func(s string) {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+	}
+	lb.Handle("T", "slow", func(nango.LoopbackCall) (string, bool) {
+		clock.Sleep(30 * time.Millisecond)
+		record("slow")
+		return "done", true
+	})
+	lb.Handle("T", "late", func(nango.LoopbackCall) (string, bool) {
+		clock.Sleep(10 * time.Millisecond)
+		record("late")
+		return "", true
+	})
+	lb.Handle(nango.NamespaceArduino, nango.MethodDigitalWrite, func(c nango.LoopbackCall) (string, bool) {
+		record("dw " + c.Args[0])
+		return "", true
+	})
+	lb.Handle("T", "queued", func(nango.LoopbackCall) (string, bool) {
+		record("queued")
+		return "", true
+	})
+	entered := make(chan struct{}, 1)
+	mw := func(next nango.CallFunc) nango.CallFunc {
+		return func(ctx context.Context, c *nango.CallInfo) (*nango.Buffer, error) {
+			if c.Method == "queued" {
+				entered <- struct{}{}
+			}
+			return next(ctx, c)
+		}
+	}
+	conn := nango.NewFirmwareConnection(nil, nango.WithTransport(lb.Dial), nango.WithClock(clock),
+		nango.WithMiddleware(mw))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	api := nango.NewArduinoApi(conn)
+	f := &nango.FirmwareClass{Conn: conn, Namespace: "T"}
+	waitSleeping := func() {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	//calls made while closing, here by a hook, are drained before the port
+	//is closed rather than failed
+	late := make(chan error, 2)
+	conn.OnClose(func(ctx context.Context) error {
+		if err := api.DigitalWrite("D7", nango.PinLow); err != nil {
+			return err
+		}
+		go func() {
+			late <- f.CallAndReturnNothing("late")
+		}()
+		waitSleeping()
+		go func() {
+			late <- f.CallAndReturnNothing("queued")
+		}()
+		<-entered
+		return nil
+	})
+
+	slow := make(chan string, 1)
+	go func() {
+		s, _ := f.CallContext(context.Background(), "slow")
+		slow <- s
+	}()
+	waitSleeping()
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.CloseWithContext(context.Background())
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("expected close to wait for the call in flight, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(30 * time.Millisecond)
+	if s := <-slow; s != "done" {
+		t.Fatalf("in flight call did not complete: %q", s)
+	}
+	waitSleeping()
+	select {
+	case err := <-closed:
+		t.Fatalf("expected close to drain the calls made by the hook, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(10 * time.Millisecond)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-late; err != nil {
+			t.Fatalf("expected the calls made while closing to be sent, got %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 4 || order[0] != "slow" || order[1] != "dw D7" || order[2] != "late" || order[3] != "queued" {
+		t.Fatalf("unexpected order %v", order)
+	}
+}
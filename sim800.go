@@ -0,0 +1,209 @@
+package nango
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SMS is a text message received by a SIM800
+type SMS struct {
+	//Index is the message's storage location on the SIM
+	Index  int
+	Sender string
+	Time   time.Time
+	Text   string
+}
+
+//SIM800 drives a SIM800 series GSM module attached to a UART on the board,
+//to send and receive text messages, e.g. for alerts
+type SIM800 struct {
+	at *atModem
+	//Timeout is how long to wait for the response to a command
+	Timeout time.Duration
+}
+
+//sim800SendTimeout is how long the network may take to accept a message
+const sim800SendTimeout = 60 * time.Second
+
+//NewSIM800 initializes the module on serial, which must already have been
+//begun at the module's baud rate, for text mode messaging
+func NewSIM800(conn Conn, serial AttachedSerial) (*SIM800, error) {
+	s := &SIM800{at: newATModem(serial, clockOf(conn)), Timeout: 5 * time.Second}
+	s.at.isResponse = isSIM800Response
+	for _, cmd := range []string{"AT", "ATE0", "AT+CMGF=1"} {
+		if _, err := s.at.command(cmd, s.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//isSIM800Response reports whether line belongs to the response to cmd rather
+//than being an unsolicited result code such as +CMTI. The text of messages
+//listed by +CMGL may look like anything, e.g. "+1 alarm", so every line after
+//the first header is part of the response.
+func isSIM800Response(cmd string, lines []string, line string) bool {
+	if len(lines) > 0 && strings.HasPrefix(cmd, "AT+CMGL") {
+		return true
+	}
+	if !strings.HasPrefix(line, "+") {
+		return true
+	}
+	i := strings.IndexByte(line, ':')
+	return i > 0 && strings.HasPrefix(cmd, "AT"+line[:i])
+}
+
+//responseFields returns the comma separated fields of the response line
+//starting with prefix, e.g. "+CSQ: "
+func responseFields(lines []string, prefix string) ([]string, error) {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			r := csv.NewReader(strings.NewReader(strings.TrimPrefix(line, prefix)))
+			return r.Read()
+		}
+	}
+	return nil, fmt.Errorf("missing %s in response %q", strings.TrimSpace(prefix), lines)
+}
+
+//SendSMS sends text to number, in international format
+func (s *SIM800) SendSMS(number string, text string) error {
+	_, err := s.at.commandData(fmt.Sprintf("AT+CMGS=%q", number), text, "\x1a", sim800SendTimeout)
+	return err
+}
+
+//Registration returns the network registration status: 1 registered on the
+//home network, 5 registered roaming, 2 searching, 0 not searching and 3
+//denied
+func (s *SIM800) Registration() (int, error) {
+	lines, err := s.at.command("AT+CREG?", s.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	f, err := responseFields(lines, "+CREG: ")
+	if err != nil {
+		return 0, err
+	}
+	if len(f) < 2 {
+		return 0, fmt.Errorf("malformed registration %q", lines)
+	}
+	return strconv.Atoi(f[1])
+}
+
+//Registered reports whether the module is registered on a network
+func (s *SIM800) Registered() (bool, error) {
+	stat, err := s.Registration()
+	return stat == 1 || stat == 5, err
+}
+
+//SignalStrength returns the received signal strength in dBm, ok is false if
+//it is not known
+func (s *SIM800) SignalStrength() (dbm int, ok bool, err error) {
+	lines, err := s.at.command("AT+CSQ", s.Timeout)
+	if err != nil {
+		return 0, false, err
+	}
+	f, err := responseFields(lines, "+CSQ: ")
+	if err != nil {
+		return 0, false, err
+	}
+	rssi, err := strconv.Atoi(f[0])
+	if err != nil || rssi == 99 {
+		return 0, false, err
+	}
+	return -113 + 2*rssi, true, nil
+}
+
+//parseSMSTime parses a yy/MM/dd,hh:mm:ss±zz timestamp, zz being the offset
+//from UTC in quarter hours
+func parseSMSTime(s string) (time.Time, error) {
+	if len(s) != 20 {
+		return time.Time{}, fmt.Errorf("malformed SMS timestamp %q", s)
+	}
+	q, err := strconv.Atoi(s[17:])
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse("06/01/02,15:04:05", s[:17])
+	if err != nil {
+		return time.Time{}, err
+	}
+	zone := time.FixedZone("", q*15*60)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, zone), nil
+}
+
+//ReadUnread returns the messages which haven't been read, marking them read
+func (s *SIM800) ReadUnread() ([]SMS, error) {
+	lines, err := s.at.command(`AT+CMGL="REC UNREAD"`, s.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []SMS
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "+CMGL: ") {
+			continue
+		}
+		//index,status,sender,name,timestamp followed by the text
+		f, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(lines[i], "+CMGL: "))).Read()
+		if err != nil || len(f) < 5 {
+			return msgs, fmt.Errorf("malformed message header %q", lines[i])
+		}
+		var m SMS
+		if m.Index, err = strconv.Atoi(f[0]); err != nil {
+			return msgs, err
+		}
+		m.Sender = f[2]
+		if m.Time, err = parseSMSTime(f[4]); err != nil {
+			return msgs, err
+		}
+		var text []string
+		for i+1 < len(lines) && !strings.HasPrefix(lines[i+1], "+CMGL: ") {
+			i++
+			text = append(text, lines[i])
+		}
+		m.Text = strings.Join(text, "\n")
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+//Delete deletes the message at index from the SIM
+func (s *SIM800) Delete(index int) error {
+	_, err := s.at.command("AT+CMGD="+strconv.Itoa(index), s.Timeout)
+	return err
+}
+
+//Receive polls for unread messages every poll interval, delivering each on
+//the returned channel and deleting it from the SIM, until ctx is done.
+//Errors polling are passed to onError, if not nil, and polling continues.
+func (s *SIM800) Receive(ctx context.Context, poll time.Duration, onError func(error)) <-chan SMS {
+	c := make(chan SMS)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.at.clock.After(poll):
+			}
+			msgs, err := s.ReadUnread()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+			for _, m := range msgs {
+				select {
+				case c <- m:
+				case <-ctx.Done():
+					return
+				}
+				if err := s.Delete(m.Index); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return c
+}
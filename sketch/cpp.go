@@ -0,0 +1,129 @@
+package sketch
+
+import (
+	"io"
+	"text/template"
+)
+
+var cppTypes = map[Type]string{
+	Void:   "void",
+	Int:    "int",
+	Float:  "float",
+	Bool:   "bool",
+	String: "const char*",
+	Bytes:  "const char*",
+}
+
+//cppGetters parse argument i from the MethodDescriptor
+var cppGetters = map[Type]string{
+	Int:    "m->getInt(%d)",
+	Float:  "m->getFloat(%d)",
+	Bool:   "m->getBool(%d)",
+	String: "m->getString(%d)",
+	Bytes:  "m->getString(%d)",
+}
+
+var cppTemplate = template.Must(template.New("cpp").Funcs(template.FuncMap{
+	"ctype":  func(t Type) string { return cppTypes[t] },
+	"getter": func(t Type, i int) string { return sprintf(cppGetters[t], i) },
+	"void":   func(t Type) bool { return t == Void },
+	"bytes":  func(t Type) bool { return t == Bytes },
+}).Parse(`// Code generated by nango/sketch. DO NOT EDIT.
+// Implement the {{.Namespace}}_* functions declared below in your sketch.
+
+#include "BaseClass.h"
+#include "MethodDescriptor.h"
+{{if .HexDecode}}
+#ifndef NANGO_HEX_DECODE
+#define NANGO_HEX_DECODE
+static int nango_hex_digit(char c) {
+    if (c >= '0' && c <= '9') return c - '0';
+    if (c >= 'a' && c <= 'f') return c - 'a' + 10;
+    if (c >= 'A' && c <= 'F') return c - 'A' + 10;
+    return -1;
+}
+
+// nango_hex_decode decodes the hex encoded string hex into out, which holds
+// size bytes, and returns the number of bytes decoded, or -1 if hex is
+// malformed or doesn't fit.
+static int nango_hex_decode(const char* hex, uint8_t* out, int size) {
+    int n = 0;
+    for (; hex[0] && hex[1]; hex += 2) {
+        int hi = nango_hex_digit(hex[0]);
+        int lo = nango_hex_digit(hex[1]);
+        if (hi < 0 || lo < 0 || n == size) return -1;
+        out[n++] = (uint8_t)(hi << 4 | lo);
+    }
+    return hex[0] ? -1 : n;
+}
+#endif
+{{end}}
+{{range .Methods}}
+{{- if .Args}}{{$m := .}}
+{{- range .Args}}{{if bytes .Type}}// {{$m.Name}}: {{.Name}} is hex encoded, decode it with nango_hex_decode
+{{end}}{{end}}
+{{- end -}}
+{{ctype .Returns}} {{$.Namespace}}_{{.Name}}({{if $.Instances}}int id{{if .Args}}, {{end}}{{end}}{{range $i, $a := .Args}}{{if $i}}, {{end}}{{ctype $a.Type}} {{$a.Name}}{{end}});
+{{end}}
+{{- if .Instances}}
+int {{.Namespace}}_new({{range $i, $a := .ConstructorArgs}}{{if $i}}, {{end}}{{ctype $a.Type}} {{$a.Name}}{{end}});
+void {{.Namespace}}_remove(int id);
+{{end}}
+namespace nanpy {
+    class {{.Namespace}}Class: public BaseClass {
+        public:
+            const char* get_firmware_id() { return "{{.Namespace}}"; }
+            void elaborate(nanpy::MethodDescriptor* m);
+    };
+}
+
+void nanpy::{{.Namespace}}Class::elaborate(nanpy::MethodDescriptor* m) {
+{{- if .Instances}}
+    if (strcmp(m->getName(), "new") == 0) {
+        m->returns({{.Namespace}}_new({{range $i, $a := .ConstructorArgs}}{{if $i}}, {{end}}{{getter $a.Type $i}}{{end}}));
+        return;
+    }
+    if (strcmp(m->getName(), "remove") == 0) {
+        {{.Namespace}}_remove(m->getObjectId());
+        m->returns(0);
+        return;
+    }
+{{- end}}
+{{- range .Methods}}
+    if (strcmp(m->getName(), "{{.Name}}") == 0) {
+        {{if void .Returns}}{{$.Namespace}}_{{.Name}}({{else}}m->returns({{$.Namespace}}_{{.Name}}({{end -}}
+        {{if $.Instances}}m->getObjectId(){{if .Args}}, {{end}}{{end -}}
+        {{range $i, $a := .Args}}{{if $i}}, {{end}}{{getter $a.Type $i}}{{end}}){{if not (void .Returns)}}){{end}};
+        {{- if void .Returns}}
+        m->returns(0);
+        {{- end}}
+        return;
+    }
+{{- end}}
+}
+`))
+
+//WriteSketch writes the Arduino C++ class for c. The sketch must define a
+//function for each method, named namespace_method, which the class calls
+//with the parsed arguments. Bytes arguments are passed hex encoded; the
+//class then also defines nango_hex_decode to decode them.
+func WriteSketch(w io.Writer, c Class) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+	hexDecode := false
+	for _, m := range c.Methods {
+		for _, a := range m.Args {
+			if a.Type == Bytes {
+				hexDecode = true
+			}
+		}
+	}
+	return cppTemplate.Execute(w, struct {
+		Class
+		//HexDecode is set if the sketch needs nango_hex_decode for Bytes
+		//arguments
+		HexDecode bool
+	}{c, hexDecode})
+}
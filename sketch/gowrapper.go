@@ -0,0 +1,121 @@
+package sketch
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"text/template"
+)
+
+var goTypes = map[Type]string{
+	Int:    "int",
+	Float:  "float64",
+	Bool:   "bool",
+	String: "string",
+	Bytes:  "[]byte",
+}
+
+//goCalls are the FirmwareClass methods which call a method returning a type
+var goCalls = map[Type]string{
+	Void:   "CallAndReturnNothing",
+	Int:    "CallAndReturnInt",
+	Float:  "CallAndReturnFloat",
+	Bool:   "CallAndReturnInt",
+	String: "CallContext",
+	Bytes:  "CallAndReturnBytes",
+}
+
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{
+	"gotype": func(t Type) string { return goTypes[t] },
+	"export": func(s string) string { return strings.ToUpper(s[:1]) + s[1:] },
+	"call":   func(t Type) string { return goCalls[t] },
+	"void":   func(t Type) bool { return t == Void },
+	"is":     func(t Type, name string) bool { return goTypes[t] == name },
+}).Parse(`// Code generated by nango/sketch. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .NeedsContext}}
+	"context"
+{{end}}
+	"github.com/justinsantoro/nango"
+)
+{{with .Class}}
+type {{.Namespace}} struct {
+	*nango.FirmwareClass
+}
+{{if .Instances}}
+// New{{.Namespace}} creates a {{.Namespace}} instance on the firmware
+func New{{.Namespace}}(conn nango.Conn{{range .ConstructorArgs}}, {{.Name}} {{gotype .Type}}{{end}}) (*{{.Namespace}}, error) {
+	f, err := nango.NewFirmwareObject(conn, "{{.Namespace}}"{{range .ConstructorArgs}}, {{.Name}}{{end}})
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Namespace}}{f}, nil
+}
+{{else}}
+func New{{.Namespace}}(conn nango.Conn) *{{.Namespace}} {
+	return &{{.Namespace}}{
+		&nango.FirmwareClass{
+			Conn:      conn,
+			Id:        0,
+			Namespace: "{{.Namespace}}",
+		},
+	}
+}
+{{end}}
+{{- $ns := .Namespace}}
+{{- range .Methods}}
+{{if .Doc}}// {{export .Name}} {{.Doc}}
+{{end -}}
+func (c *{{$ns}}) {{export .Name}}({{range $i, $a := .Args}}{{if $i}}, {{end}}{{$a.Name}} {{gotype $a.Type}}{{end}}) {{if void .Returns}}error{{else}}({{gotype .Returns}}, error){{end}} {
+	{{- if void .Returns}}
+	return c.{{call .Returns}}("{{.Name}}"{{range .Args}}, {{.Name}}{{end}})
+	{{- else if is .Returns "bool"}}
+	v, err := c.{{call .Returns}}("{{.Name}}"{{range .Args}}, {{.Name}}{{end}})
+	return v == 1, err
+	{{- else if is .Returns "string"}}
+	return c.{{call .Returns}}(context.Background(), "{{.Name}}"{{range .Args}}, {{.Name}}{{end}})
+	{{- else}}
+	return c.{{call .Returns}}("{{.Name}}"{{range .Args}}, {{.Name}}{{end}})
+	{{- end}}
+}
+{{end}}
+{{- end}}
+`))
+
+//WriteGo writes a gofmt'd Go wrapper for c in package pkg
+func WriteGo(w io.Writer, pkg string, c Class) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+	needsContext := false
+	for _, m := range c.Methods {
+		if m.Returns == String {
+			needsContext = true
+		}
+	}
+	var b bytes.Buffer
+	err = goTemplate.Execute(&b, struct {
+		Package      string
+		NeedsContext bool
+		Class        Class
+	}{pkg, needsContext, c})
+	if err != nil {
+		return err
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("sketch: generated invalid Go: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(format, a...)
+}
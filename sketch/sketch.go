@@ -0,0 +1,91 @@
+//Package sketch generates both sides of a custom firmware class from one Go
+//declaration: the Arduino C++ class which registers the namespace and parses
+//the arguments of each method, and the Go wrapper which calls it. Run it from
+//a go:generate program so the two can't drift apart.
+//
+//	c := sketch.Class{
+//		Namespace: "Thermo",
+//		Methods: []sketch.Method{
+//			{Name: "read", Args: []sketch.Arg{{"channel", sketch.Int}}, Returns: sketch.Float},
+//		},
+//	}
+//	sketch.WriteSketch(cppFile, c)
+//	sketch.WriteGo(goFile, "mypkg", c)
+package sketch
+
+import (
+	"fmt"
+	"regexp"
+)
+
+//Type is the type of a method argument or return value
+type Type int
+
+const (
+	Void Type = iota
+	Int
+	Float
+	Bool
+	String
+	//Bytes are sent hex encoded
+	Bytes
+)
+
+//Arg is a method argument
+type Arg struct {
+	Name string
+	Type Type
+}
+
+//Method is a method of a firmware class
+type Method struct {
+	Name    string
+	Args    []Arg
+	Returns Type
+	//Doc is copied to the Go wrapper method
+	Doc string
+}
+
+//Class is a custom firmware class
+type Class struct {
+	Namespace string
+	//Instances is set for classes constructed with NewFirmwareObject, whose
+	//methods are called on an instance id, rather than a single static
+	//namespace
+	Instances bool
+	//ConstructorArgs are the arguments of the instance constructor
+	ConstructorArgs []Arg
+	Methods         []Method
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//Validate checks that every name is a valid identifier in both languages
+//and that no method is declared twice
+func (c Class) Validate() error {
+	if !identifier.MatchString(c.Namespace) {
+		return fmt.Errorf("sketch: invalid namespace %q", c.Namespace)
+	}
+	seen := make(map[string]bool)
+	for _, m := range c.Methods {
+		if !identifier.MatchString(m.Name) {
+			return fmt.Errorf("sketch: %s: invalid method name %q", c.Namespace, m.Name)
+		}
+		if m.Name == "new" || m.Name == "remove" {
+			return fmt.Errorf("sketch: %s: method name %q is reserved", c.Namespace, m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("sketch: %s: method %q declared twice", c.Namespace, m.Name)
+		}
+		seen[m.Name] = true
+		for _, a := range m.Args {
+			if !identifier.MatchString(a.Name) {
+				return fmt.Errorf("sketch: %s.%s: invalid argument name %q", c.Namespace, m.Name, a.Name)
+			}
+			if a.Type == Void {
+				return fmt.Errorf("sketch: %s.%s: argument %q has no type", c.Namespace, m.Name, a.Name)
+			}
+		}
+	}
+	return nil
+}
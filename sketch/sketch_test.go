@@ -0,0 +1,85 @@
+package sketch
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+var testClass = Class{
+	Namespace:       "Thermo",
+	Instances:       true,
+	ConstructorArgs: []Arg{{"pin", String}},
+	Methods: []Method{
+		{Name: "read", Args: []Arg{{"channel", Int}}, Returns: Float, Doc: "returns the temperature of a channel"},
+		{Name: "calibrate", Args: []Arg{{"table", Bytes}, {"persist", Bool}}},
+		{Name: "ready", Returns: Bool},
+		{Name: "label", Returns: String},
+	},
+}
+
+func TestWriteSketch(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteSketch(&b, testClass); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`float Thermo_read(int id, int channel);`,
+		`const char* get_firmware_id() { return "Thermo"; }`,
+		`m->returns(Thermo_read(m->getObjectId(), m->getInt(0)));`,
+		`Thermo_calibrate(m->getObjectId(), m->getString(0), m->getBool(1));`,
+		`m->returns(Thermo_new(m->getString(0)));`,
+		`// calibrate: table is hex encoded, decode it with nango_hex_decode`,
+		`static int nango_hex_decode(const char* hex, uint8_t* out, int size) {`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("sketch missing %q:\n%s", want, b.String())
+		}
+	}
+
+	//the helper is only emitted for classes taking Bytes arguments
+	b.Reset()
+	if err := WriteSketch(&b, Class{Namespace: "Led", Methods: []Method{{Name: "on", Args: []Arg{{"pin", Int}}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "nango_hex_decode") {
+		t.Errorf("unexpected hex decoder in sketch without Bytes arguments:\n%s", b.String())
+	}
+}
+
+func TestWriteGo(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteGo(&b, "thermo", testClass); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`func NewThermo(conn nango.Conn, pin string) (*Thermo, error) {`,
+		`// Read returns the temperature of a channel`,
+		`func (c *Thermo) Read(channel int) (float64, error) {`,
+		`return c.CallAndReturnNothing("calibrate", table, persist)`,
+		`return v == 1, err`,
+		`return c.CallContext(context.Background(), "label")`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("wrapper missing %q:\n%s", want, b.String())
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "thermo.go", b.Bytes(), parser.AllErrors); err != nil {
+		t.Fatalf("generated invalid Go: %s\n%s", err, b.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []Class{
+		{Namespace: "1abc"},
+		{Namespace: "A", Methods: []Method{{Name: "new"}}},
+		{Namespace: "A", Methods: []Method{{Name: "x"}, {Name: "x"}}},
+		{Namespace: "A", Methods: []Method{{Name: "x", Args: []Arg{{"v", Void}}}}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
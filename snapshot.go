@@ -0,0 +1,278 @@
+package nango
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//BoardSnapshot is the state of a Board as far as the host knows it. It
+//marshals to JSON for attaching to bug reports.
+type BoardSnapshot struct {
+	Time     time.Time              `json:"time"`
+	Model    string                 `json:"model,omitempty"`
+	Firmware FirmwareSnapshot       `json:"firmware"`
+	Pins     map[string]PinSnapshot `json:"pins,omitempty"`
+	Drivers  []DriverSnapshot       `json:"drivers,omitempty"`
+}
+
+//FirmwareSnapshot describes the firmware a Board is connected to
+type FirmwareSnapshot struct {
+	Port    string   `json:"port,omitempty"`
+	Classes []string `json:"classes,omitempty"`
+	Millis  int      `json:"millis"`
+	//Error is the first error querying the firmware, which leaves the
+	//fields after Port unset
+	Error string `json:"error,omitempty"`
+}
+
+//PinSnapshot is the last mode and output value set on a pin through the
+//Board. Pins whose state is unknown, e.g. after a failed call, are left out.
+type PinSnapshot struct {
+	//Mode is input, output or input_pullup
+	Mode string `json:"mode,omitempty"`
+	//Value is the value last written with DigitalWrite
+	Value *int `json:"value,omitempty"`
+	//Duty is the value last written with AnalogWrite
+	Duty *int `json:"duty,omitempty"`
+}
+
+//DriverSnapshot describes a driver attached to the Board
+type DriverSnapshot struct {
+	//Name is the name the driver is registered as by a plugin. It is empty
+	//for drivers not created with NewDriver.
+	Name    string            `json:"name,omitempty"`
+	Type    string            `json:"type"`
+	Pin     string            `json:"pin,omitempty"`
+	Address I2CAddress        `json:"address,omitempty"`
+	Args    map[string]string `json:"args,omitempty"`
+}
+
+//attachedDriver is a driver created on a Board with NewDriver
+type attachedDriver struct {
+	name   string
+	args   map[string]string
+	driver interface{}
+}
+
+//attach records a driver created with NewDriver
+func (b *Board) attach(name string, args map[string]string, d interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drivers = append(b.drivers, attachedDriver{name: name, args: args, driver: d})
+}
+
+//PinStates returns the pin modes and outputs last set through api, keyed by
+//pin. Pins whose state is unknown are left out.
+func (api *ArduinoApi) PinStates() map[string]PinSnapshot {
+	api.cacheMu.Lock()
+	defer api.cacheMu.Unlock()
+	pins := make(map[string]PinSnapshot)
+	for pin, mode := range api.modes {
+		p := pins[pin]
+		p.Mode = pinModeNames[mode]
+		if p.Mode == "" {
+			p.Mode = strconv.Itoa(mode)
+		}
+		pins[pin] = p
+	}
+	for pin, v := range api.values {
+		v := v
+		p := pins[pin]
+		p.Value = &v
+		pins[pin] = p
+	}
+	for pin, v := range api.duty {
+		v := v
+		p := pins[pin]
+		p.Duty = &v
+		pins[pin] = p
+	}
+	return pins
+}
+
+//Snapshot returns the known state of the board: the pin modes and output
+//values set through Pins, the attached drivers and what the firmware
+//reports about itself. A firmware which doesn't respond is recorded in the
+//snapshot rather than failing it, since a snapshot is most useful from a
+//misbehaving board.
+func (b *Board) Snapshot() *BoardSnapshot {
+	b.mu.Lock()
+	snap := &BoardSnapshot{Time: clockOf(b.conn).Now()}
+	if b.Model != nil {
+		snap.Model = b.Model.Name
+	}
+	if b.pins != nil {
+		snap.Pins = b.pins.PinStates()
+	}
+	//drivers created with NewDriver are listed once, under their name, even
+	//if they also registered on the I2C bus
+	named := make(map[interface{}]bool)
+	for _, d := range b.drivers {
+		ds := DriverSnapshot{Name: d.name, Type: fmt.Sprintf("%T", d.driver), Args: d.args}
+		if i2c, ok := d.driver.(I2CDriver); ok {
+			ds.Address = i2c.Address()
+		}
+		snap.Drivers = append(snap.Drivers, ds)
+		if hashable(d.driver) {
+			named[d.driver] = true
+		}
+	}
+	if b.i2c != nil {
+		for _, d := range b.i2c.Drivers() {
+			if hashable(d) && named[d] {
+				continue
+			}
+			snap.Drivers = append(snap.Drivers, DriverSnapshot{Type: fmt.Sprintf("%T", d), Address: d.Address()})
+		}
+	}
+	servoPins := make([]string, 0, len(b.servos))
+	for pin := range b.servos {
+		servoPins = append(servoPins, pin)
+	}
+	sort.Strings(servoPins)
+	for _, pin := range servoPins {
+		snap.Drivers = append(snap.Drivers, DriverSnapshot{Type: fmt.Sprintf("%T", b.servos[pin]), Pin: pin})
+	}
+	b.mu.Unlock()
+
+	snap.Firmware = b.firmwareSnapshot()
+	return snap
+}
+
+//firmwareSnapshot queries the firmware for its uptime and classes
+func (b *Board) firmwareSnapshot() FirmwareSnapshot {
+	var fs FirmwareSnapshot
+	fc, isFirmware := b.conn.(*FirmwareConnection)
+	if isFirmware {
+		fs.Port = fc.Name()
+	}
+	var err error
+	fs.Millis, err = NewArduinoApi(b.conn).Millis()
+	if err != nil {
+		fs.Millis = 0
+		fs.Error = err.Error()
+		return fs
+	}
+	if isFirmware {
+		fs.Classes, err = fc.FirmwareClasses()
+		if err != nil {
+			fs.Error = err.Error()
+		}
+	}
+	return fs
+}
+
+//Apply brings the board to the state described by snap, e.g. one saved with
+//Snapshot and decoded from JSON: it sets the output value and then the mode
+//of each pin, and creates the servos and plugin drivers listed. Drivers not
+//created with NewDriver, other than servos, can't be recreated and are
+//ignored, as are drivers already attached to the board. Apply stops at the
+//first error.
+//
+//To restore a board after its firmware resets, call InvalidateCache on Pins
+//first if SkipRedundant is set, so no call is skipped.
+func (b *Board) Apply(snap *BoardSnapshot) error {
+	if snap.Model != "" {
+		m, ok := BoardModels[snap.Model]
+		if !ok {
+			return fmt.Errorf("unknown board model %q", snap.Model)
+		}
+		b.mu.Lock()
+		if b.Model == nil {
+			b.Model = m
+		}
+		model := b.Model
+		b.mu.Unlock()
+		if model != m {
+			return fmt.Errorf("snapshot of a %s board applied to a %s board", snap.Model, model.Name)
+		}
+	}
+	pins := make([]string, 0, len(snap.Pins))
+	for pin := range snap.Pins {
+		pins = append(pins, pin)
+	}
+	sort.Strings(pins)
+	api := b.Pins()
+	for _, pin := range pins {
+		ps := snap.Pins[pin]
+		mode := -1
+		if ps.Mode != "" {
+			var err error
+			mode, err = parsePinMode(ps.Mode)
+			if err != nil {
+				return fmt.Errorf("pin %s: %w", pin, err)
+			}
+		}
+		//the output is written before the mode, so a pin restored as a high
+		//output isn't driven low in between
+		var err error
+		switch {
+		case ps.Duty != nil:
+			err = api.AnalogWrite(pin, *ps.Duty)
+		case ps.Value != nil:
+			err = api.DigitalWrite(pin, *ps.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("setting output of pin %s: %w", pin, err)
+		}
+		if mode < 0 {
+			continue
+		}
+		if err := api.PinMode(pin, mode); err != nil {
+			return fmt.Errorf("setting mode of pin %s: %w", pin, err)
+		}
+	}
+	servo := fmt.Sprintf("%T", (*Servo)(nil))
+	for _, ds := range snap.Drivers {
+		var err error
+		switch {
+		case ds.Name != "":
+			if b.attached(ds.Name, ds.Args) {
+				continue
+			}
+			_, err = NewDriver(b, ds.Name, ds.Args)
+		case ds.Type == servo && ds.Pin != "":
+			_, err = b.Servo(ds.Pin)
+			if err != nil {
+				err = fmt.Errorf("servo on pin %s: %w", ds.Pin, err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//attached reports whether a driver named name was created on the board with
+//args
+func (b *Board) attached(name string, args map[string]string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, d := range b.drivers {
+		if d.name == name && (len(d.args) == 0 && len(args) == 0 || reflect.DeepEqual(d.args, args)) {
+			return true
+		}
+	}
+	return false
+}
+
+//parsePinMode parses a mode as named in a PinSnapshot
+func parsePinMode(name string) (int, error) {
+	if mode, err := ParsePinMode(name); err == nil {
+		return mode, nil
+	}
+	mode, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pin mode %q", name)
+	}
+	return mode, nil
+}
+
+//hashable reports whether v can be used as a map key
+func hashable(v interface{}) bool {
+	return v != nil && reflect.TypeOf(v).Comparable()
+}
@@ -0,0 +1,173 @@
+package nango
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type snapshotDriver struct {
+	addr I2CAddress
+}
+
+func (d *snapshotDriver) Address() I2CAddress {
+	return d.addr
+}
+
+func TestBoardSnapshot(t *testing.T) {
+	registerPlugin(t, &Plugin{
+		Name: "example.com/snapshot",
+		Drivers: map[string]DriverFactory{
+			"snapshotdriver": func(b *Board, args map[string]string) (interface{}, error) {
+				d := &snapshotDriver{addr: 0x48}
+				return d, b.I2C().Register(d)
+			},
+		},
+	})
+	lb, conn := openLoopback(t)
+	lb.Respond(NamespaceArduino, MethodMillis, "1234")
+	lb.Respond(NamespaceInfo, "count", "1")
+	lb.Respond(NamespaceInfo, "name", NamespaceArduino)
+	lb.Respond(NamespaceServo, MethodNew, "1")
+	b := NewBoard(conn)
+	b.Model = BoardModels["uno"]
+	api := b.Pins()
+	if err := api.PinMode("D13", PinOutput); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DigitalWrite("D13", PinHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PinMode("D2", PinInputPullup); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DigitalWrite("D6", PinHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AnalogWrite("D6", 128); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.I2C().Register(&snapshotDriver{addr: 0x20}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDriver(b, "snapshotdriver", map[string]string{"rate": "fast"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Servo("D9"); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := b.Snapshot()
+	if snap.Model != "uno" {
+		t.Fatalf("expected model uno, got %q", snap.Model)
+	}
+	expectedFirmware := FirmwareSnapshot{Port: "transport", Classes: []string{NamespaceArduino}, Millis: 1234}
+	if !reflect.DeepEqual(snap.Firmware, expectedFirmware) {
+		t.Fatalf("expected firmware %+v, got %+v", expectedFirmware, snap.Firmware)
+	}
+	high, duty := PinHigh, 128
+	expectedPins := map[string]PinSnapshot{
+		"D13": {Mode: "output", Value: &high},
+		"D2":  {Mode: "input_pullup"},
+		"D6":  {Duty: &duty},
+	}
+	if !reflect.DeepEqual(snap.Pins, expectedPins) {
+		t.Fatalf("expected pins %v, got %v", expectedPins, snap.Pins)
+	}
+	expectedDrivers := []DriverSnapshot{
+		{Name: "snapshotdriver", Type: "*nango.snapshotDriver", Address: 0x48, Args: map[string]string{"rate": "fast"}},
+		{Type: "*nango.snapshotDriver", Address: 0x20},
+		{Type: "*nango.Servo", Pin: "D9"},
+	}
+	if !reflect.DeepEqual(snap.Drivers, expectedDrivers) {
+		t.Fatalf("expected drivers %+v, got %+v", expectedDrivers, snap.Drivers)
+	}
+	if _, err := json.Marshal(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	//a firmware which stops responding is recorded rather than failing the
+	//snapshot
+	lb.Handle(NamespaceArduino, MethodMillis, func(LoopbackCall) (string, bool) { return "", false })
+	snap = b.Snapshot()
+	if snap.Firmware.Error == "" || len(snap.Pins) != 3 {
+		t.Fatalf("expected firmware error and pins, got %+v", snap)
+	}
+}
+
+func TestBoardApply(t *testing.T) {
+	created := 0
+	registerPlugin(t, &Plugin{
+		Name: "example.com/apply",
+		Drivers: map[string]DriverFactory{
+			"applydriver": func(b *Board, args map[string]string) (interface{}, error) {
+				created++
+				return &snapshotDriver{addr: 0x50}, nil
+			},
+		},
+	})
+	lb, conn := openLoopback(t)
+	var calls []string
+	record := func(c LoopbackCall) (string, bool) {
+		calls = append(calls, c.Method+" "+c.Args[0]+" "+c.Args[1])
+		return "", true
+	}
+	lb.Handle(NamespaceArduino, MethodPinMode, record)
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, record)
+	lb.Handle(NamespaceArduino, MethodAnalogWrite, record)
+	servos := 0
+	lb.Handle(NamespaceServo, MethodNew, func(LoopbackCall) (string, bool) {
+		servos++
+		return "1", true
+	})
+	var snap BoardSnapshot
+	err := json.Unmarshal([]byte(`{
+		"model": "uno",
+		"pins": {
+			"D13": {"mode": "output", "value": 1},
+			"D2": {"mode": "input_pullup"},
+			"D6": {"mode": "output", "duty": 64}
+		},
+		"drivers": [
+			{"name": "applydriver", "type": "*nango.snapshotDriver", "address": 80, "args": {"gain": "2"}},
+			{"type": "*nango.snapshotDriver", "address": 32},
+			{"type": "*nango.Servo", "pin": "D9"}
+		]
+	}`), &snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBoard(conn)
+	if err := b.Apply(&snap); err != nil {
+		t.Fatal(err)
+	}
+	//each output is written before its pin becomes an output
+	expected := []string{
+		"dw D13 1", "pm D13 1",
+		"pm D2 2",
+		"aw D6 64", "pm D6 1",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	if b.Model != BoardModels["uno"] || created != 1 || servos != 1 {
+		t.Fatalf("expected uno with driver and servo, got %v, %d drivers, %d servos", b.Model, created, servos)
+	}
+
+	//applying again restores the pins without recreating the drivers
+	calls = nil
+	if err := b.Apply(&snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != len(expected) || created != 1 || servos != 1 {
+		t.Fatalf("expected pins only to be set again, got %v, %d drivers, %d servos", calls, created, servos)
+	}
+
+	b.Model = BoardModels["mega2560"]
+	if err := b.Apply(&snap); err == nil {
+		t.Fatal("expected error applying an uno snapshot to a mega")
+	}
+	if err := b.Apply(&BoardSnapshot{Pins: map[string]PinSnapshot{"D3": {Mode: "sideways"}}}); err == nil {
+		t.Fatal("expected error for an invalid pin mode")
+	}
+}
@@ -0,0 +1,33 @@
+package nango
+
+//SoftwareSerial gives access to an instance of the arduino SoftwareSerial
+//library as an AttachedSerial
+//http://arduino.cc/en/Reference/SoftwareSerial
+type SoftwareSerial struct {
+	*uart
+}
+
+//NewSoftwareSerial creates a SoftwareSerial instance on the firmware using
+//the given receive and transmit pins
+func NewSoftwareSerial(conn Conn, rxPin string, txPin string) (*SoftwareSerial, error) {
+	f, err := NewFirmwareObject(conn, NamespaceSoftwareSerial, rxPin, txPin)
+	if err != nil {
+		return nil, err
+	}
+	return &SoftwareSerial{newUart(f)}, nil
+}
+
+//Listen makes this instance the one receiving data. Only one SoftwareSerial
+//instance can receive at a time.
+func (s *SoftwareSerial) Listen() error {
+	return s.CallAndReturnNothing(MethodSoftwareSerialListen)
+}
+
+//Close ends communication and destroys the firmware instance
+func (s *SoftwareSerial) Close() error {
+	err := s.CallAndReturnNothing(MethodStreamEnd)
+	if err != nil {
+		return err
+	}
+	return s.Remove()
+}
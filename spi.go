@@ -0,0 +1,192 @@
+package nango
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	SpiMode0 = iota
+	SpiMode1
+	SpiMode2
+	SpiMode3
+)
+
+//SpiSettings describes the clock speed, bit order and data mode used for an
+//SPI transaction
+//http://arduino.cc/en/reference/SPISettings
+type SpiSettings struct {
+	//Clock is the maximum clock speed in Hz
+	Clock int
+	//BitOrder is one of LsbFirst or MsbFirst
+	BitOrder int
+	//DataMode is one of SpiMode0 through SpiMode3
+	DataMode int
+}
+
+//DefaultSpiSettings matches the arduino SPISettings default constructor
+var DefaultSpiSettings = SpiSettings{
+	Clock:    4000000,
+	BitOrder: MsbFirst,
+	DataMode: SpiMode0,
+}
+
+type Spi struct {
+	*FirmwareClass
+	//mu serializes SPIDevice transactions sharing the bus
+	mu sync.Mutex
+}
+
+//NewSpi returns an Spi struct giving access to the arduino SPI library
+//http://arduino.cc/en/reference/SPI
+func NewSpi(conn Conn) *Spi {
+	return &Spi{
+		FirmwareClass: &FirmwareClass{
+			Conn:      conn,
+			Id:        StaticId,
+			Namespace: NamespaceSPI,
+		},
+	}
+}
+
+//Begin initializes the SPI bus, setting SCK, MOSI and SS to outputs
+func (s *Spi) Begin() error {
+	return s.CallAndReturnNothing(MethodSPIBegin)
+}
+
+//End disables the SPI bus
+func (s *Spi) End() error {
+	return s.CallAndReturnNothing(MethodSPIEnd)
+}
+
+//BeginTransaction gains exclusive use of the bus using the given settings
+func (s *Spi) BeginTransaction(settings SpiSettings) error {
+	return s.CallAndReturnNothing(MethodSPIBeginTransaction, settings.Clock, settings.BitOrder, settings.DataMode)
+}
+
+//EndTransaction releases the bus for use by other libraries
+func (s *Spi) EndTransaction() error {
+	return s.CallAndReturnNothing(MethodSPIEndTransaction)
+}
+
+//Transfer sends b and returns the byte received at the same time
+func (s *Spi) Transfer(b byte) (byte, error) {
+	v, err := s.CallAndReturnInt(MethodSPITransfer, int(b))
+	if err != nil {
+		return 0, err
+	}
+	return byte(v), nil
+}
+
+//TransferBytes sends the whole buffer in a single call and returns the bytes
+//received during the full-duplex transfer
+func (s *Spi) TransferBytes(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return []byte{}, nil
+	}
+	r, err := s.CallAndReturnBytes(MethodSPITransferBytes, b)
+	if err != nil {
+		return nil, err
+	}
+	if len(r) != len(b) {
+		return nil, fmt.Errorf("spi transferBytes: sent %d bytes but received %d", len(b), len(r))
+	}
+	return r, nil
+}
+
+//TransferInto sends tx in a single call and writes the bytes received into rx,
+//which must be at least as long as tx. Unlike TransferBytes it does not
+//allocate, which suits repeated transfers such as display refreshes.
+func (s *Spi) TransferInto(rx []byte, tx []byte) error {
+	if len(rx) < len(tx) {
+		return fmt.Errorf("spi transferInto: receive buffer of %d bytes too small for %d byte transfer", len(rx), len(tx))
+	}
+	if len(tx) == 0 {
+		return nil
+	}
+	b, err := s.CallAndBorrowBytes(MethodSPITransferBytes, tx)
+	if err != nil {
+		return err
+	}
+	defer b.Release()
+	if len(b.Bytes()) != len(tx) {
+		return fmt.Errorf("spi transferBytes: sent %d bytes but received %d", len(tx), len(b.Bytes()))
+	}
+	copy(rx, b.Bytes())
+	return nil
+}
+
+//SPIDevice is a slave on a shared SPI bus selected by an active low chip
+//select pin. Every transfer is bracketed by a bus transaction using the
+//device's settings, so devices with different clock speeds or modes can share
+//one bus.
+type SPIDevice struct {
+	spi      *Spi
+	api      *ArduinoApi
+	CsPin    string
+	Settings SpiSettings
+}
+
+func NewSPIDevice(spi *Spi, api *ArduinoApi, csPin string, settings SpiSettings) *SPIDevice {
+	return &SPIDevice{
+		spi:      spi,
+		api:      api,
+		CsPin:    csPin,
+		Settings: settings,
+	}
+}
+
+//Begin configures the chip select pin as an output and deselects the device
+func (d *SPIDevice) Begin() error {
+	err := d.api.PinMode(d.CsPin, PinOutput)
+	if err != nil {
+		return err
+	}
+	return d.api.DigitalWrite(d.CsPin, PinHigh)
+}
+
+//Tx runs fn with the bus claimed, the transaction started and the chip
+//selected. The device is deselected and the transaction ended even if fn fails.
+func (d *SPIDevice) Tx(fn func(spi *Spi) error) (err error) {
+	d.spi.mu.Lock()
+	defer d.spi.mu.Unlock()
+	err = d.spi.BeginTransaction(d.Settings)
+	if err != nil {
+		return
+	}
+	defer func() {
+		errEnd := d.spi.EndTransaction()
+		if err == nil {
+			err = errEnd
+		}
+	}()
+	err = d.api.DigitalWrite(d.CsPin, PinLow)
+	if err != nil {
+		return
+	}
+	defer func() {
+		errCs := d.api.DigitalWrite(d.CsPin, PinHigh)
+		if err == nil {
+			err = errCs
+		}
+	}()
+	return fn(d.spi)
+}
+
+//Transfer exchanges a single byte with the device
+func (d *SPIDevice) Transfer(b byte) (r byte, err error) {
+	err = d.Tx(func(spi *Spi) error {
+		r, err = spi.Transfer(b)
+		return err
+	})
+	return
+}
+
+//TransferBytes exchanges a buffer with the device in one transaction
+func (d *SPIDevice) TransferBytes(b []byte) (r []byte, err error) {
+	err = d.Tx(func(spi *Spi) error {
+		r, err = spi.TransferBytes(b)
+		return err
+	})
+	return
+}
@@ -0,0 +1,110 @@
+package nango
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+//attachSPISlave answers SPI transfers on lb with the complement of each byte
+//sent, dropping the last byte of transfers longer than short if short is set
+func attachSPISlave(lb *Loopback, short int) {
+	lb.Handle(NamespaceSPI, MethodSPITransfer, func(c LoopbackCall) (string, bool) {
+		v, _ := strconv.Atoi(c.Args[0])
+		return strconv.Itoa(int(^byte(v))), true
+	})
+	lb.Handle(NamespaceSPI, MethodSPITransferBytes, func(c LoopbackCall) (string, bool) {
+		b, _ := hex.DecodeString(c.Args[0])
+		for i := range b {
+			b[i] = ^b[i]
+		}
+		if short > 0 && len(b) > short {
+			b = b[:len(b)-1]
+		}
+		return hex.EncodeToString(b), true
+	})
+}
+
+func TestSpiTransfer(t *testing.T) {
+	lb, conn := openLoopback(t)
+	attachSPISlave(lb, 0)
+	s := NewSpi(conn)
+	if r, err := s.Transfer(0x0F); err != nil || r != 0xF0 {
+		t.Fatalf("expected 0xF0, got 0x%02X, %v", r, err)
+	}
+	r, err := s.TransferBytes([]byte{0x00, 0x55, 0xFF})
+	if err != nil || string(r) != string([]byte{0xFF, 0xAA, 0x00}) {
+		t.Fatalf("expected the complemented bytes, got % x, %v", r, err)
+	}
+	if r, err := s.TransferBytes(nil); err != nil || len(r) != 0 {
+		t.Fatalf("expected an empty transfer to succeed, got % x, %v", r, err)
+	}
+	rx := make([]byte, 4)
+	if err := s.TransferInto(rx, []byte{0x01, 0x02}); err != nil || string(rx) != string([]byte{0xFE, 0xFD, 0, 0}) {
+		t.Fatalf("expected the received bytes written into rx, got % x, %v", rx, err)
+	}
+	if err := s.TransferInto(make([]byte, 1), []byte{0x01, 0x02}); err == nil {
+		t.Fatal("expected an error for a receive buffer shorter than the transfer")
+	}
+}
+
+func TestSpiTransferLengthMismatch(t *testing.T) {
+	lb, conn := openLoopback(t)
+	attachSPISlave(lb, 1)
+	s := NewSpi(conn)
+	if _, err := s.TransferBytes([]byte{1, 2, 3}); err == nil {
+		t.Fatal("expected an error when fewer bytes are received than sent")
+	}
+	rx := make([]byte, 3)
+	if err := s.TransferInto(rx, []byte{1, 2, 3}); err == nil {
+		t.Fatal("expected an error when fewer bytes are received than sent")
+	}
+	if string(rx) != string(make([]byte, 3)) {
+		t.Fatalf("expected rx untouched after a failed transfer, got % x", rx)
+	}
+}
+
+func TestSPIDeviceTx(t *testing.T) {
+	lb, conn := openLoopback(t)
+	var calls []string
+	record := func(c LoopbackCall) (string, bool) {
+		calls = append(calls, c.Method+" "+strings.Join(c.Args, ","))
+		return "0", true
+	}
+	for _, m := range []string{MethodSPIBeginTransaction, MethodSPIEndTransaction, MethodSPITransfer} {
+		lb.Handle(NamespaceSPI, m, record)
+	}
+	lb.Handle(NamespaceArduino, MethodDigitalWrite, record)
+	d := NewSPIDevice(NewSpi(conn), NewArduinoApi(conn), "D10", SpiSettings{Clock: 1000000, BitOrder: MsbFirst, DataMode: SpiMode3})
+	expected := []string{
+		MethodSPIBeginTransaction + " 1000000,1,3",
+		MethodDigitalWrite + " D10,0",
+		MethodSPITransfer + " 66",
+		MethodDigitalWrite + " D10,1",
+		MethodSPIEndTransaction + " ",
+	}
+	if _, err := d.Transfer(0x42); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Fatalf("expected calls %q, got %q", expected, calls)
+	}
+
+	//the chip is deselected and the transaction ended when fn fails
+	calls = nil
+	failed := errors.New("failed")
+	err := d.Tx(func(spi *Spi) error {
+		if _, err := spi.Transfer(0x42); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if strings.Join(calls, ";") != strings.Join(expected, ";") {
+		t.Fatalf("expected calls %q, got %q", expected, calls)
+	}
+}
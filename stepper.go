@@ -0,0 +1,33 @@
+package nango
+
+//Stepper gives access to an instance of the arduino Stepper library
+//https://www.arduino.cc/en/Reference/Stepper
+type Stepper struct {
+	*FirmwareClass
+}
+
+//NewStepper creates a Stepper instance on the firmware for a motor with
+//stepsPerRev steps per revolution driven through pin1 and pin2
+func NewStepper(conn Conn, stepsPerRev int, pin1 string, pin2 string) (*Stepper, error) {
+	f, err := NewFirmwareObject(conn, NamespaceStepper, stepsPerRev, pin1, pin2)
+	if err != nil {
+		return nil, err
+	}
+	return &Stepper{f}, nil
+}
+
+//SetSpeed sets the speed of subsequent steps in revolutions per minute
+func (s *Stepper) SetSpeed(rpm int) error {
+	return s.CallAndReturnNothing(MethodStepperSetSpeed, rpm)
+}
+
+//Step turns the motor by steps, backwards if negative. The firmware responds
+//once the steps are complete, so large moves need a long read timeout.
+func (s *Stepper) Step(steps int) error {
+	return s.CallAndReturnNothing(MethodStepperStep, steps)
+}
+
+//Close destroys the firmware instance
+func (s *Stepper) Close() error {
+	return s.Remove()
+}
@@ -0,0 +1,153 @@
+package nango
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//StreamStats accounts for the data delivered and lost by a SampleStream
+type StreamStats struct {
+	Batches int
+	Samples int
+	//Dropped is the total number of samples the firmware discarded because
+	//its ring buffer overflowed between fetches
+	Dropped int
+	//Pauses is the number of times sampling was paused because the consumer
+	//had no credit left
+	Pauses int
+	//Paused is the total time sampling was paused for
+	Paused time.Duration
+}
+
+//SampleStream fetches batches from an AnalogSampler in the background and
+//delivers them on C. C holds at most the number of batches the stream was
+//given credit for; when the consumer falls that far behind, sampling is
+//paused in firmware until a batch is received, so a slow consumer causes gaps
+//in sampling rather than unbounded buffering or silent loss. Samples the
+//firmware drops are counted in Stats and in each batch.
+type SampleStream struct {
+	C <-chan SampleBatch
+
+	sampler *AnalogSampler
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu    sync.Mutex
+	stats StreamStats
+	err   error
+}
+
+//Stream starts delivering samples, fetching up to max samples every poll
+//interval. credits is the number of undelivered batches allowed before
+//sampling is paused. The sampler must already have been started.
+func (a *AnalogSampler) Stream(poll time.Duration, max int, credits int) *SampleStream {
+	if credits < 1 {
+		credits = 1
+	}
+	c := make(chan SampleBatch, credits)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SampleStream{
+		C:       c,
+		sampler: a,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go s.run(ctx, c, poll, max)
+	return s
+}
+
+func (s *SampleStream) run(ctx context.Context, c chan<- SampleBatch, poll time.Duration, max int) {
+	defer close(s.done)
+	defer close(c)
+	clock := clockOf(s.sampler.Conn)
+	//fetches are timed from an absolute deadline so the time taken by each
+	//fetch doesn't add up to a drift in the period
+	next := clock.Now()
+	for {
+		next = next.Add(poll)
+		if now := clock.Now(); next.Before(now) {
+			//fetch once after falling behind rather than for every period
+			//missed
+			next = now
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(next.Sub(clock.Now())):
+		}
+		batch, err := s.sampler.Fetch(max)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if len(batch.Samples) == 0 && batch.Dropped == 0 {
+			continue
+		}
+		s.mu.Lock()
+		s.stats.Batches++
+		s.stats.Samples += len(batch.Samples)
+		s.stats.Dropped += batch.Dropped
+		s.mu.Unlock()
+		select {
+		case c <- batch:
+			continue
+		default:
+		}
+		//out of credit
+		err = s.sampler.Pause()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		paused := clock.Now()
+		select {
+		case c <- batch:
+		case <-ctx.Done():
+			//leave the sampler running as documented by Close
+			err = s.sampler.Resume()
+			if err != nil {
+				s.fail(err)
+			}
+			return
+		}
+		s.mu.Lock()
+		s.stats.Pauses++
+		s.stats.Paused += clock.Now().Sub(paused)
+		s.mu.Unlock()
+		err = s.sampler.Resume()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+func (s *SampleStream) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+//Stats returns the stream's accounting so far
+func (s *SampleStream) Stats() StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+//Err returns the error which ended the stream, if any. C is closed when the
+//stream ends.
+func (s *SampleStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+//Close stops the stream and returns the error which ended it, if any. The
+//sampler is left running.
+func (s *SampleStream) Close() error {
+	s.cancel()
+	<-s.done
+	return s.Err()
+}
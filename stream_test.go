@@ -0,0 +1,54 @@
+package nango_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"github.com/justinsantoro/nango/nangotest"
+)
+
+func TestSampleStreamPeriod(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := nangotest.NewClock(start)
+	lb, conn := openClocked(t, clock)
+	var mu sync.Mutex
+	var fetches []time.Duration
+	lb.Handle(nango.NamespaceSampler, nango.MethodSamplerFetch, func(c nango.LoopbackCall) (string, bool) {
+		mu.Lock()
+		fetches = append(fetches, clock.Now().Sub(start))
+		mu.Unlock()
+		//each fetch takes 3ms
+		clock.Advance(3 * time.Millisecond)
+		return "0,0100", true
+	})
+	s := nango.NewAnalogSampler(conn).Stream(10*time.Millisecond, 16, 10)
+	defer s.Close()
+
+	//step the clock whenever the stream waits on it until three batches
+	//have been delivered
+	deadline := time.Now().Add(5 * time.Second)
+	for batches := 0; batches < 3; {
+		select {
+		case <-s.C:
+			batches++
+			continue
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d batches, expected 3", batches)
+		}
+		if clock.Waiters() > 0 {
+			clock.Advance(time.Millisecond)
+		} else {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	if len(fetches) < 3 || fetches[0] != expected[0] || fetches[1] != expected[1] || fetches[2] != expected[2] {
+		t.Fatalf("expected fetches at %v, got %v", expected, fetches)
+	}
+}
@@ -0,0 +1,109 @@
+package nango
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+//DialTCP returns a DialFunc connecting to a board exposed on the network at
+//address, e.g. by a serial to TCP bridge or a WiFi board running the
+//firmware, for WithTransport. Anyone who can reach the port can drive the
+//board, so use DialTLS or WithSharedSecret on networks which aren't trusted.
+func DialTCP(address string) DialFunc {
+	return func() (Transport, error) {
+		c, err := net.DialTimeout("tcp", address, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return &netTransport{Conn: c}, nil
+	}
+}
+
+//DialTLS is like DialTCP but encrypts the connection with TLS configured by
+//conf, which should verify the server's certificate and, to authenticate the
+//connection to a server requiring client certificates, hold one, e.g. as
+//returned by NewTLSConfig
+func DialTLS(address string, conf *tls.Config) DialFunc {
+	return func() (Transport, error) {
+		d := &net.Dialer{Timeout: 10 * time.Second}
+		c, err := tls.DialWithDialer(d, "tcp", address, conf)
+		if err != nil {
+			return nil, err
+		}
+		return &netTransport{Conn: c}, nil
+	}
+}
+
+//NewTLSConfig returns a TLS configuration verifying the server's certificate
+//against the PEM encoded certificate authorities in caFile, or the system's
+//if caFile is empty, and presenting the certificate and key in certFile and
+//keyFile, if given, as the client's certificate
+func NewTLSConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+//netTransport adapts a network connection to a Transport
+type netTransport struct {
+	net.Conn
+}
+
+//Read returns 0 and io.EOF if no data arrives within readPollInterval. The
+//peer closing the connection is an error, so calls fail at once rather than
+//waiting out their timeout.
+func (t *netTransport) Read(b []byte) (int, error) {
+	if err := t.SetReadDeadline(time.Now().Add(readPollInterval)); err != nil {
+		return 0, err
+	}
+	n, err := t.Conn.Read(b)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return n, io.EOF
+	}
+	if err == io.EOF {
+		return n, fmt.Errorf("connection closed by %s: %w", t.RemoteAddr(), io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+//Flush discards data already received
+func (t *netTransport) Flush() error {
+	var buf [256]byte
+	for {
+		if err := t.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+			return err
+		}
+		_, err := t.Conn.Read(buf[:])
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
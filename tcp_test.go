@@ -0,0 +1,180 @@
+package nango
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+//serveLoopback answers each connection accepted by l with a new Loopback, as
+//a serial to TCP bridge would with a board
+func serveLoopback(t *testing.T, newLoopback func() *Loopback, l net.Listener) {
+	t.Helper()
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		l.Close()
+	})
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			tr, _ := newLoopback().Dial()
+			go func() {
+				buf := make([]byte, 256)
+				for {
+					n, err := c.Read(buf)
+					if err != nil {
+						return
+					}
+					tr.Write(buf[:n])
+				}
+			}()
+			go func() {
+				defer c.Close()
+				buf := make([]byte, 256)
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					n, _ := tr.Read(buf)
+					if n > 0 {
+						if _, err := c.Write(buf[:n]); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+}
+
+//handleAuth answers the handshake of WithSharedSecret as a board with key
+func handleAuth(lb *Loopback, key []byte) {
+	nonce := []byte("0123456789abcdef")
+	lb.Respond(NamespaceAuth, "challenge", hex.EncodeToString(nonce))
+	lb.Handle(NamespaceAuth, "respond", func(c LoopbackCall) (string, bool) {
+		theirs, _ := hex.DecodeString(c.Args[1])
+		if c.Args[0] != hex.EncodeToString(authMAC(key, authRoleHost, nonce, theirs)) {
+			return "!ERR respond\tbad key\t512", true
+		}
+		return hex.EncodeToString(authMAC(key, authRoleBoard, nonce, theirs)), true
+	})
+}
+
+func TestTCPSharedSecret(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveLoopback(t, func() *Loopback {
+		lb := NewLoopback()
+		lb.Respond(NamespaceArduino, MethodDigitalRead, "1")
+		handleAuth(lb, []byte("secret"))
+		return lb
+	}, l)
+
+	open := func(key string) (*FirmwareConnection, error) {
+		conn := NewFirmwareConnection(nil,
+			WithTransport(DialTCP(l.Addr().String())),
+			WithReadTimeout(500*time.Millisecond),
+			WithSharedSecret([]byte(key)))
+		return conn, conn.Open()
+	}
+	conn, err := open("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := NewArduinoApi(conn).DigitalRead("D2"); err != nil || v != 1 {
+		t.Fatalf("expected 1 over TCP, got %v (%v)", v, err)
+	}
+	conn.Close()
+
+	if _, err := open("guess"); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected authentication to fail, got %v", err)
+	}
+}
+
+func TestSharedSecretImpostor(t *testing.T) {
+	lb := NewLoopback()
+	//a board which accepts any key but can't prove it knows the secret
+	lb.Respond(NamespaceAuth, "challenge", hex.EncodeToString(make([]byte, authNonceSize)))
+	lb.Respond(NamespaceAuth, "respond", hex.EncodeToString(make([]byte, 32)))
+	conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithSharedSecret([]byte("secret")))
+	if err := conn.Open(); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected authentication to fail, got %v", err)
+	}
+}
+
+func TestSharedSecretReflection(t *testing.T) {
+	//an impostor board relays the nonce the host sends on one connection as
+	//its challenge on a second, hoping the host's answer proves it knows the
+	//secret on the first
+	hostNonce := make(chan string, 1)
+	reflected := make(chan string, 1)
+	first := NewLoopback()
+	first.Respond(NamespaceAuth, "challenge", hex.EncodeToString(make([]byte, authNonceSize)))
+	first.Handle(NamespaceAuth, "respond", func(c LoopbackCall) (string, bool) {
+		hostNonce <- c.Args[1]
+		return <-reflected, true
+	})
+	second := NewLoopback()
+	second.Handle(NamespaceAuth, "challenge", func(LoopbackCall) (string, bool) {
+		return <-hostNonce, true
+	})
+	second.Handle(NamespaceAuth, "respond", func(c LoopbackCall) (string, bool) {
+		reflected <- c.Args[0]
+		return "!ERR respond\tbad key\t512", true
+	})
+	open := func(lb *Loopback) error {
+		conn := NewFirmwareConnection(nil, WithTransport(lb.Dial), WithReadTimeout(time.Second), WithSharedSecret([]byte("secret")))
+		err := conn.Open()
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	result := make(chan error, 1)
+	go func() { result <- open(first) }()
+	open(second)
+	if err := <-result; !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected the reflected proof to be rejected, got %v", err)
+	}
+}
+
+func TestTCPPeerClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		//a bridge which drops the link as soon as a call arrives
+		c.Read(make([]byte, 64))
+		c.Close()
+	}()
+	conn := NewFirmwareConnection(nil, WithTransport(DialTCP(l.Addr().String())), WithReadTimeout(5*time.Second))
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	_, err = NewArduinoApi(conn).DigitalRead("D2")
+	var te *TransportError
+	if !errors.As(err, &te) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a TransportError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the closed link to fail the call at once, took %s", elapsed)
+	}
+}
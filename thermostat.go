@@ -0,0 +1,219 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+//RelayOutput switches the relay called name on for any demand above zero
+func RelayOutput(b *RelayBank, name string) OutputFunc {
+	return func(ctx context.Context, v float64) error {
+		return b.Set(name, v > 0)
+	}
+}
+
+//SetpointPeriod sets the setpoint from a time of day until the next period
+type SetpointPeriod struct {
+	//From is the start of the period as the time since local midnight
+	From     time.Duration
+	Setpoint float64
+}
+
+//ThermostatState is a snapshot of a Thermostat
+type ThermostatState struct {
+	Updated     time.Time
+	Temperature float64
+	Setpoint    float64
+	//Demand is the output last written, from 0 to 1
+	Demand float64
+	//OnSince is when the output was last switched on, zero if it is off
+	OnSince time.Time
+	//SensorErr is the error of the latest reading, if it failed
+	SensorErr error
+	//Fault is set once the thermostat has locked its output off, until
+	//ClearFault is called
+	Fault error
+}
+
+//ErrMaxRuntime is the fault of a thermostat or irrigation zone whose output
+//has been on for longer than its MaxRuntime
+var ErrMaxRuntime = errors.New("output on for longer than the maximum runtime")
+
+//defaultSensorFailures rides out an occasional failed reading without
+//holding a stale demand for long
+const defaultSensorFailures = 3
+
+//Thermostat controls a heater from a temperature sensor. By default it
+//switches the output fully on or off with hysteresis around the setpoint;
+//with a PID, whose output range should be 0 to 1, the output is
+//proportional.
+type Thermostat struct {
+	Sensor ReadFunc
+	//Output is written the demand for heat, from 0 to 1
+	Output OutputFunc
+	//Interval is how often the temperature is read
+	Interval time.Duration
+	//Setpoint is the target temperature outside of the Schedule
+	Setpoint float64
+	//Schedule, if set, varies the setpoint by time of day
+	Schedule []SetpointPeriod
+	//Hysteresis is the width of the band around the setpoint within which
+	//the output isn't switched
+	Hysteresis float64
+	PID        *PID
+	//MaxTemp, if non-zero, switches the output off while the temperature is
+	//at or above it
+	MaxTemp float64
+	//MaxRuntime, if non-zero, locks the output off with ErrMaxRuntime if it
+	//stays on for longer, e.g. because the heater has failed
+	MaxRuntime time.Duration
+	//SensorFailures is the number of consecutive failed readings after which
+	//the output falls back to FallbackDemand, 3 if zero
+	SensorFailures int
+	FallbackDemand float64
+
+	mu       sync.Mutex
+	state    ThermostatState
+	failures int
+	sched    *Scheduler
+	name     string
+	stopped  bool
+}
+
+//setpointAt returns the setpoint scheduled for t
+func (th *Thermostat) setpointAt(t time.Time) float64 {
+	if len(th.Schedule) == 0 {
+		return th.Setpoint
+	}
+	periods := append([]SetpointPeriod(nil), th.Schedule...)
+	sort.Slice(periods, func(i, j int) bool { return periods[i].From < periods[j].From })
+	y, m, d := t.Date()
+	sinceMidnight := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	//the last period of the day carries on past midnight
+	sp := periods[len(periods)-1].Setpoint
+	for _, p := range periods {
+		if p.From <= sinceMidnight {
+			sp = p.Setpoint
+		}
+	}
+	return sp
+}
+
+//Step reads the temperature and updates the output at now
+func (th *Thermostat) Step(ctx context.Context, now time.Time) error {
+	v, err := th.Sensor(ctx)
+	th.mu.Lock()
+	s := &th.state
+	s.Updated = now
+	s.Setpoint = th.setpointAt(now)
+	s.SensorErr = err
+	var demand float64
+	switch {
+	case s.Fault != nil:
+		//the output stays locked off, whatever the sensor reads
+		if err != nil {
+			th.failures++
+		} else {
+			th.failures = 0
+			s.Temperature = v
+		}
+	case err != nil:
+		th.failures++
+		demand = s.Demand
+		limit := th.SensorFailures
+		if limit == 0 {
+			limit = defaultSensorFailures
+		}
+		if th.failures >= limit {
+			demand = th.FallbackDemand
+		}
+	default:
+		th.failures = 0
+		s.Temperature = v
+		demand = th.demand(now, v, s.Setpoint)
+	}
+	if demand > 0 && s.OnSince.IsZero() {
+		s.OnSince = now
+	} else if demand <= 0 {
+		s.OnSince = time.Time{}
+	}
+	if demand > 0 && th.MaxRuntime > 0 && now.Sub(s.OnSince) >= th.MaxRuntime {
+		s.Fault = ErrMaxRuntime
+		s.OnSince = time.Time{}
+		demand = 0
+	}
+	s.Demand = demand
+	stopped := th.stopped
+	th.mu.Unlock()
+	if stopped {
+		//Stop was called during the step
+		return nil
+	}
+	if errOutput := th.Output(ctx, demand); errOutput != nil {
+		return errOutput
+	}
+	return err
+}
+
+//demand computes the demand for heat with no fault or sensor failure
+func (th *Thermostat) demand(now time.Time, v float64, setpoint float64) float64 {
+	if th.MaxTemp != 0 && v >= th.MaxTemp {
+		return 0
+	}
+	if th.PID != nil {
+		th.PID.SetSetpoint(setpoint)
+		return th.PID.Update(now, v)
+	}
+	switch on := th.state.Demand > 0; {
+	case on && v >= setpoint+th.Hysteresis/2:
+		return 0
+	case !on && v <= setpoint-th.Hysteresis/2:
+		return 1
+	case on:
+		return 1
+	}
+	return 0
+}
+
+//Snapshot returns the thermostat's current state
+func (th *Thermostat) Snapshot() ThermostatState {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	return th.state
+}
+
+//ClearFault releases the output after a fault
+func (th *Thermostat) ClearFault() {
+	th.mu.Lock()
+	th.state.Fault = nil
+	th.mu.Unlock()
+}
+
+//Start runs the thermostat every Interval on s as the task called name
+func (th *Thermostat) Start(s *Scheduler, name string) {
+	th.mu.Lock()
+	th.sched, th.name = s, name
+	th.stopped = false
+	th.mu.Unlock()
+	s.Every(name, th.Interval, func(ctx context.Context) error {
+		return th.Step(ctx, s.clock.Now())
+	})
+}
+
+//Stop stops the thermostat and switches the output off
+func (th *Thermostat) Stop() error {
+	th.mu.Lock()
+	s, name := th.sched, th.name
+	th.sched = nil
+	th.stopped = true
+	th.state.Demand = 0
+	th.state.OnSince = time.Time{}
+	th.mu.Unlock()
+	if s != nil {
+		s.Remove(name)
+	}
+	return th.Output(context.Background(), 0)
+}
@@ -0,0 +1,120 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThermostat(t *testing.T) {
+	temp := 18.0
+	var sensorErr error
+	var out []float64
+	th := &Thermostat{
+		Sensor: func(ctx context.Context) (float64, error) { return temp, sensorErr },
+		Output: func(ctx context.Context, v float64) error {
+			out = append(out, v)
+			return nil
+		},
+		Setpoint:       20,
+		Hysteresis:     1,
+		MaxRuntime:     10 * time.Minute,
+		SensorFailures: 2,
+	}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	step := func(v float64) float64 {
+		temp = v
+		th.Step(context.Background(), now)
+		now = now.Add(time.Minute)
+		return th.Snapshot().Demand
+	}
+	for _, c := range []struct{ temp, demand float64 }{
+		{18, 1}, {20, 1}, {20.6, 0}, {20, 0}, {19.4, 1},
+	} {
+		if d := step(c.temp); d != c.demand {
+			t.Fatalf("at %v expected demand %v, got %v", c.temp, c.demand, d)
+		}
+	}
+
+	//the output stays on while a single reading fails, then falls back
+	sensorErr = errors.New("timeout")
+	if step(0) != 1 || step(0) != 0 {
+		t.Fatal("expected fallback after 2 sensor failures")
+	}
+	sensorErr = nil
+
+	for i := 0; i < 11; i++ {
+		step(10)
+	}
+	if s := th.Snapshot(); s.Fault != ErrMaxRuntime || s.Demand != 0 {
+		t.Fatalf("expected max runtime fault, got %+v", s)
+	}
+	th.ClearFault()
+	if step(10) != 1 {
+		t.Fatal("expected output on after clearing the fault")
+	}
+}
+
+func TestThermostatSchedule(t *testing.T) {
+	th := &Thermostat{
+		Setpoint: 20,
+		Schedule: []SetpointPeriod{
+			{From: 22 * time.Hour, Setpoint: 16},
+			{From: 7 * time.Hour, Setpoint: 21},
+		},
+	}
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		at time.Duration
+		sp float64
+	}{
+		{3 * time.Hour, 16}, {7 * time.Hour, 21}, {12 * time.Hour, 21}, {23 * time.Hour, 16},
+	} {
+		if sp := th.setpointAt(day.Add(c.at)); sp != c.sp {
+			t.Errorf("at %s expected setpoint %v, got %v", c.at, c.sp, sp)
+		}
+	}
+}
+
+func TestThermostatFaultLockout(t *testing.T) {
+	sensorErr := errors.New("timeout")
+	var out []float64
+	th := &Thermostat{
+		Sensor: func(ctx context.Context) (float64, error) { return 0, sensorErr },
+		Output: func(ctx context.Context, v float64) error {
+			out = append(out, v)
+			return nil
+		},
+		Setpoint:       20,
+		FallbackDemand: 0.5,
+	}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	step := func() float64 {
+		th.Step(context.Background(), now)
+		now = now.Add(time.Minute)
+		return th.Snapshot().Demand
+	}
+	//a zero SensorFailures falls back after the default number of failures
+	for i := 1; i < defaultSensorFailures; i++ {
+		if d := step(); d != 0 {
+			t.Fatalf("expected no fallback after %d failures, got demand %v", i, d)
+		}
+	}
+	if d := step(); d != 0.5 {
+		t.Fatalf("expected fallback demand, got %v", d)
+	}
+
+	//a latched fault keeps the output off while the sensor keeps failing
+	th.mu.Lock()
+	th.state.Fault = ErrMaxRuntime
+	th.mu.Unlock()
+	for i := 0; i < 2*defaultSensorFailures; i++ {
+		if d := step(); d != 0 {
+			t.Fatalf("expected the fault to hold the output off, got demand %v", d)
+		}
+	}
+	if out[len(out)-1] != 0 {
+		t.Fatalf("expected output written off, got %v", out)
+	}
+}
@@ -0,0 +1,155 @@
+package nango
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nango test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+//issue returns the PEM encoded certificate and key of a leaf
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSMutualAuthentication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nango")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, b []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "bridge", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "host", x509.ExtKeyUsageClientAuth)
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveLoopback(t, func() *Loopback {
+		lb := NewLoopback()
+		lb.Respond(NamespaceArduino, MethodDigitalRead, "1")
+		return lb
+	}, l)
+
+	caFile := write("ca.pem", ca.pem)
+	read := func(conf *tls.Config) (int, error) {
+		conn := NewFirmwareConnection(nil, WithTransport(DialTLS(l.Addr().String(), conf)), WithReadTimeout(500*time.Millisecond))
+		if err := conn.Open(); err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return NewArduinoApi(conn).DigitalRead("D2")
+	}
+
+	conf, err := NewTLSConfig(caFile, write("host.pem", clientCert), write("host.key", clientKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := read(conf); err != nil || v != 1 {
+		t.Fatalf("expected 1 over TLS, got %v (%v)", v, err)
+	}
+
+	//the bridge rejects hosts without a client certificate
+	conf, err = NewTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := read(conf); err == nil {
+		t.Fatal("expected a host without a certificate rejected")
+	}
+
+	//and the host rejects bridges it doesn't trust
+	other := newTestCA(t)
+	conf, err = NewTLSConfig(write("other.pem", other.pem), write("host.pem", clientCert), write("host.key", clientKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := read(conf); err == nil {
+		t.Fatal("expected an untrusted bridge rejected")
+	}
+
+	if _, err := NewTLSConfig(caFile, "host.pem", ""); err == nil {
+		t.Fatal("expected an error for a certificate without a key")
+	}
+}
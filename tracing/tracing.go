@@ -0,0 +1,108 @@
+//Package tracing records nango calls as OpenTelemetry spans, so interactions
+//with a board show up in the traces of the larger system driving it.
+//
+//	t := tracing.New(otel.GetTracerProvider())
+//	conn := nango.NewFirmwareConnection(conf, t.Options()...)
+//
+//Each call gets a "nango.call" span, covering any other middleware, with a
+//"nango.transport" child span covering the time the call waited for the
+//connection, was sent and retried until its response was read.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/justinsantoro/nango"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/justinsantoro/nango/tracing"
+
+//Attribute keys set on call spans
+const (
+	NamespaceKey    = attribute.Key("nango.namespace")
+	MethodKey       = attribute.Key("nango.method")
+	ArgsKey         = attribute.Key("nango.args")
+	ArgsSizeKey     = attribute.Key("nango.args_size")
+	ResponseSizeKey = attribute.Key("nango.response_size")
+)
+
+//Tracer creates spans for calls. It implements nango.Tracer, which times the
+//transport, and its Middleware method is a nango.Middleware which records
+//the call as a whole.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+//New returns a Tracer creating spans with tracers from tp
+func New(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+//Options returns the options which install t on a connection
+func (t *Tracer) Options() []nango.Option {
+	return []nango.Option{nango.WithMiddleware(t.Middleware), nango.WithTracer(t)}
+}
+
+//Middleware starts a span for every call made on the connection
+func (t *Tracer) Middleware(next nango.CallFunc) nango.CallFunc {
+	return func(ctx context.Context, c *nango.CallInfo) (*nango.Buffer, error) {
+		ctx, span := t.tracer.Start(ctx, "nango.call",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				NamespaceKey.String(c.Namespace),
+				MethodKey.String(c.Method),
+				ArgsKey.Int(len(c.Args)),
+				ArgsSizeKey.Int(argsSize(c.Args)),
+			))
+		defer span.End()
+		b, err := next(ctx, c)
+		if err != nil {
+			end(span, err)
+			return b, err
+		}
+		if b != nil {
+			span.SetAttributes(ResponseSizeKey.Int(len(b.Bytes())))
+		}
+		return b, nil
+	}
+}
+
+//StartCall implements nango.Tracer
+func (t *Tracer) StartCall(ctx context.Context, namespace string, method string) (context.Context, func(error)) {
+	ctx, span := t.tracer.Start(ctx, "nango.transport",
+		trace.WithAttributes(NamespaceKey.String(namespace), MethodKey.String(method)))
+	return ctx, func(err error) {
+		end(span, err)
+		span.End()
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+//argsSize approximates the number of bytes args take up on the wire
+func argsSize(args []interface{}) int {
+	n := 0
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+		case string:
+			n += len(v) + 1
+		case []byte:
+			n += 2*len(v) + 1
+		case []interface{}:
+			n += argsSize(v)
+		default:
+			n += len(fmt.Sprint(v)) + 1
+		}
+	}
+	return n
+}
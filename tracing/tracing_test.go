@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justinsantoro/nango"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//recorder is a TracerProvider keeping the spans started with it in memory
+type recorder struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (r *recorder) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return r
+}
+
+func (r *recorder) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	conf := trace.NewSpanStartConfig(opts...)
+	s := &span{
+		Span:  trace.SpanFromContext(context.Background()),
+		name:  name,
+		kind:  conf.SpanKind(),
+		attrs: conf.Attributes(),
+	}
+	s.parent, _ = trace.SpanFromContext(ctx).(*span)
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+//Ended returns the spans which have been ended, in the order they started
+func (r *recorder) Ended() []*span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ended []*span
+	for _, s := range r.spans {
+		if !s.end.IsZero() {
+			ended = append(ended, s)
+		}
+	}
+	return ended
+}
+
+type span struct {
+	trace.Span
+	name   string
+	kind   trace.SpanKind
+	parent *span
+	attrs  []attribute.KeyValue
+	status codes.Code
+	errs   []error
+	end    time.Time
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	s.attrs = append(s.attrs, kv...)
+}
+
+func (s *span) RecordError(err error, opts ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *span) End(opts ...trace.SpanEndOption) {
+	s.end = time.Now()
+}
+
+func (s *span) attr(k attribute.Key) attribute.Value {
+	for _, kv := range s.attrs {
+		if kv.Key == k {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracer(t *testing.T) {
+	rec := &recorder{}
+	lb := nango.NewLoopback()
+	lb.Respond("T", "get", "12345")
+	lb.Respond("T", "fail", "!ERR fail\tassert i < 8\t312")
+	opts := append(New(rec).Options(), nango.WithTransport(lb.Dial), nango.WithReadTimeout(100*time.Millisecond))
+	conn := nango.NewFirmwareConnection(nil, opts...)
+	if err := conn.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f := &nango.FirmwareClass{Conn: conn, Namespace: "T"}
+	rec.mu.Lock()
+	rec.spans = nil
+	rec.mu.Unlock()
+
+	if _, err := f.CallAndReturnInt("get", 42, "ab"); err != nil {
+		t.Fatal(err)
+	}
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a call and a transport span, got %d spans", len(spans))
+	}
+	call, transport := spans[0], spans[1]
+	if call.name != "nango.call" || call.kind != trace.SpanKindClient || call.parent != nil {
+		t.Fatalf("expected a root client call span, got %+v", call)
+	}
+	if transport.name != "nango.transport" || transport.parent != call {
+		t.Fatalf("expected the transport span to be a child of the call span, got %+v", transport)
+	}
+	if call.attr(NamespaceKey).AsString() != "T" || call.attr(MethodKey).AsString() != "get" ||
+		call.attr(ArgsKey).AsInt64() != 2 || call.attr(ArgsSizeKey).AsInt64() != 6 ||
+		call.attr(ResponseSizeKey).AsInt64() != 5 {
+		t.Fatalf("unexpected call attributes %v", call.attrs)
+	}
+	if call.status != codes.Unset || len(call.errs) != 0 {
+		t.Fatalf("expected no error recorded, got %v", call.errs)
+	}
+
+	rec.mu.Lock()
+	rec.spans = nil
+	rec.mu.Unlock()
+	_, err := f.CallAndReturnInt("fail")
+	var exc *nango.FirmwareException
+	if !errors.As(err, &exc) {
+		t.Fatalf("expected a FirmwareException, got %v", err)
+	}
+	spans = rec.Ended()
+	if len(spans) != 2 || spans[0].status != codes.Error || len(spans[0].errs) != 1 {
+		t.Fatalf("expected the error recorded on the call span, got %+v", spans)
+	}
+}
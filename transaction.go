@@ -0,0 +1,140 @@
+package nango
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//Transaction sequences operations on several subsystems which are sent to the
+//firmware as one transfer by Commit, so no other calls on the connection are
+//interleaved with them. The firmware executes the operations in order and
+//carries on after one fails, so a transaction is only as atomic as the
+//hardware allows; rollback hooks registered with OnRollback run if any
+//operation fails.
+//
+//Operations bypass the caches of ArduinoApi, which should be invalidated
+//after committing pin writes made in a transaction.
+type Transaction struct {
+	p         *Pipeline
+	checks    []txCheck
+	rollbacks []func() error
+}
+
+//txCheck validates the result of an operation once committed
+type txCheck struct {
+	r     *PipelineResult
+	check func(r *PipelineResult) error
+}
+
+//Transaction returns an empty Transaction on the connection
+func (s *FirmwareConnection) Transaction() *Transaction {
+	return &Transaction{p: s.Pipeline()}
+}
+
+func (t *Transaction) arduino() *FirmwareClass {
+	return &FirmwareClass{Conn: t.p.conn, Id: StaticId, Namespace: NamespaceArduino}
+}
+
+func (t *Transaction) add(r *PipelineResult, check func(r *PipelineResult) error) {
+	t.checks = append(t.checks, txCheck{r, check})
+}
+
+//Call adds a call to methodName on f
+func (t *Transaction) Call(f *FirmwareClass, methodName string, args ...interface{}) *Transaction {
+	t.add(t.p.Call(f, methodName, args...), nil)
+	return t
+}
+
+//PinMode adds setting the mode of pin
+func (t *Transaction) PinMode(pin string, mode int) *Transaction {
+	return t.Call(t.arduino(), MethodPinMode, pin, mode)
+}
+
+//DigitalWrite adds writing val to the digital pin
+func (t *Transaction) DigitalWrite(pin string, val int) *Transaction {
+	return t.Call(t.arduino(), MethodDigitalWrite, pin, val)
+}
+
+//AnalogWrite adds writing the PWM duty cycle val to pin
+func (t *Transaction) AnalogWrite(pin string, val int) *Transaction {
+	return t.Call(t.arduino(), MethodAnalogWrite, pin, val)
+}
+
+//Delay adds a pause of d, rounded down to milliseconds, made by the
+//firmware. The firmware must implement MethodDelay.
+func (t *Transaction) Delay(d time.Duration) *Transaction {
+	return t.Call(t.arduino(), MethodDelay, int(d/time.Millisecond))
+}
+
+//I2CWrite adds a transmission of data to the slave at address. The I2C bus
+//must already have been joined as a master.
+func (t *Transaction) I2CWrite(address I2CAddress, data []byte) *Transaction {
+	w := NewWire(t.p.conn)
+	t.Call(w.FirmwareClass, MethodWireBeginTransmission, address.Value())
+	for _, b := range data {
+		t.Call(w.FirmwareClass, MethodWireWrite, b)
+	}
+	t.add(t.p.Call(w.FirmwareClass, MethodWireEndTransmission, true), func(r *PipelineResult) error {
+		c, err := r.Int()
+		if err != nil {
+			return err
+		}
+		if c != 0 {
+			return &I2CError{Address: address, Code: c}
+		}
+		return nil
+	})
+	return t
+}
+
+//OnRollback registers fn to be run if the transaction fails. Hooks run in
+//the reverse order they were registered, e.g. to return outputs to a safe
+//state.
+func (t *Transaction) OnRollback(fn func() error) *Transaction {
+	t.rollbacks = append(t.rollbacks, fn)
+	return t
+}
+
+//Len returns the number of calls the transaction makes
+func (t *Transaction) Len() int {
+	return t.p.Len()
+}
+
+//Commit sends the transaction. If it fails every rollback hook is run and
+//the first error encountered is returned. The transaction is empty afterwards and may be
+//reused.
+func (t *Transaction) Commit() error {
+	return t.CommitContext(context.Background())
+}
+
+//CommitContext is like Commit but the transaction is abandoned if ctx is
+//done before it has been sent to the firmware
+func (t *Transaction) CommitContext(ctx context.Context) error {
+	checks, rollbacks := t.checks, t.rollbacks
+	t.checks, t.rollbacks = nil, nil
+	err := t.p.ExecContext(ctx)
+	if err == nil {
+		for _, c := range checks {
+			if c.check == nil {
+				continue
+			}
+			if err = c.check(c.r); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	var rerr error
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		if e := rollbacks[i](); e != nil && rerr == nil {
+			rerr = e
+		}
+	}
+	if rerr != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+	}
+	return err
+}
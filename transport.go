@@ -0,0 +1,22 @@
+package nango
+
+import (
+	"io"
+)
+
+//Transport is the byte stream a connection talks to the firmware over. The
+//default transport is the serial port described by the connection's
+//SerialConfig.
+//
+//Read must not block indefinitely while no data is arriving: returning 0 and
+//io.EOF after a short timeout lets calls notice that their deadline has
+//passed.
+type Transport interface {
+	io.ReadWriter
+	//Flush discards any data received but not yet read
+	Flush() error
+	Close() error
+}
+
+//DialFunc opens a Transport. It is called each time the connection is opened.
+type DialFunc func() (Transport, error)
@@ -0,0 +1,185 @@
+package nango
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//WeatherObservation aggregates a weather station's measurements over a
+//period
+type WeatherObservation struct {
+	Time   time.Time
+	Period time.Duration
+	Environment
+	//Rainfall is in millimetres
+	Rainfall float64
+	//WindSpeed is the mean over the period in metres per second
+	WindSpeed float64
+	//WindGust is the highest mean over a GustInterval in the period
+	WindGust float64
+	//Err is the first error reading the station during the period. The
+	//measurements which failed are zero.
+	Err error
+}
+
+//WeatherStation combines an environmental sensor with tipping bucket rain
+//gauge and cup anemometer pulses counted by a Counter, delivering an
+//observation on C every Interval. Observations are dropped if the consumer
+//falls behind.
+type WeatherStation struct {
+	C <-chan WeatherObservation
+
+	//Sensor, if set, reads the temperature, pressure and humidity
+	Sensor  func(ctx context.Context) (Environment, error)
+	Counter *Counter
+	//RainPin and WindPin are the counter pins of the rain gauge and
+	//anemometer. Either may be empty if not fitted.
+	RainPin string
+	WindPin string
+	//RainPerPulse is the rainfall per tip of the gauge in millimetres
+	RainPerPulse float64
+	//WindPerHz is the wind speed in metres per second per Hz of anemometer
+	//pulses
+	WindPerHz float64
+	//Interval is the period of an observation
+	Interval time.Duration
+	//GustInterval is the period the wind is averaged over to find gusts,
+	//3s as recommended by the WMO
+	GustInterval time.Duration
+
+	c     chan WeatherObservation
+	mu    sync.Mutex
+	start time.Time
+	wind  PulseCount
+	gust  float64
+	err   error
+	sched *Scheduler
+	name  string
+}
+
+//NewWeatherStation returns a station reading sensor, if not nil, and rain and
+//wind pulses on the given counter pins, with defaults for the common
+//SparkFun/Argent Data weather meters
+func NewWeatherStation(sensor *BME280, counter *Counter, rainPin string, windPin string) *WeatherStation {
+	c := make(chan WeatherObservation, 1)
+	w := &WeatherStation{
+		C:            c,
+		c:            c,
+		Counter:      counter,
+		RainPin:      rainPin,
+		WindPin:      windPin,
+		RainPerPulse: 0.2794,
+		WindPerHz:    2.4 / 3.6,
+		Interval:     time.Minute,
+		GustInterval: 3 * time.Second,
+	}
+	if sensor != nil {
+		w.Sensor = func(ctx context.Context) (Environment, error) { return sensor.Read() }
+	}
+	return w
+}
+
+//Step samples the anemometer and, once Interval has passed since the last
+//observation, delivers the next. The first step resets the counts and
+//starts the first period.
+func (w *WeatherStation) Step(ctx context.Context, now time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.start.IsZero() {
+		w.start = now
+		for _, pin := range []string{w.RainPin, w.WindPin} {
+			if pin == "" {
+				continue
+			}
+			if _, err := w.Counter.ReadAndReset(pin); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var stepErr error
+	if w.WindPin != "" {
+		p, err := w.Counter.ReadAndReset(w.WindPin)
+		if err != nil {
+			stepErr = err
+			w.record(err)
+		} else {
+			w.wind.Count += p.Count
+			w.wind.Elapsed += p.Elapsed
+			if gust := p.Frequency() * w.WindPerHz; gust > w.gust {
+				w.gust = gust
+			}
+		}
+	}
+	if now.Sub(w.start) < w.Interval {
+		return stepErr
+	}
+
+	obs := WeatherObservation{
+		Time:      now,
+		Period:    now.Sub(w.start),
+		WindSpeed: w.wind.Frequency() * w.WindPerHz,
+		WindGust:  w.gust,
+	}
+	if w.RainPin != "" {
+		p, err := w.Counter.ReadAndReset(w.RainPin)
+		if err != nil {
+			w.record(err)
+		}
+		obs.Rainfall = float64(p.Count) * w.RainPerPulse
+	}
+	if w.Sensor != nil {
+		env, err := w.Sensor(ctx)
+		if err != nil {
+			w.record(err)
+		}
+		obs.Environment = env
+	}
+	obs.Err = w.err
+	w.start, w.wind, w.gust, w.err = now, PulseCount{}, 0, nil
+	select {
+	case w.c <- obs:
+	default:
+	}
+	return stepErr
+}
+
+//record keeps the first error of the period
+func (w *WeatherStation) record(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+//Start attaches the counters and schedules Step every GustInterval on s
+func (w *WeatherStation) Start(s *Scheduler, name string) error {
+	for _, pin := range []string{w.RainPin, w.WindPin} {
+		if pin == "" {
+			continue
+		}
+		if err := w.Counter.Attach(pin, EdgeFalling); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	w.sched, w.name = s, name
+	w.start = time.Time{}
+	w.mu.Unlock()
+	s.Every(name, w.GustInterval, func(ctx context.Context) error {
+		return w.Step(ctx, s.clock.Now())
+	})
+	return nil
+}
+
+//Stop unschedules the station. The observation in progress is discarded.
+func (w *WeatherStation) Stop() {
+	w.mu.Lock()
+	s, name := w.sched, w.name
+	w.sched = nil
+	w.mu.Unlock()
+	if s != nil {
+		s.Remove(name)
+	}
+}
@@ -0,0 +1,54 @@
+package nango
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWeatherStation(t *testing.T) {
+	lb, conn := openLoopback(t)
+	counts := map[string][]string{
+		"D2": {"9,0", "5,60000"},
+		"D3": {"0,0", "3,1000", "12,1000", "6,1000"},
+	}
+	lb.Handle(NamespaceCounter, "readAndReset", func(c LoopbackCall) (string, bool) {
+		q := counts[c.Args[0]]
+		if len(q) == 0 {
+			return "0,0", true
+		}
+		counts[c.Args[0]] = q[1:]
+		return q[0], true
+	})
+	w := NewWeatherStation(nil, NewCounter(conn), "D2", "D3")
+	w.Interval = 3 * time.Second
+	w.GustInterval = time.Second
+	sensorErr := errors.New("no ack")
+	w.Sensor = func(ctx context.Context) (Environment, error) { return Environment{}, sensorErr }
+
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if err := w.Step(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+	var obs WeatherObservation
+	select {
+	case obs = <-w.C:
+	default:
+		t.Fatal("expected an observation after the interval")
+	}
+	if obs.Period != 3*time.Second || math.Abs(obs.Rainfall-5*0.2794) > 1e-9 {
+		t.Fatalf("unexpected observation %+v", obs)
+	}
+	if math.Abs(obs.WindSpeed-7*w.WindPerHz) > 1e-9 || math.Abs(obs.WindGust-12*w.WindPerHz) > 1e-9 {
+		t.Fatalf("expected mean 7Hz and gust 12Hz, got %+v", obs)
+	}
+	if obs.Err != sensorErr {
+		t.Fatalf("expected sensor error, got %v", obs.Err)
+	}
+}